@@ -0,0 +1,28 @@
+// Package apps is the catalogue of apps TuiTop can install and run.
+package apps
+
+import (
+	"embed"
+	"io"
+	"sort"
+	"strings"
+)
+
+//go:embed *.tui.yaml
+var files embed.FS
+
+// Open returns the definition of the app named name, such as "browser".
+func Open(name string) (io.ReadCloser, error) {
+	return files.Open(name + ".tui.yaml")
+}
+
+// Names returns the names of the apps in the catalogue, sorted.
+func Names() []string {
+	entries, _ := files.ReadDir(".")
+	names := []string{}
+	for _, e := range entries {
+		names = append(names, strings.TrimSuffix(e.Name(), ".tui.yaml"))
+	}
+	sort.Strings(names)
+	return names
+}
@@ -1,6 +1,7 @@
 package cterm
 
 import (
+	"io"
 	"log"
 	"os/exec"
 	"sync"
@@ -17,11 +18,29 @@ type Terminal struct {
 	term    *tcellterm.VT
 	running bool
 	cmd     *exec.Cmd
-	screen  tcell.Screen
+	// conn and onResize are used instead of cmd by terminals not running
+	// a local command
+	conn     io.ReadWriteCloser
+	onResize func(w, h int)
+	screen   tcell.Screen
+	view     *views.ViewPort
+	onFocus  func()
+	onLink   func(url string)
+	onMenu   func(x, y int)
+	// selecting is whether the mouse is selecting text
+	selecting bool
+	// hints are the links labelled in hint mode, and hintInput the label
+	// typed so far
+	hints     []tcellterm.Link
+	hintInput string
+	// find is the find bar, or nil when it is closed
+	find *finder
 
 	sync.Once
 	sync.RWMutex
 	oldW, oldH int
+	// rect is the inner rect the view was last placed at
+	rect [4]int
 }
 
 func NewTerminal(cmd *exec.Cmd) *Terminal {
@@ -31,24 +50,118 @@ func NewTerminal(cmd *exec.Cmd) *Terminal {
 		term: n,
 		cmd:  cmd,
 	}
+	// The terminal draws all its cells itself, and only those which changed
+	t.Box.SetBackgroundTransparent(true)
+	return t
+}
+
+// NewTerminalIO returns a terminal for a program connected through conn, such
+// as an SSH channel or a serial port. resize is called with the size in
+// columns and rows when it changes, and may be nil.
+func NewTerminalIO(conn io.ReadWriteCloser, resize func(w, h int)) *Terminal {
+	t := NewTerminal(nil)
+	t.conn = conn
+	t.onResize = resize
 	return t
 }
 
-/*
 func (t *Terminal) Focus(delegate func(p cview.Primitive)) {
-	t.term.ShowCursor()
+	t.term.Focus()
 	t.Box.Focus(delegate)
+	if t.onFocus != nil {
+		t.onFocus()
+	}
+}
+
+// SetFocusHandler sets a function called when the terminal gains focus.
+func (t *Terminal) SetFocusHandler(f func()) {
+	t.onFocus = f
+}
+
+// SetLinkHandler sets a function called with the URL of a link the user
+// opens, by Ctrl+clicking it or in hint mode.
+func (t *Terminal) SetLinkHandler(f func(url string)) {
+	t.onLink = f
+}
+
+func (t *Terminal) openLink(url string) {
+	if t.onLink != nil {
+		t.onLink(url)
+	}
+}
+
+// SetMenuHandler sets a function called with the screen position of a right
+// click, unless the program uses the mouse.
+func (t *Terminal) SetMenuHandler(f func(x, y int)) {
+	t.onMenu = f
+}
+
+// LinkAt returns the URL of the link drawn at x, y on the screen.
+func (t *Terminal) LinkAt(x, y int) (string, bool) {
+	gx, gy, _, _ := t.GetInnerRect()
+	link, ok := t.term.LinkAt(y-gy, x-gx)
+	return link.URL, ok
+}
+
+// SelectedText returns the text selected with the mouse, or "".
+func (t *Terminal) SelectedText() string {
+	return t.term.SelectedText()
+}
+
+// Paste sends text to the program as if it was typed.
+func (t *Terminal) Paste(text string) {
+	t.term.Paste(text)
+}
+
+// Find opens the find bar.
+func (t *Terminal) Find() {
+	t.startFind()
+}
+
+// ClearScrollback removes the lines which scrolled off the screen.
+func (t *Terminal) ClearScrollback() {
+	t.term.ClearScrollback()
+}
+
+// Close kills the program, or closes the connection to it.
+func (t *Terminal) Close() {
+	t.term.Close()
+}
+
+// WorkingDir returns the working directory of the program, or "".
+func (t *Terminal) WorkingDir() string {
+	return t.term.WorkingDir()
+}
+
+// Pid returns the process ID of the command, or 0 if it is not running or
+// the terminal is connected to a remote program.
+func (t *Terminal) Pid() int {
+	return t.term.Pid()
 }
+
+// ForegroundPgrp returns the process group in the foreground of the pty, or
+// 0 if it is unknown.
+func (t *Terminal) ForegroundPgrp() int {
+	return t.term.ForegroundPgrp()
+}
+
 func (t *Terminal) Blur() {
-	t.term.HideCursor()
+	t.term.Blur()
 	t.Box.Blur()
+	if t.screen != nil {
+		t.screen.HideCursor()
+	}
+}
+
+// SetCellSize sets the size of a cell in pixels reported to the application.
+func (t *Terminal) SetCellSize(w, h int) {
+	t.term.CellWidth, t.term.CellHeight = w, h
 }
-*/
 
 // TODO if X is clicked, call t.term.Close()
 
 func (t *Terminal) Attach(eventHandler func(ev tcell.Event)) {
-	log.Printf("Attach", eventHandler)
+	log.Print("Attach")
 	t.term.Attach(eventHandler)
 }
 func (t *Terminal) Draw(s tcell.Screen) {
@@ -60,15 +173,26 @@ func (t *Terminal) Draw(s tcell.Screen) {
 	defer t.Unlock()
 
 	x, y, w, h := t.GetInnerRect()
-	view := views.NewViewPort(s, x, y, w, h)
-	t.term.SetSurface(view)
+	if t.view == nil || t.screen != s {
+		t.view = views.NewViewPort(s, x, y, w, h)
+		t.term.SetSurface(viewSurface{t.view, s})
+	} else if t.rect != [4]int{x, y, w, h} {
+		t.view.Resize(x, y, w, h)
+		t.term.Invalidate()
+	}
+	t.rect = [4]int{x, y, w, h}
 	t.screen = s
 
 	t.Once.Do(func() {
 		//t.term.Watch(t)		// TODO !
 		go func() {
 			//attr := &syscall.SysProcAttr{Setsid: true, Setctty: true, Ctty: 1}err := t.term.RunWithAttrs(t.cmd, attr);
-			err := t.term.Start(t.cmd)
+			var err error
+			if t.conn != nil {
+				err = t.term.StartIO(t.conn, t.onResize)
+			} else {
+				err = t.term.Start(t.cmd)
+			}
 			if err != nil {
 				panic(err)
 			}
@@ -76,6 +200,36 @@ func (t *Terminal) Draw(s tcell.Screen) {
 		}()
 	})
 	t.term.Draw()
+	t.drawGutter(s, x, y, h)
+	t.drawHints(s, x, y)
+	t.drawFind(s, x, y, w, h)
+}
+
+// viewSurface is the part of the screen the terminal is drawn on. It is read
+// back from the screen, so cells which windows below drew over are drawn
+// again.
+type viewSurface struct {
+	*views.ViewPort
+	screen tcell.Screen
+}
+
+func (v viewSurface) GetContent(x, y int) (rune, []rune, tcell.Style, int) {
+	vx, vy, _, _ := v.GetPhysical()
+	return v.screen.GetContent(vx+x, vy+y)
+}
+
+// drawGutter marks prompts whose command failed on the left border.
+func (t *Terminal) drawGutter(s tcell.Screen, x, y, h int) {
+	if x == 0 {
+		return
+	}
+	style := tcell.StyleDefault.Foreground(tcell.ColorRed)
+	for _, mark := range t.term.PromptMarks() {
+		if !mark.Done || mark.Exit == 0 || mark.Row >= h {
+			continue
+		}
+		s.SetContent(x-1, y+mark.Row, '●', nil, style)
+	}
 }
 
 func (t *Terminal) SetRect(x, y, w, h int) {
@@ -93,10 +247,10 @@ func (t *Terminal) HandleEvent(ev tcell.Event) bool {
 		t.Draw(t.screen)
 		if t.HasFocus() {
 
-			x, y, style, vis := t.term.Cursor()
+			row, col, style, vis := t.term.Cursor()
 			gx, gy, _, _ := t.Box.GetInnerRect()
 			if vis {
-				t.screen.ShowCursor(x+gx, y+gy)
+				t.screen.ShowCursor(col+gx, row+gy)
 				t.screen.SetCursorStyle(style)
 			} else {
 				t.screen.HideCursor()
@@ -118,12 +272,83 @@ func (t *Terminal) GetFocusable() cview.Focusable {
 
 func (t *Terminal) InputHandler() func(event *tcell.EventKey, setFocus func(p cview.Primitive)) {
 	return t.WrapInputHandler(func(event *tcell.EventKey, setFocus func(p cview.Primitive)) {
+		if t.hints != nil {
+			t.hintKey(event)
+			return
+		}
+		if t.find != nil {
+			t.findKey(event)
+			return
+		}
+		if t.handleShortcut(event) {
+			return
+		}
 		t.term.HandleEvent(event)
 	})
 }
 
+// handleShortcut handles the keys which move through the scrollback rather
+// than going to the program. Ctrl+Shift+Up and Down jump to the previous and
+// next prompt, Shift+PgUp and PgDn page through the scrollback and
+// Alt+Shift+O selects the output of the last command. Alt+Shift+L labels the
+// links for opening them from the keyboard and Alt+Shift+F opens the find bar.
+func (t *Terminal) handleShortcut(event *tcell.EventKey) bool {
+	_, _, _, h := t.GetInnerRect()
+	mods := event.Modifiers()
+	switch {
+	case event.Key() == tcell.KeyUp && mods == tcell.ModCtrl|tcell.ModShift:
+		t.term.ScrollToPrompt(true)
+	case event.Key() == tcell.KeyDown && mods == tcell.ModCtrl|tcell.ModShift:
+		t.term.ScrollToPrompt(false)
+	case event.Key() == tcell.KeyPgUp && mods == tcell.ModShift:
+		t.term.ScrollView(h - 1)
+	case event.Key() == tcell.KeyPgDn && mods == tcell.ModShift:
+		t.term.ScrollView(1 - h)
+	case event.Key() == tcell.KeyRune && event.Rune() == 'O' && mods&tcell.ModAlt != 0:
+		t.term.SelectLastOutput()
+	case event.Key() == tcell.KeyRune && event.Rune() == 'L' && mods&tcell.ModAlt != 0:
+		t.startHints()
+	case event.Key() == tcell.KeyRune && event.Rune() == 'F' && mods&tcell.ModAlt != 0:
+		t.startFind()
+	default:
+		return false
+	}
+	return true
+}
+
 func (t *Terminal) MouseHandler() func(action cview.MouseAction, event *tcell.EventMouse, setFocus func(p cview.Primitive)) (consumed bool, capture cview.Primitive) {
 	return t.WrapMouseHandler(func(action cview.MouseAction, event *tcell.EventMouse, setFocus func(p cview.Primitive)) (consumed bool, capture cview.Primitive) {
+		x, y, _, _ := t.GetInnerRect()
+		px, py := event.Position()
+		ownMouse := !t.term.MouseReporting()
+		switch {
+		case action == cview.MouseLeftClick && event.Modifiers()&tcell.ModCtrl != 0:
+			if link, ok := t.term.LinkAt(py-y, px-x); ok {
+				t.openLink(link.URL)
+				return true, nil
+			}
+		case action == cview.MouseRightClick && ownMouse && t.onMenu != nil:
+			t.onMenu(px, py)
+			return true, nil
+		case action == cview.MouseLeftDown && ownMouse && event.Modifiers()&tcell.ModCtrl == 0:
+			// Dragging selects text
+			t.term.StartSelection(py-y, px-x)
+			t.selecting = true
+			setFocus(t)
+			return true, t
+		case action == cview.MouseMove && t.selecting:
+			if event.Buttons()&tcell.Button1 == 0 {
+				t.selecting = false
+				return true, nil
+			}
+			t.term.ExtendSelection(py-y, px-x)
+			return true, t
+		case action == cview.MouseLeftUp && t.selecting:
+			t.selecting = false
+			return true, nil
+		case action == cview.MouseMove:
+			t.term.SetHover(py-y, px-x)
+		}
 		return t.term.HandleEvent(event), nil
 	})
 }
@@ -0,0 +1,126 @@
+package cterm
+
+import (
+	"fmt"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/snadrus/tuitop/deps/tcellterm"
+)
+
+// finder is the state of the find bar.
+type finder struct {
+	query   []rune
+	opts    tcellterm.SearchOptions
+	matches []tcellterm.Match
+	current int
+	err     error
+}
+
+// startFind opens the find bar.
+func (t *Terminal) startFind() {
+	if t.find == nil {
+		t.find = &finder{}
+	}
+}
+
+// stopFind closes the find bar and removes the highlights.
+func (t *Terminal) stopFind() {
+	t.find = nil
+	t.term.SetHighlights(nil, -1)
+}
+
+// findKey handles a key while the find bar is open. Typing searches as it
+// goes. Enter and Down go to the next match, Up to the previous one, Alt+R
+// switches to regular expressions, Alt+C ignores case and Escape closes the
+// bar.
+func (t *Terminal) findKey(event *tcell.EventKey) {
+	f := t.find
+	switch {
+	case event.Key() == tcell.KeyEscape:
+		t.stopFind()
+		return
+	case event.Key() == tcell.KeyEnter, event.Key() == tcell.KeyDown:
+		t.moveMatch(1)
+		return
+	case event.Key() == tcell.KeyUp:
+		t.moveMatch(-1)
+		return
+	case event.Key() == tcell.KeyBackspace, event.Key() == tcell.KeyBackspace2:
+		if len(f.query) == 0 {
+			return
+		}
+		f.query = f.query[:len(f.query)-1]
+	case event.Key() == tcell.KeyRune && event.Modifiers()&tcell.ModAlt != 0:
+		switch event.Rune() {
+		case 'r', 'R':
+			f.opts.Regexp = !f.opts.Regexp
+		case 'c', 'C':
+			f.opts.IgnoreCase = !f.opts.IgnoreCase
+		default:
+			return
+		}
+	case event.Key() == tcell.KeyRune:
+		f.query = append(f.query, event.Rune())
+	default:
+		return
+	}
+	t.search()
+}
+
+// search runs the query again and goes to the newest match.
+func (t *Terminal) search() {
+	f := t.find
+	f.matches, f.err = t.term.Search(string(f.query), f.opts)
+	f.current = len(f.matches) - 1
+	t.showMatch()
+}
+
+// moveMatch goes to the next match, or the previous one for -1, wrapping
+// around.
+func (t *Terminal) moveMatch(n int) {
+	f := t.find
+	if len(f.matches) == 0 {
+		return
+	}
+	f.current = (f.current + n + len(f.matches)) % len(f.matches)
+	t.showMatch()
+}
+
+func (t *Terminal) showMatch() {
+	f := t.find
+	t.term.SetHighlights(f.matches, f.current)
+	if f.current >= 0 {
+		t.term.ShowLine(f.matches[f.current].StartLine)
+	}
+}
+
+// drawFind draws the find bar over the bottom row.
+func (t *Terminal) drawFind(s tcell.Screen, x, y, w, h int) {
+	f := t.find
+	if f == nil || h == 0 {
+		return
+	}
+	flags := ""
+	if f.opts.Regexp {
+		flags += " .*"
+	}
+	if f.opts.IgnoreCase {
+		flags += " Aa"
+	}
+	status := fmt.Sprintf("%d/%d", f.current+1, len(f.matches))
+	switch {
+	case f.err != nil:
+		status = "bad pattern"
+	case len(f.matches) == 0:
+		status = "no matches"
+	}
+	text := []rune(fmt.Sprintf("Find%s: %s  %s", flags, string(f.query), status))
+	style := tcell.StyleDefault.Background(tcell.ColorWhite).Foreground(tcell.ColorBlack)
+	for i := 0; i < w; i += 1 {
+		r := ' '
+		if i < len(text) {
+			r = text[i]
+		}
+		s.SetContent(x+i, y+h-1, r, nil, style)
+	}
+}
@@ -0,0 +1,77 @@
+package cterm
+
+import (
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+const hintAlphabet = "asdfghjklqwertyuiopzxcvbnm"
+
+// hintLabels returns n labels, of one letter if there are few enough links
+// and of two letters otherwise.
+func hintLabels(n int) []string {
+	labels := make([]string, 0, n)
+	if n <= len(hintAlphabet) {
+		for i := 0; i < n; i += 1 {
+			labels = append(labels, hintAlphabet[i:i+1])
+		}
+		return labels
+	}
+	for _, a := range hintAlphabet {
+		for _, b := range hintAlphabet {
+			if len(labels) == n {
+				return labels
+			}
+			labels = append(labels, string(a)+string(b))
+		}
+	}
+	return labels
+}
+
+// startHints labels every link in the view. Typing a label opens its link.
+func (t *Terminal) startHints() {
+	links := t.term.Links()
+	if len(links) > len(hintAlphabet)*len(hintAlphabet) {
+		links = links[:len(hintAlphabet)*len(hintAlphabet)]
+	}
+	if len(links) == 0 {
+		return
+	}
+	t.hints = links
+	t.hintInput = ""
+}
+
+// hintKey handles a key in hint mode. Keys which match no label, such as
+// Escape, leave hint mode.
+func (t *Terminal) hintKey(event *tcell.EventKey) {
+	if event.Key() == tcell.KeyRune {
+		t.hintInput += string(event.Rune())
+		for i, label := range hintLabels(len(t.hints)) {
+			switch {
+			case label == t.hintInput:
+				url := t.hints[i].URL
+				t.hints = nil
+				t.openLink(url)
+				return
+			case strings.HasPrefix(label, t.hintInput):
+				return
+			}
+		}
+	}
+	t.hints = nil
+}
+
+// drawHints draws the labels of hint mode over the start of each link.
+func (t *Terminal) drawHints(s tcell.Screen, x, y int) {
+	style := tcell.StyleDefault.Background(tcell.ColorYellow).Foreground(tcell.ColorBlack).Bold(true)
+	for i, label := range hintLabels(len(t.hints)) {
+		if !strings.HasPrefix(label, t.hintInput) {
+			continue
+		}
+		link := t.hints[i]
+		for j, r := range label {
+			s.SetContent(x+link.StartCol+j, y+link.StartRow, r, nil, style)
+		}
+	}
+}
@@ -3,7 +3,7 @@ package tcellterm
 func (vt *VT) c0(r rune) {
 	switch r {
 	case 0x07:
-		vt.postEvent(EventBell{
+		vt.postEvent(&EventBell{
 			EventTerminal: newEventTerminal(vt),
 		})
 	case 0x08:
@@ -52,7 +52,7 @@ func (vt *VT) lf() {
 	if vt.mode&lnm != lnm {
 		return
 	}
-	vt.cursor.col = vt.margin.left
+	vt.cursor.col = vt.leftEdge()
 }
 
 // Vertical tabulation 0x11
@@ -68,5 +68,5 @@ func (vt *VT) ff() {
 // Carriage return 0x13
 func (vt *VT) cr() {
 	vt.lastCol = false
-	vt.cursor.col = vt.margin.left
+	vt.cursor.col = vt.leftEdge()
 }
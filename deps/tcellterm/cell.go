@@ -7,6 +7,9 @@ type cell struct {
 	combining []rune
 	width     int
 	attrs     tcell.Style
+	ext       extendedAttrs
+	link      hyperlink
+	sem       semantic
 	wrapped   bool
 }
 
@@ -25,9 +28,63 @@ func (c *cell) erase(s tcell.Style) {
 	_, bg, _ := s.Decompose()
 	c.content = 0
 	c.attrs = tcell.StyleDefault.Background(bg)
+	c.ext = extendedAttrs{}
+	c.link = hyperlink{}
+	c.sem = semantic{}
 }
 
 // selectiveErase removes the cell content, but keeps the attributes
 func (c *cell) selectiveErase() {
 	c.content = 0
 }
+
+// visible returns the content and style to draw the cell with. Concealed cells
+// are drawn blank
+func (c *cell) visible() (rune, []rune, tcell.Style) {
+	style := c.attrs
+	if c.ext.underline != underlineNone {
+		// The styles are numbered as in tcell
+		style = style.Underline(tcell.UnderlineStyle(c.ext.underline), c.ext.underlineColor)
+	}
+	if c.ext.conceal {
+		return ' ', nil, style
+	}
+	return c.content, c.combining, style
+}
+
+// equal reports whether two cells would be drawn identically
+func (c *cell) equal(o *cell) bool {
+	if c.content != o.content || c.width != o.width || c.attrs != o.attrs || c.ext != o.ext || c.link != o.link {
+		return false
+	}
+	if len(c.combining) != len(o.combining) {
+		return false
+	}
+	for i := range c.combining {
+		if c.combining[i] != o.combining[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// copyScreen returns a deep copy of a screen
+func copyScreen(screen [][]cell) [][]cell {
+	cp := make([][]cell, len(screen))
+	for r := range screen {
+		cp[r] = copyLine(screen[r])
+	}
+	return cp
+}
+
+// copyLine returns a deep copy of a line
+func copyLine(line []cell) []cell {
+	cp := make([]cell, len(line))
+	copy(cp, line)
+	for c := range cp {
+		if cp[c].combining != nil {
+			cp[c].combining = append([]rune(nil), cp[c].combining...)
+		}
+	}
+	return cp
+}
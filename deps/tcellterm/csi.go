@@ -78,7 +78,7 @@ func (vt *VT) csi(csi string, params []int) {
 		resp.WriteString("22")
 		// Response terminator
 		resp.WriteString("c")
-		vt.pty.WriteString(resp.String())
+		vt.writeString(resp.String())
 	case "d":
 		vt.vpa(ps(params))
 	case "e":
@@ -98,28 +98,52 @@ func (vt *VT) csi(csi string, params []int) {
 		vt.decrst(params)
 	case "m":
 		vt.sgr(params)
+	case ">m":
+		vt.xtmodkeys(params)
+	case "?m":
+		vt.xtqmodkeys(params)
 	case "n":
 		// Send device status report
 		switch ps(params) {
 		case 5:
 			// "Ok"
-			vt.pty.WriteString("\x1B[0n")
+			vt.writeString("\x1B[0n")
 		case 6:
 			// report cursor position
 			// This sequence can be identical to a function key?
 			// CSI r ; c R
 			resp := fmt.Sprintf("\x1B[%d;%dR", vt.cursor.row+1, vt.cursor.col+1)
-			vt.pty.WriteString(resp)
+			vt.writeString(resp)
 		}
 	case "r":
 		vt.decstbm(params)
+	case "t":
+		vt.xtwinops(params)
 	case "s":
+		if vt.mode&declrmm != 0 {
+			vt.decslrm(params)
+			return
+		}
 		vt.decsc()
 	case "u":
 		vt.decrc()
+	case ">u":
+		vt.kittyPush(ps(params))
+	case "<u":
+		vt.kittyPop(ps(params))
+	case "=u":
+		vt.kittySet(params)
+	case "?u":
+		vt.kittyQuery()
 	case " q":
 		ps(params)
 		vt.cursor.style = tcell.CursorStyle(ps(params))
+	case ">q":
+		vt.xtversion()
+	case "$p":
+		vt.decrqm(ps(params), false)
+	case "?$p":
+		vt.decrqm(ps(params), true)
 	}
 }
 
@@ -134,31 +158,24 @@ func ps(params []int) int {
 }
 
 // Insert Blank Character (ICH) CSI Ps @
-// Insert Ps blank characters. Cursor does not change position.
+// Insert Ps blank characters. Cursor does not change position. Characters
+// pushed past the right margin are lost. This sequence is ignored when the
+// cursor is outside the left and right margins.
 func (vt *VT) ich(ps int) {
+	vt.lastCol = false
 	if ps == 0 {
 		ps = 1
 	}
 	col := vt.cursor.col
-	row := vt.cursor.row
-	line := vt.activeScreen[row]
-	for i := vt.margin.right; i > col; i -= 1 {
-		if col+i > column(vt.width()-1) {
-			break
-		}
-		if (i - column(ps)) < 0 {
-			continue
-		}
+	if col < vt.margin.left || col > vt.margin.right {
+		return
+	}
+	line := vt.activeScreen[vt.cursor.row]
+	for i := vt.margin.right; i >= col+column(ps); i -= 1 {
 		line[i] = line[i-column(ps)]
 	}
-	for i := 0; i < ps; i += 1 {
-		if int(col)+i >= (vt.width() - 1) {
-			break
-		}
-		line[col+column(i)] = cell{
-			content: ' ',
-			width:   1,
-		}
+	for i := col; i < col+column(ps) && i <= vt.margin.right; i += 1 {
+		line[i].erase(vt.cursor.attrs)
 	}
 }
 
@@ -199,9 +216,10 @@ func (vt *VT) cuf(ps int) {
 	if ps == 0 {
 		ps = 1
 	}
+	right := vt.rightEdge()
 	vt.cursor.col += column(ps)
-	if vt.cursor.col > vt.margin.right {
-		vt.cursor.col = vt.margin.right
+	if vt.cursor.col > right {
+		vt.cursor.col = right
 	}
 }
 
@@ -212,9 +230,10 @@ func (vt *VT) cub(ps int) {
 	if ps == 0 {
 		ps = 1
 	}
+	left := vt.leftEdge()
 	vt.cursor.col -= column(ps)
-	if vt.cursor.col < vt.margin.left {
-		vt.cursor.col = vt.margin.left
+	if vt.cursor.col < left {
+		vt.cursor.col = left
 	}
 }
 
@@ -240,24 +259,47 @@ func (vt *VT) cpl(ps int) {
 	for i := 0; i < ps; i += 1 {
 		vt.ri()
 	}
-	vt.cursor.col = vt.margin.left
+	vt.cursor.col = vt.leftEdge()
 }
 
 // Cursor Character Absolute (CHA) CSI Ps G
-// Move cursor to Ps column, stopping at right/left margin. Default is 1, but we
-// default to 0 since our columns our 0 indexed
+// Move cursor to Ps column. Default is 1, but we default to 0 since our
+// columns our 0 indexed. In origin mode, the column is relative to the left
+// margin and the cursor stops at the right margin
 func (vt *VT) cha(ps int) {
 	vt.lastCol = false
 	if ps == 0 {
 		ps = 1
 	}
-	vt.cursor.col = column(ps - 1)
-	if vt.cursor.col > vt.margin.right {
-		vt.cursor.col = vt.margin.right
+	vt.cursor.col = vt.originCol(ps - 1)
+}
+
+// originCol returns the 0-indexed column, adjusted for origin mode and clamped
+// to the screen or the margins
+func (vt *VT) originCol(col int) column {
+	left, right := column(0), column(vt.width()-1)
+	if vt.mode&decom != 0 {
+		left, right = vt.margin.left, vt.margin.right
 	}
-	if vt.cursor.col < vt.margin.left {
-		vt.cursor.col = vt.margin.left
+	c := left + column(col)
+	if c > right {
+		c = right
+	}
+	return c
+}
+
+// originRow returns the 0-indexed row, adjusted for origin mode and clamped to
+// the screen or the margins
+func (vt *VT) originRow(rw int) row {
+	top, bottom := row(0), row(vt.height()-1)
+	if vt.mode&decom != 0 {
+		top, bottom = vt.margin.top, vt.margin.bottom
 	}
+	r := top + row(rw)
+	if r > bottom {
+		r = bottom
+	}
+	return r
 }
 
 // Cursor Position (CUP) CSI Ps;Ps H
@@ -273,14 +315,14 @@ func (vt *VT) cup(pm []int) {
 	default:
 		return
 	}
-	vt.cursor.row = row(pm[0] - 1)
-	vt.cursor.col = column(pm[1] - 1)
-	if vt.cursor.col > column(vt.width()-1) {
-		vt.cursor.col = column(vt.width() - 1)
+	if pm[0] == 0 {
+		pm[0] = 1
 	}
-	if vt.cursor.row > row(vt.height()-1) {
-		vt.cursor.row = row(vt.height() - 1)
+	if pm[1] == 0 {
+		pm[1] = 1
 	}
+	vt.cursor.row = vt.originRow(pm[0] - 1)
+	vt.cursor.col = vt.originCol(pm[1] - 1)
 }
 
 // Cursor Forward Tabulation (CHT) CSI Ps I
@@ -347,6 +389,10 @@ func (vt *VT) ed(ps int) {
 				vt.activeScreen[r][col].erase(vt.cursor.attrs)
 			}
 		}
+
+	// Erases the saved lines
+	case 3:
+		vt.clearScrollback()
 	}
 }
 
@@ -410,7 +456,7 @@ func (vt *VT) il(ps int) {
 
 	// move the lines first
 	for r := vt.margin.bottom; r >= (vt.cursor.row + row(ps)); r -= 1 {
-		copy(vt.activeScreen[r], vt.activeScreen[r-row(ps)])
+		vt.copyRow(int(r), int(r)-ps)
 	}
 
 	// insert the blank lines (we do this by erasing the cells)
@@ -455,7 +501,7 @@ func (vt *VT) dl(ps int) {
 
 	for r := vt.cursor.row; r <= vt.margin.bottom; r += 1 {
 		if r <= vt.margin.bottom-row(ps) {
-			copy(vt.activeScreen[r], vt.activeScreen[r+row(ps)])
+			vt.copyRow(int(r), int(r)+ps)
 			continue
 		}
 		for col := vt.margin.left; col <= vt.margin.right; col += 1 {
@@ -472,11 +518,16 @@ func (vt *VT) dl(ps int) {
 // to the left. This creates a space character at the right margin for each
 // character deleted. Character attributes move with the characters. The spaces
 // created at the end of the line have all their character attributes off.
+// This sequence is ignored when the cursor is outside the left and right
+// margins.
 func (vt *VT) dch(ps int) {
 	vt.lastCol = false
 	if ps == 0 {
 		ps = 1
 	}
+	if vt.cursor.col < vt.margin.left || vt.cursor.col > vt.margin.right {
+		return
+	}
 	row := vt.cursor.row
 	for col := vt.cursor.col; col <= vt.margin.right; col += 1 {
 		if col+column(ps) > vt.margin.right {
@@ -553,10 +604,7 @@ func (vt *VT) vpa(ps int) {
 	if ps == 0 {
 		ps = 1
 	}
-	vt.cursor.row = row(ps - 1)
-	if vt.cursor.row > row(vt.height()-1) {
-		vt.cursor.row = row(vt.height() - 1)
-	}
+	vt.cursor.row = vt.originRow(ps - 1)
 }
 
 // Line Position Relative (VPR) CSI Ps e
@@ -581,10 +629,7 @@ func (vt *VT) hpa(ps int) {
 	if ps == 0 {
 		ps = 1
 	}
-	vt.cursor.col = column(ps - 1)
-	if vt.cursor.col > column(vt.width()-1) {
-		vt.cursor.col = column(vt.width() - 1)
-	}
+	vt.cursor.col = vt.originCol(ps - 1)
 }
 
 // Character Position Relative (HPR) CSI Ps a
@@ -629,6 +674,82 @@ func (vt *VT) decstbm(pm []int) {
 	}
 	vt.margin.top = row(pm[0]) - 1
 	vt.margin.bottom = row(pm[1]) - 1
-	vt.cursor.row = 0
-	vt.cursor.col = 0
+	vt.home()
+}
+
+// Set left and right margins (DECSLRM) CSI Ps ; Ps s
+//
+// Only available when DECLRMM is set, otherwise CSI s is DECSC. The cursor
+// moves to the home position
+func (vt *VT) decslrm(pm []int) {
+	left, right := 1, vt.width()
+	if len(pm) > 0 && pm[0] > 0 {
+		left = pm[0]
+	}
+	if len(pm) > 1 && pm[1] > 0 {
+		right = pm[1]
+	}
+	if right > vt.width() {
+		right = vt.width()
+	}
+	if left >= right {
+		return
+	}
+	vt.margin.left = column(left) - 1
+	vt.margin.right = column(right) - 1
+	vt.home()
+}
+
+// Window manipulation (XTWINOPS) CSI Ps ; Ps ; Ps t
+//
+// Reports the text area size in pixels (14) and cells (18), and the cell size
+// in pixels (16). Deiconify (1), iconify (2), raise (5), resize in cells (8)
+// and maximize (9) are posted as an EventWindow, for the host to carry out if
+// it allows them
+func (vt *VT) xtwinops(params []int) {
+	cellW, cellH := vt.CellWidth, vt.CellHeight
+	if cellW <= 0 || cellH <= 0 {
+		cellW, cellH = 8, 16
+	}
+	switch ps(params) {
+	case 1:
+		vt.postWindowOp(WindowDeiconify, 0, 0)
+	case 2:
+		vt.postWindowOp(WindowIconify, 0, 0)
+	case 5:
+		vt.postWindowOp(WindowRaise, 0, 0)
+	case 8:
+		// Omitted dimensions keep the current size
+		rows, cols := vt.height(), vt.width()
+		if len(params) > 1 {
+			rows = params[1]
+		}
+		if len(params) > 2 {
+			cols = params[2]
+		}
+		vt.postWindowOp(WindowResize, cols, rows)
+	case 9:
+		switch {
+		case len(params) < 2:
+		case params[1] == 0:
+			vt.postWindowOp(WindowUnmaximize, 0, 0)
+		case params[1] == 1:
+			vt.postWindowOp(WindowMaximize, 0, 0)
+		}
+	case 14:
+		vt.writeString(fmt.Sprintf("\x1b[4;%d;%dt", vt.height()*cellH, vt.width()*cellW))
+	case 16:
+		vt.writeString(fmt.Sprintf("\x1b[6;%d;%dt", cellH, cellW))
+	case 18:
+		vt.writeString(fmt.Sprintf("\x1b[8;%d;%dt", vt.height(), vt.width()))
+	}
+}
+
+func (vt *VT) postWindowOp(op WindowOp, cols, rows int) {
+	vt.postEvent(&EventWindow{
+		EventTerminal: newEventTerminal(vt),
+		op:            op,
+		cols:          cols,
+		rows:          rows,
+	})
 }
@@ -1,6 +1,7 @@
 package tcellterm
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -103,3 +104,222 @@ func TestDCH(t *testing.T) {
 	vt.dch(2)
 	assert.Equal(t, "ad  ", vt.String())
 }
+
+// feed runs the input through the parser and into the VT
+func feed(vt *VT, input string) {
+	parser := NewParser(strings.NewReader(input))
+	for {
+		seq := parser.Next()
+		if _, ok := seq.(EOF); ok || seq == nil {
+			return
+		}
+		vt.update(seq)
+		select {
+		case <-vt.events:
+		default:
+		}
+	}
+}
+
+func TestDECSLRM(t *testing.T) {
+	t.Run("CSI s is DECSC without DECLRMM", func(t *testing.T) {
+		vt := New()
+		vt.Resize(4, 2)
+		feed(vt, "\x1b[2;3s")
+		assert.Equal(t, column(0), vt.margin.left)
+		assert.Equal(t, column(3), vt.margin.right)
+	})
+
+	t.Run("Margins and home", func(t *testing.T) {
+		vt := New()
+		vt.Resize(6, 2)
+		feed(vt, "\x1b[?69h\x1b[1;2Hx\x1b[2;4s")
+		assert.Equal(t, column(1), vt.margin.left)
+		assert.Equal(t, column(3), vt.margin.right)
+		assert.Equal(t, column(0), vt.cursor.col)
+		assert.Equal(t, row(0), vt.cursor.row)
+
+		// Invalid margins are ignored
+		feed(vt, "\x1b[4;4s")
+		assert.Equal(t, column(1), vt.margin.left)
+
+		// Resetting DECLRMM resets the margins
+		feed(vt, "\x1b[?69l")
+		assert.Equal(t, column(0), vt.margin.left)
+		assert.Equal(t, column(5), vt.margin.right)
+	})
+
+	t.Run("Autowrap within margins", func(t *testing.T) {
+		vt := New()
+		vt.Resize(5, 3)
+		feed(vt, "\x1b[?69h\x1b[2;4s\x1b[1;2Habcdef")
+		assert.Equal(t, " abc \n def \n     ", vt.String())
+	})
+
+	t.Run("Scroll within margins", func(t *testing.T) {
+		// vttest fills the screen and scrolls with the margins set,
+		// only the text inside the margins moves
+		vt := New()
+		vt.Resize(4, 3)
+		feed(vt, "abcd\r\nefgh\r\nijkl")
+		feed(vt, "\x1b[?69h\x1b[2;3s\x1b[3;2H\n")
+		assert.Equal(t, "afgd\nejkh\ni  l", vt.String())
+		feed(vt, "\x1b[1;2H\x1bM")
+		assert.Equal(t, "a  d\nefgh\nijkl", vt.String())
+	})
+
+	t.Run("IL and DL within margins", func(t *testing.T) {
+		vt := New()
+		vt.Resize(4, 3)
+		feed(vt, "abcd\r\nefgh\r\nijkl")
+		feed(vt, "\x1b[?69h\x1b[2;3s\x1b[1;2H\x1b[L")
+		assert.Equal(t, "a  d\nebch\nifgl", vt.String())
+		assert.Equal(t, column(1), vt.cursor.col)
+		feed(vt, "\x1b[2M")
+		assert.Equal(t, "afgd\ne  h\ni  l", vt.String())
+
+		// Ignored outside of the margins
+		feed(vt, "\x1b[1;1H\x1b[L")
+		assert.Equal(t, "afgd\ne  h\ni  l", vt.String())
+	})
+
+	t.Run("ICH and DCH within margins", func(t *testing.T) {
+		vt := New()
+		vt.Resize(5, 1)
+		feed(vt, "abcde\x1b[?69h\x1b[2;4s\x1b[1;2H\x1b[@")
+		assert.Equal(t, "a bce", vt.String())
+		feed(vt, "\x1b[2P")
+		assert.Equal(t, "ac  e", vt.String())
+	})
+
+	t.Run("Origin mode", func(t *testing.T) {
+		vt := New()
+		vt.Resize(5, 4)
+		feed(vt, "\x1b[?69h\x1b[2;4s\x1b[2;3r\x1b[?6h")
+		assert.Equal(t, column(1), vt.cursor.col)
+		assert.Equal(t, row(1), vt.cursor.row)
+		feed(vt, "\x1b[9;9Hx")
+		assert.Equal(t, "     \n     \n   x \n     ", vt.String())
+		feed(vt, "\x1b[1Gy")
+		assert.Equal(t, "     \n     \n y x \n     ", vt.String())
+	})
+}
+
+func TestDECRQM(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "DEC mode set",
+			input:    "\x1b[?7$p",
+			expected: "\x1b[?7;1$y",
+		},
+		{
+			name:     "DEC mode reset",
+			input:    "\x1b[?2026$p",
+			expected: "\x1b[?2026;2$y",
+		},
+		{
+			name:     "DEC mode set by the application",
+			input:    "\x1b[?2004h\x1b[?2004$p",
+			expected: "\x1b[?2004;1$y",
+		},
+		{
+			name:     "DEC mode permanently reset",
+			input:    "\x1b[?5$p",
+			expected: "\x1b[?5;4$y",
+		},
+		{
+			name:     "DEC mode unknown",
+			input:    "\x1b[?9999$p",
+			expected: "\x1b[?9999;0$y",
+		},
+		{
+			name:     "ANSI mode",
+			input:    "\x1b[4h\x1b[4$p",
+			expected: "\x1b[4;1$y",
+		},
+		{
+			name:     "ANSI mode unknown",
+			input:    "\x1b[7$p",
+			expected: "\x1b[7;0$y",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			vt := New()
+			vt.Resize(4, 4)
+			assert.Equal(t, test.expected, reply(t, vt, test.input))
+		})
+	}
+}
+
+func TestXTWINOPS(t *testing.T) {
+	t.Run("reports", func(t *testing.T) {
+		vt := New()
+		vt.Resize(80, 24)
+		assert.Equal(t, "\x1b[4;384;640t", reply(t, vt, "\x1b[14t"))
+		assert.Equal(t, "\x1b[8;24;80t", reply(t, vt, "\x1b[18t"))
+		vt.CellWidth, vt.CellHeight = 10, 20
+		assert.Equal(t, "\x1b[6;20;10t", reply(t, vt, "\x1b[16t"))
+		assert.Equal(t, "\x1b[4;480;800t", reply(t, vt, "\x1b[14t"))
+	})
+
+	tests := []struct {
+		name   string
+		params []int
+		op     WindowOp
+		cols   int
+		rows   int
+	}{
+		{
+			name:   "iconify",
+			params: []int{2},
+			op:     WindowIconify,
+		},
+		{
+			name:   "raise",
+			params: []int{5},
+			op:     WindowRaise,
+		},
+		{
+			name:   "resize",
+			params: []int{8, 10, 40},
+			op:     WindowResize,
+			cols:   40,
+			rows:   10,
+		},
+		{
+			name:   "resize keeping the width",
+			params: []int{8, 10},
+			op:     WindowResize,
+			cols:   80,
+			rows:   10,
+		},
+		{
+			name:   "maximize",
+			params: []int{9, 1},
+			op:     WindowMaximize,
+		},
+		{
+			name:   "unmaximize",
+			params: []int{9, 0},
+			op:     WindowUnmaximize,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			vt := New()
+			vt.Resize(80, 24)
+			vt.csi("t", test.params)
+			ev, ok := (<-vt.events).(*EventWindow)
+			assert.True(t, ok)
+			assert.Equal(t, test.op, ev.Op())
+			cols, rows := ev.Size()
+			assert.Equal(t, test.cols, cols)
+			assert.Equal(t, test.rows, rows)
+		})
+	}
+}
@@ -6,6 +6,8 @@ import (
 
 type cursor struct {
 	attrs tcell.Style
+	ext   extendedAttrs
+	link  hyperlink
 	style tcell.CursorStyle
 
 	// position
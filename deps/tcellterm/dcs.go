@@ -0,0 +1,182 @@
+package tcellterm
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// maxDCSData is the longest DCS data string which is kept. Longer strings are
+// truncated
+const maxDCSData = 4096
+
+// dcsString is a device control string being received
+type dcsString struct {
+	// id is the intermediates and final character, for example "$q"
+	id     string
+	params []int
+	data   []rune
+}
+
+// hook starts a device control string
+func (vt *VT) hook(seq DCS) {
+	id := append(seq.Intermediate, seq.Final)
+	vt.dcs = &dcsString{
+		id:     string(id),
+		params: seq.Parameters,
+	}
+}
+
+// put adds data to the device control string
+func (vt *VT) put(r rune) {
+	if vt.dcs == nil || len(vt.dcs.data) >= maxDCSData {
+		return
+	}
+	vt.dcs.data = append(vt.dcs.data, r)
+}
+
+// unhook ends the device control string and executes it
+func (vt *VT) unhook() {
+	dcs := vt.dcs
+	vt.dcs = nil
+	if dcs == nil {
+		return
+	}
+	switch dcs.id {
+	case "$q":
+		vt.decrqss(string(dcs.data))
+	case "+q":
+		vt.xtgettcap(string(dcs.data))
+	}
+}
+
+// Report xterm name and version (XTVERSION) CSI > q
+func (vt *VT) xtversion() {
+	vt.writeString("\x1bP>|tcell-term\x1b\\")
+}
+
+// Request Selection or Setting (DECRQSS) DCS $ q Pt ST
+//
+// Replies DCS 1 $ r Pt ST with the setting, or DCS 0 $ r ST if the setting is
+// not supported. Supported are SGR (m), DECSTBM (r) and DECSCUSR (SP q)
+func (vt *VT) decrqss(pt string) {
+	var setting string
+	switch pt {
+	case "m":
+		setting = vt.sgrString() + "m"
+	case "r":
+		setting = fmt.Sprintf("%d;%dr", vt.margin.top+1, vt.margin.bottom+1)
+	case " q":
+		setting = fmt.Sprintf("%d q", vt.cursor.style)
+	default:
+		vt.writeString("\x1bP0$r\x1b\\")
+		return
+	}
+	vt.writeString("\x1bP1$r" + setting + "\x1b\\")
+}
+
+// sgrString returns the SGR parameters which select the current attributes
+func (vt *VT) sgrString() string {
+	params := []string{"0"}
+	fg, bg, attrs := vt.cursor.attrs.Decompose()
+	if attrs&tcell.AttrBold != 0 {
+		params = append(params, "1")
+	}
+	if attrs&tcell.AttrDim != 0 {
+		params = append(params, "2")
+	}
+	if attrs&tcell.AttrItalic != 0 {
+		params = append(params, "3")
+	}
+	switch vt.cursor.ext.underline {
+	case underlineNone:
+	case underlineSingle:
+		params = append(params, "4")
+	default:
+		params = append(params, fmt.Sprintf("4:%d", vt.cursor.ext.underline))
+	}
+	if attrs&tcell.AttrBlink != 0 {
+		params = append(params, "5")
+	}
+	if attrs&tcell.AttrReverse != 0 {
+		params = append(params, "7")
+	}
+	if vt.cursor.ext.conceal {
+		params = append(params, "8")
+	}
+	if attrs&tcell.AttrStrikeThrough != 0 {
+		params = append(params, "9")
+	}
+	if color := sgrColor(30, fg); color != "" {
+		params = append(params, color)
+	}
+	if color := sgrColor(40, bg); color != "" {
+		params = append(params, color)
+	}
+	if color := sgrColor(50, vt.cursor.ext.underlineColor); color != "" {
+		params = append(params, color)
+	}
+	return strings.Join(params, ";")
+}
+
+// sgrColor returns the SGR parameter which selects the color. base is 30 for
+// the foreground, 40 for the background and 50 for the underline
+func sgrColor(base int, color tcell.Color) string {
+	if color == tcell.ColorDefault {
+		return ""
+	}
+	idx := int(color - tcell.ColorValid)
+	if color.IsRGB() || idx > 255 {
+		r, g, b := color.RGB()
+		return fmt.Sprintf("%d:2::%d:%d:%d", base+8, r, g, b)
+	}
+	switch {
+	case base == 50:
+	case idx < 8:
+		return fmt.Sprintf("%d", base+idx)
+	case idx < 16:
+		return fmt.Sprintf("%d", base+60+idx-8)
+	}
+	return fmt.Sprintf("%d:5:%d", base+8, idx)
+}
+
+// Request Termcap/Terminfo String (XTGETTCAP) DCS + q Pt ST
+//
+// Pt is a list of hex encoded capability names separated by semicolons. Each
+// capability is answered from the embedded tuitop entry named by TERM, or
+// else the tuitop entry, with DCS 1 + r name = value ST, or DCS 0 + r name ST
+// if it is unknown. Names and values are hex encoded in the reply
+func (vt *VT) xtgettcap(pt string) {
+	term, caps := termcaps(vt.TERM)
+	for _, hexName := range strings.Split(pt, ";") {
+		name, err := hex.DecodeString(hexName)
+		if err != nil {
+			vt.writeString("\x1bP0+r" + hexName + "\x1b\\")
+			continue
+		}
+		var (
+			value string
+			ok    bool
+		)
+		switch string(name) {
+		case "TN", "name":
+			value, ok = term, true
+		case "Co":
+			value, ok = caps["colors"]
+		default:
+			value, ok = caps[string(name)]
+		}
+		if !ok {
+			vt.writeString("\x1bP0+r" + hexName + "\x1b\\")
+			continue
+		}
+		if value == "" {
+			// Boolean capability
+			vt.writeString("\x1bP1+r" + hexName + "\x1b\\")
+			continue
+		}
+		vt.writeString("\x1bP1+r" + hexName + "=" + hex.EncodeToString([]byte(value)) + "\x1b\\")
+	}
+}
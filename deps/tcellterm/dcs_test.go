@@ -0,0 +1,154 @@
+package tcellterm
+
+import (
+	"io"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// reply feeds input to the VT and returns what it wrote to the pty
+func reply(t *testing.T, vt *VT, input string) string {
+	r, w, err := os.Pipe()
+	assert.NoError(t, err)
+	defer r.Close()
+	vt.pty = w
+	feed(vt, input)
+	vt.pty = nil
+	w.Close()
+	out, err := io.ReadAll(r)
+	assert.NoError(t, err)
+	return string(out)
+}
+
+func TestDECRQSS(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "SGR default",
+			input:    "\x1bP$qm\x1b\\",
+			expected: "\x1bP1$r0m\x1b\\",
+		},
+		{
+			name:     "SGR",
+			input:    "\x1b[1;4:3;31;48;5;100;58:2::1:2:3m\x1bP$qm\x1b\\",
+			expected: "\x1bP1$r0;1;4:3;31;48:5:100;58:2::1:2:3m\x1b\\",
+		},
+		{
+			name:     "SGR bright and RGB",
+			input:    "\x1b[8;91;48;2;1;2;3m\x1bP$qm\x1b\\",
+			expected: "\x1bP1$r0;8;91;48:2::1:2:3m\x1b\\",
+		},
+		{
+			name:     "DECSTBM",
+			input:    "\x1b[2;3r\x1bP$qr\x1b\\",
+			expected: "\x1bP1$r2;3r\x1b\\",
+		},
+		{
+			name:     "DECSCUSR",
+			input:    "\x1b[5 q\x1bP$q q\x1b\\",
+			expected: "\x1bP1$r5 q\x1b\\",
+		},
+		{
+			name:     "unsupported",
+			input:    "\x1bP$qx\x1b\\",
+			expected: "\x1bP0$r\x1b\\",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			vt := New()
+			vt.Resize(4, 4)
+			assert.Equal(t, test.expected, reply(t, vt, test.input))
+		})
+	}
+}
+
+func TestXTVERSION(t *testing.T) {
+	vt := New()
+	vt.Resize(4, 4)
+	assert.Equal(t, "\x1bP>|tcell-term\x1b\\", reply(t, vt, "\x1b[>q"))
+}
+
+func TestXTGETTCAP(t *testing.T) {
+	tests := []struct {
+		name     string
+		term     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "name",
+			term:     "tuitop-direct",
+			input:    "544e",
+			expected: "\x1bP1+r544e=747569746f702d646972656374\x1b\\",
+		},
+		{
+			name:     "string",
+			term:     "tuitop-direct",
+			input:    "736d637570",
+			expected: "\x1bP1+r736d637570=1b5b3f3130343968\x1b\\",
+		},
+		{
+			name:     "boolean and number",
+			term:     "tuitop-direct",
+			input:    "616d;436f",
+			expected: "\x1bP1+r616d\x1b\\\x1bP1+r436f=3136373737323136\x1b\\",
+		},
+		{
+			name:     "unknown",
+			term:     "tuitop",
+			input:    "7878;zz",
+			expected: "\x1bP0+r7878\x1b\\\x1bP0+rzz\x1b\\",
+		},
+		{
+			// Other TERMs are answered with what VT implements
+			name:     "other TERM",
+			term:     "xterm-256color",
+			input:    "544e;436f",
+			expected: "\x1bP1+r544e=747569746f70\x1b\\\x1bP1+r436f=323536\x1b\\",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			vt := New()
+			vt.Resize(4, 4)
+			vt.TERM = test.term
+			assert.Equal(t, test.expected, reply(t, vt, "\x1bP+q"+test.input+"\x1b\\"))
+		})
+	}
+}
+
+func TestParseTerminfo(t *testing.T) {
+	src := "# A comment\n" +
+		"base|a base entry,\n" +
+		"\tam, bce, colors#8,\n" +
+		"\tcup=\\E[%i%p1%d;%p2%dH, kbs=^?,\n" +
+		"\n" +
+		"test|alias|a test entry,\n" +
+		"\tcolors#0x100, it#8,\n" +
+		"\tcr=^M, acsc=``aa\\,\\054,\n" +
+		"\tbce@, use=base,\n"
+	base := map[string]string{
+		"am":     "",
+		"bce":    "",
+		"colors": "8",
+		"cup":    "\x1b[%i%p1%d;%p2%dH",
+		"kbs":    "\x7f",
+	}
+	test := map[string]string{
+		"am":     "",
+		"colors": "256",
+		"it":     "8",
+		"cup":    "\x1b[%i%p1%d;%p2%dH",
+		"kbs":    "\x7f",
+		"cr":     "\r",
+		"acsc":   "``aa,,",
+	}
+	expected := map[string]map[string]string{"base": base, "test": test, "alias": test}
+	assert.Equal(t, expected, parseTerminfo(src))
+}
@@ -68,7 +68,7 @@ func (vt *VT) ind() {
 // Moves cursor to the left margin of the next line, scrolling if necessary
 func (vt *VT) nel() {
 	vt.ind()
-	vt.cursor.col = vt.margin.left
+	vt.cursor.col = vt.leftEdge()
 }
 
 // Horizontal tab set ESC-H
@@ -163,8 +163,10 @@ func (vt *VT) ris() {
 		vt.altScreen[i] = make([]cell, w)
 		vt.primaryScreen[i] = make([]cell, w)
 	}
-	vt.margin.bottom = row(h) - 1
-	vt.margin.right = column(w) - 1
+	vt.margin = margin{
+		bottom: row(h) - 1,
+		right:  column(w) - 1,
+	}
 	vt.cursor.row = 0
 	vt.cursor.col = 0
 	vt.lastCol = false
@@ -179,7 +181,11 @@ func (vt *VT) ris() {
 			g3: ascii,
 		},
 	}
-	vt.mode = decawm | dectcem
+	vt.mode = defaultModes
+	vt.keyboard = keyboard{}
+	vt.syncFrame = nil
+	vt.shell = shellState{}
+	vt.clearScrollback()
 	vt.tabStop = []column{}
 	for i := 7; i < (50 * 7); i += 8 {
 		vt.tabStop = append(vt.tabStop, column(i))
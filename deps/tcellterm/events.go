@@ -63,6 +63,76 @@ type EventBell struct {
 	*EventTerminal
 }
 
+// EventNotify is emitted when the application asks for a desktop
+// notification with OSC 9 or OSC 777
+type EventNotify struct {
+	*EventTerminal
+	title string
+	body  string
+}
+
+// Title returns the title of the notification. OSC 9 notifications have none
+func (ev *EventNotify) Title() string {
+	return ev.title
+}
+
+func (ev *EventNotify) Body() string {
+	return ev.body
+}
+
+// WindowOp is a window manipulation requested by the application
+type WindowOp int
+
+const (
+	// Restore the window from being minimized
+	WindowDeiconify WindowOp = iota
+	// Minimize the window
+	WindowIconify
+	// Bring the window to the front
+	WindowRaise
+	// Resize the window to the size of the event
+	WindowResize
+	// Maximize the window
+	WindowMaximize
+	// Restore the window from being maximized
+	WindowUnmaximize
+)
+
+// EventWindow is emitted when the application asks to manipulate its window
+type EventWindow struct {
+	*EventTerminal
+	op         WindowOp
+	cols, rows int
+}
+
+func (ev *EventWindow) Op() WindowOp {
+	return ev.op
+}
+
+// Size returns the size in cells requested by WindowResize. A zero dimension
+// means the size of the screen
+func (ev *EventWindow) Size() (int, int) {
+	return ev.cols, ev.rows
+}
+
+// EventCommandDone is emitted when a command run by the shell finishes, as
+// reported with OSC 133
+type EventCommandDone struct {
+	*EventTerminal
+	exit     int
+	duration time.Duration
+}
+
+// Exit returns the exit status of the command
+func (ev *EventCommandDone) Exit() int {
+	return ev.exit
+}
+
+// Duration returns how long the command ran
+func (ev *EventCommandDone) Duration() time.Duration {
+	return ev.duration
+}
+
 type EventPanic struct {
 	*EventTerminal
 	Error error
@@ -1,7 +1,9 @@
 package tcellterm
 
 import (
+	"fmt"
 	"strings"
+	"unicode"
 
 	"github.com/gdamore/tcell/v2"
 )
@@ -22,6 +24,8 @@ func keyCode(ev *tcell.EventKey) string {
 		}
 	case tcell.ModShift:
 		switch ev.Key() {
+		case tcell.KeyRune:
+			key.WriteRune(ev.Rune())
 		case tcell.KeyUp:
 			key.WriteString(info.KeyShfUp)
 		case tcell.KeyDown:
@@ -689,3 +693,223 @@ var keyCodes = map[tcell.Key]string{
 	tcell.KeyPrint:     info.KeyPrint,
 	tcell.KeyCancel:    info.KeyCancel,
 }
+
+// encodeKey encodes the key event using the keyboard protocol negotiated by
+// the application on the active screen
+func (vt *VT) encodeKey(ev *tcell.EventKey) string {
+	if flags := vt.keyboard.flags(vt.mode&smcup != 0); flags&(kittyDisambiguate|kittyReportAll) != 0 {
+		return kittyKeyCode(ev, flags)
+	}
+	if vt.keyboard.modifyOtherKeys > 0 {
+		if str, ok := modifyOtherKeysCode(ev, vt.keyboard.modifyOtherKeys); ok {
+			return str
+		}
+	}
+	return keyCode(ev)
+}
+
+// Modifier values as encoded in CSI sequences, before adding 1
+const (
+	keyModShift = 1 << iota
+	keyModAlt
+	keyModCtrl
+	keyModSuper
+)
+
+func keyMods(mod tcell.ModMask) int {
+	var m int
+	if mod&tcell.ModShift != 0 {
+		m |= keyModShift
+	}
+	if mod&tcell.ModAlt != 0 {
+		m |= keyModAlt
+	}
+	if mod&tcell.ModCtrl != 0 {
+		m |= keyModCtrl
+	}
+	if mod&tcell.ModMeta != 0 {
+		m |= keyModSuper
+	}
+	return m
+}
+
+// otherKey resolves keys which are sent as a codepoint (text keys, and the
+// keys which legacy terminals encode as C0 codes) into the codepoint and the
+// modifiers, including any modifier implied by the tcell key. ok is false for
+// functional keys
+func otherKey(ev *tcell.EventKey) (code rune, mods int, ok bool) {
+	mods = keyMods(ev.Modifiers())
+	switch k := ev.Key(); {
+	case k == tcell.KeyRune:
+		return ev.Rune(), mods, true
+	case k == tcell.KeyBackspace2:
+		return 0x7F, mods, true
+	case k == tcell.KeyBacktab:
+		return 0x09, mods | keyModShift, true
+	case k == tcell.KeyCtrlSpace:
+		return ' ', mods | keyModCtrl, true
+	case k == tcell.KeyTab, k == tcell.KeyEnter, k == tcell.KeyEsc:
+		// These share their values with Ctrl+I, Ctrl+M and Ctrl+[.
+		// Legacy input delivers those without ModCtrl, so a set
+		// ModCtrl can only come from Ctrl+Tab, Ctrl+Enter and Ctrl+Esc
+		return rune(k), mods, true
+	case k == tcell.KeyBackspace:
+		// Backspace is always reported as DEL
+		return 0x7F, mods, true
+	case k >= tcell.KeyCtrlA && k <= tcell.KeyCtrlZ:
+		return rune(k-tcell.KeyCtrlA) + 'a', mods | keyModCtrl, true
+	case k >= tcell.KeyCtrlLeftSq && k <= tcell.KeyCtrlUnderscore:
+		return rune(k-tcell.KeyCtrlLeftSq) + '[', mods | keyModCtrl, true
+	}
+	return 0, mods, false
+}
+
+// Progressive enhancement flags of the kitty keyboard protocol
+// https://sw.kovidgoyal.net/kitty/keyboard-protocol/
+type kittyFlags int
+
+const (
+	kittyDisambiguate kittyFlags = 1 << iota
+	kittyReportEvents
+	kittyReportAlternates
+	kittyReportAll
+	kittyReportText
+
+	kittyAll = kittyDisambiguate | kittyReportEvents | kittyReportAlternates | kittyReportAll | kittyReportText
+)
+
+// kittyFunctional maps functional keys to their kitty number and final
+// character. Keys with a letter final are sent without a number unless
+// modifiers are present
+var kittyFunctional = map[tcell.Key]struct {
+	number int
+	final  rune
+}{
+	tcell.KeyInsert: {2, '~'},
+	tcell.KeyDelete: {3, '~'},
+	tcell.KeyPgUp:   {5, '~'},
+	tcell.KeyPgDn:   {6, '~'},
+	tcell.KeyUp:     {1, 'A'},
+	tcell.KeyDown:   {1, 'B'},
+	tcell.KeyRight:  {1, 'C'},
+	tcell.KeyLeft:   {1, 'D'},
+	tcell.KeyHome:   {1, 'H'},
+	tcell.KeyEnd:    {1, 'F'},
+	tcell.KeyF1:     {1, 'P'},
+	tcell.KeyF2:     {1, 'Q'},
+	tcell.KeyF3:     {13, '~'},
+	tcell.KeyF4:     {1, 'S'},
+	tcell.KeyF5:     {15, '~'},
+	tcell.KeyF6:     {17, '~'},
+	tcell.KeyF7:     {18, '~'},
+	tcell.KeyF8:     {19, '~'},
+	tcell.KeyF9:     {20, '~'},
+	tcell.KeyF10:    {21, '~'},
+	tcell.KeyF11:    {23, '~'},
+	tcell.KeyF12:    {24, '~'},
+	tcell.KeyPrint:  {57361, 'u'},
+	tcell.KeyPause:  {57362, 'u'},
+}
+
+// kittyKeyCode encodes a key press per the kitty keyboard protocol. tcell only
+// delivers key presses, so event types are never reported explicitly (press
+// is the default)
+func kittyKeyCode(ev *tcell.EventKey, flags kittyFlags) string {
+	key := strings.Builder{}
+	code, mods, ok := otherKey(ev)
+	if !ok {
+		fn, ok := kittyFunctional[ev.Key()]
+		switch {
+		case ok:
+		case ev.Key() >= tcell.KeyF13 && ev.Key() <= tcell.KeyF35:
+			fn.number = 57376 + int(ev.Key()-tcell.KeyF13)
+			fn.final = 'u'
+		default:
+			return keyCode(ev)
+		}
+		key.WriteString("\x1b[")
+		if mods != 0 || fn.final == '~' || fn.final == 'u' {
+			fmt.Fprintf(&key, "%d", fn.number)
+		}
+		if mods != 0 {
+			fmt.Fprintf(&key, ";%d", mods+1)
+		}
+		key.WriteRune(fn.final)
+		return key.String()
+	}
+
+	text := ""
+	if ev.Key() == tcell.KeyRune && mods&^keyModShift == 0 {
+		text = string(code)
+	}
+
+	if flags&kittyReportAll == 0 {
+		switch {
+		case text != "":
+			// Plain text is sent as is
+			return text
+		case mods == 0 && (code == '\r' || code == '\t' || code == 0x7F):
+			// Enter, Tab and Backspace keep their legacy encoding
+			// so a user can still type "reset" into a broken shell
+			return string(code)
+		}
+	}
+
+	shifted := rune(0)
+	if mods&keyModShift != 0 && unicode.IsUpper(code) {
+		shifted = code
+		code = unicode.ToLower(code)
+	}
+
+	key.WriteString("\x1b[")
+	fmt.Fprintf(&key, "%d", code)
+	if flags&kittyReportAlternates != 0 && shifted != 0 {
+		fmt.Fprintf(&key, ":%d", shifted)
+	}
+	switch {
+	case flags&kittyReportText != 0 && flags&kittyReportAll != 0 && text != "":
+		fmt.Fprintf(&key, ";%d;", mods+1)
+		for i, r := range text {
+			if i > 0 {
+				key.WriteRune(':')
+			}
+			fmt.Fprintf(&key, "%d", r)
+		}
+	case mods != 0:
+		fmt.Fprintf(&key, ";%d", mods+1)
+	}
+	key.WriteRune('u')
+	return key.String()
+}
+
+// modifyOtherKeysCode encodes modified keys in the xterm modifyOtherKeys
+// format CSI 27 ; mods ; code ~. At level 1 only combinations which have no
+// legacy encoding are changed, at level 2 all modified keys are. ok is false
+// if the key should use the legacy encoding
+func modifyOtherKeysCode(ev *tcell.EventKey, level int) (string, bool) {
+	code, mods, ok := otherKey(ev)
+	if !ok || mods == 0 {
+		return "", false
+	}
+	if mods == keyModShift && ev.Key() == tcell.KeyRune {
+		// Shifted text is just text
+		return "", false
+	}
+	if level < 2 {
+		switch {
+		case code == '\r', code == '\t', code == 0x7F, code == 0x1B:
+			// Modified Enter, Tab, Backspace and Escape have no
+			// legacy encoding
+		case mods&keyModCtrl != 0 && mods&keyModShift != 0:
+			// Neither does Ctrl+Shift
+		case mods&keyModCtrl != 0 && !unicode.IsLetter(code) && ev.Key() == tcell.KeyRune:
+			// Nor Ctrl with something other than a letter
+		default:
+			return "", false
+		}
+	}
+	if mods&keyModShift != 0 && ev.Key() != tcell.KeyRune {
+		code = unicode.ToUpper(code)
+	}
+	return fmt.Sprintf("\x1b[27;%d;%d~", mods+1, code), true
+}
@@ -103,3 +103,204 @@ func TestKey(t *testing.T) {
 		})
 	}
 }
+
+func TestKittyKey(t *testing.T) {
+	tests := []struct {
+		name     string
+		event    *tcell.EventKey
+		flags    kittyFlags
+		expected string
+	}{
+		{
+			name:     "rune",
+			event:    tcell.NewEventKey(tcell.KeyRune, 'j', tcell.ModNone),
+			flags:    kittyDisambiguate,
+			expected: "j",
+		},
+		{
+			name:     "escape",
+			event:    tcell.NewEventKey(tcell.KeyEsc, 0x1B, tcell.ModNone),
+			flags:    kittyDisambiguate,
+			expected: "\x1b[27u",
+		},
+		{
+			name:     "enter",
+			event:    tcell.NewEventKey(tcell.KeyEnter, '\r', tcell.ModNone),
+			flags:    kittyDisambiguate,
+			expected: "\r",
+		},
+		{
+			name:     "ctrl + enter",
+			event:    tcell.NewEventKey(tcell.KeyEnter, '\r', tcell.ModCtrl),
+			flags:    kittyDisambiguate,
+			expected: "\x1b[13;5u",
+		},
+		{
+			name:     "ctrl + tab",
+			event:    tcell.NewEventKey(tcell.KeyTab, '\t', tcell.ModCtrl),
+			flags:    kittyDisambiguate,
+			expected: "\x1b[9;5u",
+		},
+		{
+			name:     "shift + tab",
+			event:    tcell.NewEventKey(tcell.KeyBacktab, 0, tcell.ModNone),
+			flags:    kittyDisambiguate,
+			expected: "\x1b[9;2u",
+		},
+		{
+			name:     "ctrl + j",
+			event:    tcell.NewEventKey(tcell.KeyCtrlJ, '\n', tcell.ModCtrl),
+			flags:    kittyDisambiguate,
+			expected: "\x1b[106;5u",
+		},
+		{
+			name:     "ctrl + shift + a",
+			event:    tcell.NewEventKey(tcell.KeyCtrlA, 0x01, tcell.ModCtrl|tcell.ModShift),
+			flags:    kittyDisambiguate,
+			expected: "\x1b[97;6u",
+		},
+		{
+			name:     "alt + a",
+			event:    tcell.NewEventKey(tcell.KeyRune, 'a', tcell.ModAlt),
+			flags:    kittyDisambiguate,
+			expected: "\x1b[97;3u",
+		},
+		{
+			name:     "up",
+			event:    tcell.NewEventKey(tcell.KeyUp, 0, tcell.ModNone),
+			flags:    kittyDisambiguate,
+			expected: "\x1b[A",
+		},
+		{
+			name:     "ctrl + up",
+			event:    tcell.NewEventKey(tcell.KeyUp, 0, tcell.ModCtrl),
+			flags:    kittyDisambiguate,
+			expected: "\x1b[1;5A",
+		},
+		{
+			name:     "F3",
+			event:    tcell.NewEventKey(tcell.KeyF3, 0, tcell.ModNone),
+			flags:    kittyDisambiguate,
+			expected: "\x1b[13~",
+		},
+		{
+			name:     "report all: rune",
+			event:    tcell.NewEventKey(tcell.KeyRune, 'j', tcell.ModNone),
+			flags:    kittyDisambiguate | kittyReportAll,
+			expected: "\x1b[106u",
+		},
+		{
+			name:     "report all: enter",
+			event:    tcell.NewEventKey(tcell.KeyEnter, '\r', tcell.ModNone),
+			flags:    kittyReportAll,
+			expected: "\x1b[13u",
+		},
+		{
+			name:     "report all + alternates: shift + a",
+			event:    tcell.NewEventKey(tcell.KeyRune, 'A', tcell.ModShift),
+			flags:    kittyReportAll | kittyReportAlternates,
+			expected: "\x1b[97:65;2u",
+		},
+		{
+			name:     "report all + text: shift + a",
+			event:    tcell.NewEventKey(tcell.KeyRune, 'A', tcell.ModShift),
+			flags:    kittyReportAll | kittyReportText,
+			expected: "\x1b[97;2;65u",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			actual := kittyKeyCode(test.event, test.flags)
+			assert.Equal(t, test.expected, actual)
+		})
+	}
+}
+
+func TestModifyOtherKeys(t *testing.T) {
+	tests := []struct {
+		name     string
+		event    *tcell.EventKey
+		level    int
+		expected string
+	}{
+		{
+			name:     "level 1: ctrl + a",
+			event:    tcell.NewEventKey(tcell.KeyCtrlA, 0x01, tcell.ModCtrl),
+			level:    1,
+			expected: "\x01",
+		},
+		{
+			name:     "level 1: ctrl + shift + a",
+			event:    tcell.NewEventKey(tcell.KeyCtrlA, 0x01, tcell.ModCtrl|tcell.ModShift),
+			level:    1,
+			expected: "\x1b[27;6;65~",
+		},
+		{
+			name:     "level 1: ctrl + enter",
+			event:    tcell.NewEventKey(tcell.KeyEnter, '\r', tcell.ModCtrl),
+			level:    1,
+			expected: "\x1b[27;5;13~",
+		},
+		{
+			name:     "level 2: ctrl + a",
+			event:    tcell.NewEventKey(tcell.KeyCtrlA, 0x01, tcell.ModCtrl),
+			level:    2,
+			expected: "\x1b[27;5;97~",
+		},
+		{
+			name:     "level 2: alt + a",
+			event:    tcell.NewEventKey(tcell.KeyRune, 'a', tcell.ModAlt),
+			level:    2,
+			expected: "\x1b[27;3;97~",
+		},
+		{
+			name:     "level 2: shift + a",
+			event:    tcell.NewEventKey(tcell.KeyRune, 'A', tcell.ModShift),
+			level:    2,
+			expected: "A",
+		},
+		{
+			name:     "level 2: ctrl + up",
+			event:    tcell.NewEventKey(tcell.KeyUp, 0, tcell.ModCtrl),
+			level:    2,
+			expected: "\x1b[1;5A",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			vt := New()
+			vt.keyboard.modifyOtherKeys = test.level
+			actual := vt.encodeKey(test.event)
+			assert.Equal(t, test.expected, actual)
+		})
+	}
+}
+
+func TestKittyStack(t *testing.T) {
+	vt := New()
+	vt.Resize(2, 2)
+
+	vt.csi(">u", []int{1})
+	vt.csi(">u", []int{3})
+	assert.Equal(t, kittyFlags(3), vt.keyboard.flags(false))
+	vt.csi("=u", []int{8, 2})
+	assert.Equal(t, kittyFlags(11), vt.keyboard.flags(false))
+	vt.csi("=u", []int{2, 3})
+	assert.Equal(t, kittyFlags(9), vt.keyboard.flags(false))
+
+	// The alternate screen has its own stack
+	vt.decset([]int{1049})
+	assert.Equal(t, kittyFlags(0), vt.keyboard.flags(true))
+	vt.csi(">u", []int{8})
+	assert.Equal(t, "\x1b[106u", vt.encodeKey(tcell.NewEventKey(tcell.KeyRune, 'j', tcell.ModNone)))
+	vt.decrst([]int{1049})
+	assert.Equal(t, kittyFlags(9), vt.keyboard.flags(false))
+
+	vt.csi("<u", []int{})
+	assert.Equal(t, kittyFlags(1), vt.keyboard.flags(false))
+	vt.csi("<u", []int{5})
+	assert.Equal(t, kittyFlags(0), vt.keyboard.flags(false))
+	assert.Equal(t, "j", vt.encodeKey(tcell.NewEventKey(tcell.KeyRune, 'j', tcell.ModNone)))
+}
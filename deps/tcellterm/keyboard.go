@@ -0,0 +1,112 @@
+package tcellterm
+
+import "fmt"
+
+// maxKittyStack is the depth of the kitty flag stacks. When full, the oldest
+// entry is evicted
+const maxKittyStack = 16
+
+// keyboard holds the key encoding negotiated by the application. The kitty
+// protocol keeps a separate stack of flags for each screen, modifyOtherKeys is
+// global
+type keyboard struct {
+	primary         []kittyFlags
+	alt             []kittyFlags
+	modifyOtherKeys int
+}
+
+// stack returns the kitty flag stack for the primary or the alternate screen
+func (kb *keyboard) stack(alt bool) *[]kittyFlags {
+	if alt {
+		return &kb.alt
+	}
+	return &kb.primary
+}
+
+// flags returns the kitty flags in effect for the given screen
+func (kb *keyboard) flags(alt bool) kittyFlags {
+	stack := *kb.stack(alt)
+	if len(stack) == 0 {
+		return 0
+	}
+	return stack[len(stack)-1]
+}
+
+// Push kitty flags CSI > Ps u
+func (vt *VT) kittyPush(ps int) {
+	stack := vt.keyboard.stack(vt.mode&smcup != 0)
+	if len(*stack) == maxKittyStack {
+		*stack = (*stack)[1:]
+	}
+	*stack = append(*stack, kittyFlags(ps)&kittyAll)
+}
+
+// Pop kitty flags CSI < Ps u
+//
+// Pops Ps entries from the stack, default 1. Popping more entries than exist
+// empties the stack
+func (vt *VT) kittyPop(ps int) {
+	if ps == 0 {
+		ps = 1
+	}
+	stack := vt.keyboard.stack(vt.mode&smcup != 0)
+	if ps > len(*stack) {
+		ps = len(*stack)
+	}
+	*stack = (*stack)[:len(*stack)-ps]
+}
+
+// Set kitty flags CSI = Ps ; Pm u
+//
+// Pm 1 replaces the current flags, 2 sets the given bits and 3 clears them
+func (vt *VT) kittySet(pm []int) {
+	flags := kittyFlags(ps(pm)) & kittyAll
+	how := 1
+	if len(pm) > 1 {
+		how = pm[1]
+	}
+	stack := vt.keyboard.stack(vt.mode&smcup != 0)
+	if len(*stack) == 0 {
+		*stack = append(*stack, 0)
+	}
+	top := &(*stack)[len(*stack)-1]
+	switch how {
+	case 1:
+		*top = flags
+	case 2:
+		*top |= flags
+	case 3:
+		*top &^= flags
+	}
+}
+
+// Query kitty flags CSI ? u
+func (vt *VT) kittyQuery() {
+	flags := vt.keyboard.flags(vt.mode&smcup != 0)
+	vt.writeString(fmt.Sprintf("\x1b[?%du", flags))
+}
+
+// Set key modifier options (XTMODKEYS) CSI > Pp ; Pv m
+//
+// Only Pp 4 (modifyOtherKeys) is supported. Omitting Pv resets the option
+func (vt *VT) xtmodkeys(pm []int) {
+	if ps(pm) != 4 {
+		return
+	}
+	level := 0
+	if len(pm) > 1 {
+		level = pm[1]
+	}
+	if level < 0 || level > 2 {
+		return
+	}
+	vt.keyboard.modifyOtherKeys = level
+}
+
+// Query key modifier options (XTQMODKEYS) CSI ? Pp m
+func (vt *VT) xtqmodkeys(pm []int) {
+	if ps(pm) != 4 {
+		return
+	}
+	vt.writeString(fmt.Sprintf("\x1b[>4;%dm", vt.keyboard.modifyOtherKeys))
+}
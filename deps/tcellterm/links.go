@@ -0,0 +1,260 @@
+package tcellterm
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// hyperlink is a link set with OSC 8
+type hyperlink struct {
+	url string
+	id  string
+}
+
+// Link is a hyperlink in the view, either set with OSC 8 or found in the text
+type Link struct {
+	URL string
+	// The link runs from the start cell to the end cell, inclusive. It
+	// continues on the following rows when the line wraps
+	StartRow, StartCol int
+	EndRow, EndCol     int
+}
+
+// Contains reports whether the cell at row, col in the view is part of the
+// link
+func (l Link) Contains(row, col int) bool {
+	p := position{line: row, col: col}
+	return !p.before(position{line: l.StartRow, col: l.StartCol}) &&
+		!(position{line: l.EndRow, col: l.EndCol}).before(p)
+}
+
+var (
+	urlPattern = regexp.MustCompile(`\b(?:https?|ftp|file)://[^\s<>"'` + "`" + `]+`)
+	// Paths are absolute, or start with ~, . or .. and are preceded by a
+	// blank, a quote, a bracket or an equals sign
+	pathPattern = regexp.MustCompile(`(?:^|[\s'"(\[=])((?:~|\.{1,2})?/[^\s'"<>()\[\]` + "`" + `:]+)`)
+)
+
+// logicalLine is a line of text, joining rows which wrapped
+type logicalLine struct {
+	text string
+	// at is the cell of each byte of text
+	at []position
+	// links are the cells with an OSC 8 link, in order
+	links []linkedCell
+}
+
+type linkedCell struct {
+	pos  position
+	link hyperlink
+}
+
+// logicalLines returns the lines shown in the view. Positions are rows and
+// columns of the view
+func (vt *VT) logicalLines() []logicalLine {
+	return joinLines(vt.viewRows(), 0)
+}
+
+// joinLines returns the text of rows, joining rows which wrapped. The first
+// row is at line first
+func joinLines(rows [][]cell, first int) []logicalLine {
+	lines := []logicalLine{}
+	var (
+		text  strings.Builder
+		at    []position
+		links []linkedCell
+	)
+	for row, cells := range rows {
+		for col := 0; col < len(cells); col += 1 {
+			c := cells[col]
+			pos := position{line: first + row, col: col}
+			if c.link.url != "" {
+				links = append(links, linkedCell{pos: pos, link: c.link})
+			}
+			r := c.rune()
+			n, _ := text.WriteRune(r)
+			for _, comb := range c.combining {
+				m, _ := text.WriteRune(comb)
+				n += m
+			}
+			for i := 0; i < n; i += 1 {
+				at = append(at, pos)
+			}
+			if c.width == 2 {
+				// Skip the trailing cell
+				col += 1
+			}
+		}
+		if len(cells) > 0 && cells[len(cells)-1].wrapped {
+			continue
+		}
+		lines = append(lines, logicalLine{text: text.String(), at: at, links: links})
+		text.Reset()
+		at, links = nil, nil
+	}
+	if text.Len() > 0 {
+		lines = append(lines, logicalLine{text: text.String(), at: at, links: links})
+	}
+	return lines
+}
+
+// Links returns the links shown in the view: those set with OSC 8, URLs and
+// paths to existing files. Relative paths are found from the working
+// directory. Paths are returned as file:// URLs
+func (vt *VT) Links() []Link {
+	dir := vt.WorkingDir()
+	vt.mu.Lock()
+	defer vt.mu.Unlock()
+	return vt.links(dir, true)
+}
+
+// links returns the links in the view, only looking for paths, which are
+// checked for on the disk, if paths is set
+func (vt *VT) links(dir string, paths bool) []Link {
+	links := []Link{}
+	for _, line := range vt.logicalLines() {
+		// Cells already part of a link
+		taken := map[position]bool{}
+		for i := 0; i < len(line.links); {
+			start := line.links[i]
+			end := start
+			taken[start.pos] = true
+			for i += 1; i < len(line.links); i += 1 {
+				next := line.links[i]
+				if next.link != start.link || !adjacent(end.pos, next.pos, vt.width()) {
+					break
+				}
+				end = next
+				taken[next.pos] = true
+			}
+			links = append(links, newLink(start.link.url, start.pos, end.pos))
+		}
+		for _, m := range urlPattern.FindAllStringIndex(line.text, -1) {
+			url := trimURL(line.text[m[0]:m[1]])
+			end := m[0] + len(url) - 1
+			if taken[line.at[m[0]]] || taken[line.at[end]] {
+				continue
+			}
+			links = append(links, newLink(url, line.at[m[0]], line.at[end]))
+			for i := m[0]; i <= end; i += 1 {
+				taken[line.at[i]] = true
+			}
+		}
+		if !paths {
+			continue
+		}
+		for _, m := range pathPattern.FindAllStringSubmatchIndex(line.text, -1) {
+			path := trimURL(line.text[m[2]:m[3]])
+			end := m[2] + len(path) - 1
+			if taken[line.at[m[2]]] || taken[line.at[end]] {
+				continue
+			}
+			abs, ok := existingPath(path, dir)
+			if !ok {
+				continue
+			}
+			links = append(links, newLink("file://"+abs, line.at[m[2]], line.at[end]))
+		}
+	}
+	return links
+}
+
+func newLink(url string, start position, end position) Link {
+	return Link{
+		URL:      url,
+		StartRow: start.line,
+		StartCol: start.col,
+		EndRow:   end.line,
+		EndCol:   end.col,
+	}
+}
+
+// adjacent reports whether the cell at b follows the one at a, on the same
+// row or at the start of the next one. Wide characters take up two cells
+func adjacent(a position, b position, width int) bool {
+	switch {
+	case a.line == b.line:
+		return b.col-a.col <= 2
+	case b.line == a.line+1:
+		return a.col >= width-2 && b.col == 0
+	}
+	return false
+}
+
+// trimURL removes trailing punctuation which is more likely part of the
+// surrounding text. A closing bracket is kept when the URL opened it
+func trimURL(url string) string {
+	for len(url) > 0 {
+		last := url[len(url)-1]
+		switch {
+		case strings.IndexByte(".,;:!?'\"", last) >= 0:
+		case last == ')' && strings.Count(url, "(") < strings.Count(url, ")"):
+		case last == ']' && strings.Count(url, "[") < strings.Count(url, "]"):
+		default:
+			return url
+		}
+		url = url[:len(url)-1]
+	}
+	return url
+}
+
+// existingPath returns the absolute path of a file which exists
+func existingPath(path string, dir string) (string, bool) {
+	switch {
+	case path == "/":
+		return "", false
+	case strings.HasPrefix(path, "~/"):
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", false
+		}
+		path = filepath.Join(home, path[2:])
+	case !filepath.IsAbs(path):
+		if dir == "" {
+			return "", false
+		}
+		path = filepath.Join(dir, path)
+	}
+	if _, err := os.Stat(path); err != nil {
+		return "", false
+	}
+	return filepath.Clean(path), true
+}
+
+// LinkAt returns the link shown at row, col in the view
+func (vt *VT) LinkAt(row int, col int) (Link, bool) {
+	for _, link := range vt.Links() {
+		if link.Contains(row, col) {
+			return link, true
+		}
+	}
+	return Link{}, false
+}
+
+// SetHover underlines the link shown at row, col in the view, as the mouse
+// is over it. Use -1, -1 when the mouse leaves. It returns true if the
+// underlined link changed. Paths are not underlined, as finding them on the
+// disk on every move of the mouse would be slow
+func (vt *VT) SetHover(row int, col int) bool {
+	vt.mu.Lock()
+	defer vt.mu.Unlock()
+	link, ok := Link{}, false
+	if row >= 0 && col >= 0 {
+		if vt.hoverLinks == nil || vt.hoverOffset != vt.viewOffset {
+			vt.hoverLinks, vt.hoverOffset = vt.links("", false), vt.viewOffset
+		}
+		for _, l := range vt.hoverLinks {
+			if l.Contains(row, col) {
+				link, ok = l, true
+				break
+			}
+		}
+	}
+	if ok == vt.hovering && link == vt.hover {
+		return false
+	}
+	vt.hover, vt.hovering = link, ok
+	return true
+}
@@ -0,0 +1,51 @@
+package tcellterm
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLinks(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "f.txt")
+	assert.NoError(t, os.WriteFile(file, nil, 0o644))
+
+	vt := New()
+	vt.Resize(20, 4)
+	vt.workingDir = dir
+	// The link survives SGR 0
+	feed(vt, "\x1b]8;;http://a.example\x07a\x1b[1mb\x1b[0mc\x1b]8;;\x07 x\r\n")
+	// Wraps onto the next row, without the trailing period
+	feed(vt, "see https://b.example/x.\r\n")
+	feed(vt, "./f.txt /no/such")
+	assert.Equal(t, []Link{
+		{URL: "http://a.example", StartRow: 0, StartCol: 0, EndRow: 0, EndCol: 2},
+		{URL: "https://b.example/x", StartRow: 1, StartCol: 4, EndRow: 2, EndCol: 2},
+		{URL: "file://" + file, StartRow: 3, StartCol: 0, EndRow: 3, EndCol: 6},
+	}, vt.links(dir, true))
+
+	link, ok := vt.LinkAt(2, 0)
+	assert.True(t, ok)
+	assert.Equal(t, "https://b.example/x", link.URL)
+	_, ok = vt.LinkAt(0, 4)
+	assert.False(t, ok)
+
+	assert.True(t, vt.SetHover(0, 1))
+	assert.False(t, vt.SetHover(0, 2))
+	assert.True(t, vt.SetHover(-1, -1))
+	// Paths are not underlined
+	assert.False(t, vt.SetHover(3, 1))
+	// Links are found again once the screen changes
+	feed(vt, "\x1b[4;1Hhttp://c.example")
+	assert.True(t, vt.SetHover(3, 1))
+	assert.Equal(t, "http://c.example", vt.hover.URL)
+}
+
+func TestTrimURL(t *testing.T) {
+	assert.Equal(t, "http://a.example/x", trimURL("http://a.example/x)."))
+	assert.Equal(t, "http://a.example/(x)", trimURL("http://a.example/(x)"))
+	assert.Equal(t, "http://a.example/", trimURL("http://a.example/\","))
+}
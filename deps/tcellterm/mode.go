@@ -1,5 +1,10 @@
 package tcellterm
 
+import (
+	"fmt"
+	"time"
+)
+
 type mode int
 
 const (
@@ -55,8 +60,20 @@ const (
 	mouseSGR
 	// Alternate scroll
 	altScroll
+	// Report focus in and out
+	focusEvents
+	// Synchronized output
+	synchronized
+	// Left right margin mode
+	declrmm
+	// Grapheme cluster mode, set by default. Programs which count widths
+	// per codepoint may reset it
+	graphemes
 )
 
+// defaultModes are the modes set on start and on reset
+const defaultModes = decawm | dectcem | graphemes
+
 func (vt *VT) sm(params []int) {
 	for _, param := range params {
 		switch param {
@@ -101,6 +118,7 @@ func (vt *VT) decset(params []int) {
 		case 5:
 		case 6:
 			vt.mode |= decom
+			vt.home()
 		case 7:
 			vt.mode |= decawm
 			vt.lastCol = false
@@ -108,12 +126,16 @@ func (vt *VT) decset(params []int) {
 			vt.mode |= decarm
 		case 25:
 			vt.mode |= dectcem
+		case 69:
+			vt.mode |= declrmm
 		case 1000:
 			vt.mode |= mouseButtons
 		case 1002:
 			vt.mode |= mouseDrag
 		case 1003:
 			vt.mode |= mouseMotion
+		case 1004:
+			vt.mode |= focusEvents
 		case 1006:
 			vt.mode |= mouseSGR
 		case 1007:
@@ -127,6 +149,18 @@ func (vt *VT) decset(params []int) {
 			vt.mode |= altScroll
 		case 2004:
 			vt.mode |= paste
+		case 2027:
+			vt.mode |= graphemes
+		case 2026:
+			if vt.mode&synchronized == 0 {
+				// Begin synchronized update: keep presenting
+				// the current frame until the update ends
+				vt.syncFrame = copyScreen(vt.activeScreen)
+				start := time.Now()
+				vt.syncStart = start
+				time.AfterFunc(syncTimeout, func() { vt.syncTimedOut(start) })
+			}
+			vt.mode |= synchronized
 		}
 	}
 }
@@ -145,6 +179,7 @@ func (vt *VT) decrst(params []int) {
 		case 5:
 		case 6:
 			vt.mode &^= decom
+			vt.home()
 		case 7:
 			vt.mode &^= decawm
 			vt.lastCol = false
@@ -152,12 +187,18 @@ func (vt *VT) decrst(params []int) {
 			vt.mode &^= decarm
 		case 25:
 			vt.mode &^= dectcem
+		case 69:
+			vt.mode &^= declrmm
+			vt.margin.left = 0
+			vt.margin.right = column(vt.width()) - 1
 		case 1000:
 			vt.mode &^= mouseButtons
 		case 1002:
 			vt.mode &^= mouseDrag
 		case 1003:
 			vt.mode &^= mouseMotion
+		case 1004:
+			vt.mode &^= focusEvents
 		case 1006:
 			vt.mode &^= mouseSGR
 		case 1007:
@@ -173,6 +214,70 @@ func (vt *VT) decrst(params []int) {
 			vt.decrc()
 		case 2004:
 			vt.mode &^= paste
+		case 2027:
+			vt.mode &^= graphemes
+		case 2026:
+			vt.mode &^= synchronized
+			vt.syncFrame = nil
 		}
 	}
 }
+
+// ansiModes are the ANSI modes which can be reported with DECRQM, by number
+var ansiModes = map[int]mode{
+	2:  kam,
+	4:  irm,
+	12: srm,
+	20: lnm,
+}
+
+// decModes are the DEC private modes which can be reported with DECRQM, by
+// number
+var decModes = map[int]mode{
+	1:    decckm,
+	3:    deccolm,
+	4:    decsclm,
+	6:    decom,
+	7:    decawm,
+	8:    decarm,
+	25:   dectcem,
+	69:   declrmm,
+	1000: mouseButtons,
+	1002: mouseDrag,
+	1003: mouseMotion,
+	1004: focusEvents,
+	1006: mouseSGR,
+	1007: altScroll,
+	1049: smcup,
+	2004: paste,
+	2026: synchronized,
+	2027: graphemes,
+}
+
+// Request Mode (DECRQM) CSI Ps $ p and CSI ? Ps $ p
+//
+// Replies CSI Ps ; Pm $ y, or CSI ? Ps ; Pm $ y for DEC private modes. Pm is
+// 0 for unknown modes, 1 when set, 2 when reset, 3 when permanently set and 4
+// when permanently reset
+func (vt *VT) decrqm(ps int, private bool) {
+	modes := ansiModes
+	prefix := ""
+	if private {
+		modes = decModes
+		prefix = "?"
+	}
+	pm := 0
+	switch m, ok := modes[ps]; {
+	case ok && vt.mode&m != 0:
+		pm = 1
+	case ok:
+		pm = 2
+	case private && ps == 2:
+		// We never go into VT52 mode
+		pm = 3
+	case private && ps == 5:
+		// Reverse video is not supported
+		pm = 4
+	}
+	vt.writeString(fmt.Sprintf("\x1b[%s%d;%d$y", prefix, ps, pm))
+}
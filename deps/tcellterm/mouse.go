@@ -12,14 +12,23 @@ func (vt *VT) handleMouse(ev *tcell.EventMouse) string {
 			// Translate wheel motion into arrows up and down
 			// 3x rows
 			if ev.Buttons()&tcell.WheelUp != 0 {
-				vt.pty.WriteString(info.KeyUp)
-				vt.pty.WriteString(info.KeyUp)
-				vt.pty.WriteString(info.KeyUp)
+				vt.writeString(info.KeyUp)
+				vt.writeString(info.KeyUp)
+				vt.writeString(info.KeyUp)
 			}
 			if ev.Buttons()&tcell.WheelDown != 0 {
-				vt.pty.WriteString(info.KeyDown)
-				vt.pty.WriteString(info.KeyDown)
-				vt.pty.WriteString(info.KeyDown)
+				vt.writeString(info.KeyDown)
+				vt.writeString(info.KeyDown)
+				vt.writeString(info.KeyDown)
+			}
+		}
+		if vt.mode&smcup == 0 {
+			// Scroll the view through the scrollback
+			if ev.Buttons()&tcell.WheelUp != 0 {
+				vt.scrollView(3)
+			}
+			if ev.Buttons()&tcell.WheelDown != 0 {
+				vt.scrollView(-3)
 			}
 		}
 		return ""
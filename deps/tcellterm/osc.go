@@ -1,6 +1,9 @@
 package tcellterm
 
 import (
+	"fmt"
+	"net/url"
+	"os"
 	"strings"
 )
 
@@ -16,13 +19,77 @@ func (vt *VT) osc(data string) {
 			title:         val,
 		}
 		vt.postEvent(ev)
+	case "7":
+		if dir, ok := osc7(val); ok {
+			vt.workingDir = dir
+		}
+	case "9":
+		// ConEmu uses OSC 9 ; Ps ; ... for other things, such as
+		// progress reports
+		if sub, _, _ := cutString(val, ";"); isNumber(sub) {
+			return
+		}
+		vt.postEvent(&EventNotify{
+			EventTerminal: newEventTerminal(vt),
+			body:          val,
+		})
+	case "777":
+		// OSC 777 ; notify ; title ; body
+		cmd, args, _ := cutString(val, ";")
+		if cmd != "notify" {
+			return
+		}
+		title, body, _ := cutString(args, ";")
+		vt.postEvent(&EventNotify{
+			EventTerminal: newEventTerminal(vt),
+			title:         title,
+			body:          body,
+		})
+	case "133":
+		vt.osc133(val)
 	case "8":
-		if vt.OSC8 {
-			url, id := osc8(val)
-			vt.cursor.attrs = vt.cursor.attrs.Url(url)
-			vt.cursor.attrs = vt.cursor.attrs.UrlId(id)
+		url, id := osc8(val)
+		vt.cursor.link = hyperlink{url: url, id: id}
+	}
+}
+
+// parses an osc7 payload, a file:// URL of the working directory. Directories
+// on other hosts are not usable locally and are ignored
+func osc7(val string) (string, bool) {
+	u, err := url.Parse(val)
+	if err != nil || u.Scheme != "file" || u.Path == "" {
+		return "", false
+	}
+	switch u.Hostname() {
+	case "", "localhost":
+	default:
+		host, err := os.Hostname()
+		if err != nil || !strings.EqualFold(host, u.Hostname()) {
+			return "", false
 		}
 	}
+	return u.Path, true
+}
+
+// WorkingDir returns the working directory of the program, as reported with
+// OSC 7. If the program doesn't report it, the working directory of the
+// process is used. Returns "" if it is unknown
+func (vt *VT) WorkingDir() string {
+	vt.mu.Lock()
+	dir := vt.workingDir
+	cmd := vt.cmd
+	vt.mu.Unlock()
+	if dir != "" {
+		return dir
+	}
+	if cmd == nil || cmd.Process == nil {
+		return ""
+	}
+	dir, err := os.Readlink(fmt.Sprintf("/proc/%d/cwd", cmd.Process.Pid))
+	if err != nil {
+		return ""
+	}
+	return dir
 }
 
 // parses an osc8 payload into the URL and optional ID
@@ -47,6 +114,19 @@ func osc8(val string) (string, string) {
 	return url, id
 }
 
+// isNumber reports whether s is a non-empty string of digits
+func isNumber(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
 // Copied from stdlib to here for go 1.16 compat
 func cutString(s string, sep string) (before string, after string, found bool) {
 	if i := strings.Index(s, sep); i >= 0 {
@@ -1,6 +1,9 @@
 package tcellterm
 
 import (
+	"os"
+	"os/exec"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -72,3 +75,139 @@ func TestParseOSC8(t *testing.T) {
 		})
 	}
 }
+
+func TestOSC7(t *testing.T) {
+	host, err := os.Hostname()
+	assert.NoError(t, err)
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "no host",
+			input:    "file:///tmp/dir",
+			expected: "/tmp/dir",
+		},
+		{
+			name:     "escaped",
+			input:    "file://localhost/tmp/a%20b",
+			expected: "/tmp/a b",
+		},
+		{
+			name:     "this host",
+			input:    "file://" + host + "/tmp/dir",
+			expected: "/tmp/dir",
+		},
+		{
+			name:  "other host",
+			input: "file://not-" + host + "/tmp/dir",
+		},
+		{
+			name:  "not a file URL",
+			input: "https://example.com/tmp/dir",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			vt := New()
+			vt.Resize(4, 1)
+			feed(vt, "\x1b]7;"+test.input+"\x1b\\")
+			assert.Equal(t, test.expected, vt.WorkingDir())
+		})
+	}
+}
+
+func TestWorkingDirFallback(t *testing.T) {
+	if _, err := os.Stat("/proc/self/cwd"); err != nil {
+		t.Skip("no /proc")
+	}
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	assert.NoError(t, err)
+	cmd := exec.Command("sleep", "10")
+	cmd.Dir = dir
+	assert.NoError(t, cmd.Start())
+	defer cmd.Wait()
+	defer cmd.Process.Kill()
+
+	vt := New()
+	vt.Resize(4, 1)
+	vt.cmd = cmd
+	assert.Equal(t, dir, vt.WorkingDir())
+	feed(vt, "\x1b]7;file:///elsewhere\x1b\\")
+	assert.Equal(t, "/elsewhere", vt.WorkingDir())
+}
+
+func TestOSC133(t *testing.T) {
+	vt := New()
+	vt.Resize(10, 4)
+	feed(vt, "\x1b]133;A\x07$ \x1b]133;B\x07ls\r\n\x1b]133;C\x07a b\r\n\x1b]133;D;2\x07")
+	feed(vt, "\x1b]133;A\x07$ \x1b]133;B\x07")
+	assert.Equal(t, []PromptMark{
+		{Row: 0, Done: true, Exit: 2},
+		{Row: 2, Done: false},
+	}, vt.PromptMarks())
+	assert.Equal(t, zonePrompt, vt.primaryScreen[0][0].sem.zone)
+	assert.Equal(t, zoneInput, vt.primaryScreen[0][2].sem.zone)
+	assert.Equal(t, zoneOutput, vt.primaryScreen[1][0].sem.zone)
+
+	assert.True(t, vt.SelectLastOutput())
+	assert.Equal(t, "a b", vt.SelectedText())
+
+	// D without a command doesn't finish the prompt
+	feed(vt, "\r\n\x1b]133;D;1\x07")
+	assert.False(t, vt.PromptMarks()[1].Done)
+}
+
+func TestScrollToPrompt(t *testing.T) {
+	vt := New()
+	vt.Resize(10, 2)
+	for i := 0; i < 3; i += 1 {
+		feed(vt, "\x1b]133;A\x07$ \x1b]133;C\x07\r\nout\r\nout\r\n\x1b]133;D;0\x07")
+	}
+	// Lines: prompt, out, out, prompt, out, out, prompt, out, out, ""
+	assert.Equal(t, 8, len(vt.scrollback))
+	assert.True(t, vt.ScrollToPrompt(true))
+	assert.Equal(t, 2, vt.viewOffset)
+	assert.True(t, vt.ScrollToPrompt(true))
+	assert.Equal(t, 5, vt.viewOffset)
+	assert.True(t, vt.ScrollToPrompt(true))
+	assert.Equal(t, 8, vt.viewOffset)
+	assert.False(t, vt.ScrollToPrompt(true))
+	assert.True(t, vt.ScrollToPrompt(false))
+	assert.Equal(t, 5, vt.viewOffset)
+
+	// The view follows its lines as output scrolls
+	feed(vt, "more\r\n")
+	assert.Equal(t, 6, vt.viewOffset)
+	assert.Equal(t, []PromptMark{{Row: 0, Done: true}}, vt.PromptMarks())
+}
+
+func TestOSCNotify(t *testing.T) {
+	tests := []struct {
+		input string
+		title string
+		body  string
+		event bool
+	}{
+		{input: "9;build done", body: "build done", event: true},
+		{input: "9;4;1;50"},
+		{input: "777;notify;make;build done", title: "make", body: "build done", event: true},
+		{input: "777;notify;make", title: "make", event: true},
+		{input: "777;other;x;y"},
+	}
+	for _, test := range tests {
+		vt := New()
+		vt.osc(test.input)
+		select {
+		case ev := <-vt.events:
+			notify, ok := ev.(*EventNotify)
+			if assert.True(t, ok, test.input) && assert.True(t, test.event, test.input) {
+				assert.Equal(t, test.title, notify.Title(), test.input)
+				assert.Equal(t, test.body, notify.Body(), test.input)
+			}
+		default:
+			assert.False(t, test.event, test.input)
+		}
+	}
+}
@@ -188,7 +188,9 @@ func (p *Parser) param(r rune) {
 // character, and execute it, passing in the parameter list.
 //
 // csiDispatch will normalize SGR RGB sequences to a maximum of 5 parameters. IE
-// '38:2::0:0:0' will return []int{38,2,0,0,0}
+// '38:2::0:0:0' will return []int{38,2,0,0,0}. When any parameter has colon
+// separated subparameters, all parameters are also delivered unflattened in
+// SubParameters, so '4:3' can be told apart from '4;3'
 func (p *Parser) csiDispatch(r rune) {
 	csi := CSI{
 		Final:        r,
@@ -201,6 +203,14 @@ func (p *Parser) csiDispatch(r rune) {
 	}
 	paramStrRaw := strings.Split(string(p.params), ";")
 	paramStr := make([]string, 0, len(paramStrRaw))
+	if strings.Contains(string(p.params), ":") {
+		subParams, err := subParameters(paramStrRaw)
+		if err != nil {
+			p.emit(fmt.Errorf("csiDispatch: %w", err))
+			return
+		}
+		csi.SubParameters = subParams
+	}
 	for _, param := range paramStrRaw {
 		if !strings.Contains(param, ":") {
 			paramStr = append(paramStr, param)
@@ -242,6 +252,29 @@ func (p *Parser) csiDispatch(r rune) {
 	p.emit(csi)
 }
 
+// subParameters parses each parameter into the parameter followed by its colon
+// separated subparameters. Empty values are 0
+func subParameters(params []string) ([][]int, error) {
+	groups := make([][]int, 0, len(params))
+	for _, param := range params {
+		subs := strings.Split(param, ":")
+		group := make([]int, 0, len(subs))
+		for _, sub := range subs {
+			if sub == "" {
+				group = append(group, 0)
+				continue
+			}
+			val, err := strconv.Atoi(sub)
+			if err != nil {
+				return nil, err
+			}
+			group = append(group, val)
+		}
+		groups = append(groups, group)
+	}
+	return groups, nil
+}
+
 // When the control function OSC (Operating System Command) is recognised,
 // this action initializes an external parser (the “OSC Handler”) to handle
 // the characters from the control string. OSC control strings are not
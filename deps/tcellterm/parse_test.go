@@ -229,9 +229,10 @@ func TestCSI(t *testing.T) {
 			expected: []Sequence{
 				Print('a'),
 				CSI{
-					Final:        'm',
-					Parameters:   []int{38, 2, 0, 0, 0},
-					Intermediate: []rune{},
+					Final:         'm',
+					Parameters:    []int{38, 2, 0, 0, 0},
+					Intermediate:  []rune{},
+					SubParameters: [][]int{{38, 2, 0, 0, 0, 0}},
 				},
 			},
 		},
@@ -241,9 +242,10 @@ func TestCSI(t *testing.T) {
 			expected: []Sequence{
 				Print('a'),
 				CSI{
-					Final:        'm',
-					Parameters:   []int{38, 2, 0, 0, 0, 48, 2, 0, 0, 0},
-					Intermediate: []rune{},
+					Final:         'm',
+					Parameters:    []int{38, 2, 0, 0, 0, 48, 2, 0, 0, 0},
+					Intermediate:  []rune{},
+					SubParameters: [][]int{{38, 2, 0, 0, 0, 0}, {48, 2, 0, 0, 0, 0}},
 				},
 			},
 		},
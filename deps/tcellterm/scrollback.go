@@ -0,0 +1,237 @@
+package tcellterm
+
+import "strings"
+
+// scrollbackLines is the number of lines kept after they scroll off the top of
+// the primary screen
+const scrollbackLines = 2000
+
+// position is a cell in the scrollback followed by the primary screen
+type position struct {
+	line int
+	col  int
+}
+
+func (p position) before(o position) bool {
+	return p.line < o.line || (p.line == o.line && p.col < o.col)
+}
+
+// selection is a range of cells, from start to end inclusive. anchor is
+// where a selection made with the mouse started
+type selection struct {
+	active bool
+	start  position
+	end    position
+	anchor position
+}
+
+func (s selection) contains(p position) bool {
+	return s.active && !p.before(s.start) && !s.end.before(p)
+}
+
+// pushScrollback saves the top n lines of the primary screen before they
+// scroll off. Lines are only saved when the whole screen scrolls
+func (vt *VT) pushScrollback(n int) {
+	if len(vt.activeScreen) == 0 || &vt.activeScreen[0] != &vt.primaryScreen[0] {
+		return
+	}
+	if vt.margin.top != 0 || vt.margin.left != 0 || int(vt.margin.right) != vt.width()-1 {
+		return
+	}
+	for i := 0; i < n && i <= int(vt.margin.bottom); i += 1 {
+		vt.scrollback = append(vt.scrollback, copyLine(vt.primaryScreen[i]))
+		if vt.viewOffset > 0 {
+			// Keep showing the same lines
+			vt.viewOffset += 1
+		}
+	}
+	if drop := len(vt.scrollback) - scrollbackLines; drop > 0 {
+		vt.scrollback = append([][]cell(nil), vt.scrollback[drop:]...)
+		vt.selection.start.line -= drop
+		vt.selection.end.line -= drop
+		if vt.selection.start.line < 0 {
+			vt.selection = selection{}
+		}
+		vt.dropHighlights(drop)
+	}
+	if vt.viewOffset > len(vt.scrollback) {
+		vt.viewOffset = len(vt.scrollback)
+	}
+}
+
+// lines returns the number of lines in the scrollback and primary screen
+func (vt *VT) lines() int {
+	return len(vt.scrollback) + len(vt.primaryScreen)
+}
+
+// line returns a line of the scrollback followed by the primary screen
+func (vt *VT) line(i int) []cell {
+	if i < len(vt.scrollback) {
+		return vt.scrollback[i]
+	}
+	return vt.primaryScreen[i-len(vt.scrollback)]
+}
+
+// viewTop returns the line shown at the top of the screen
+func (vt *VT) viewTop() int {
+	return len(vt.scrollback) - vt.viewOffset
+}
+
+// viewRows returns the rows to draw, which are the active screen unless the
+// view is scrolled back
+func (vt *VT) viewRows() [][]cell {
+	if vt.viewOffset == 0 || vt.mode&smcup != 0 {
+		return vt.activeScreen
+	}
+	w := vt.width()
+	rows := make([][]cell, vt.height())
+	for i := range rows {
+		line := vt.line(vt.viewTop() + i)
+		if len(line) == w {
+			rows[i] = line
+			continue
+		}
+		// Lines saved before a resize
+		rows[i] = make([]cell, w)
+		copy(rows[i], line)
+	}
+	return rows
+}
+
+// ScrollView scrolls the view n lines back into the scrollback, or forward
+// when n is negative. The view returns to the screen on any key press
+func (vt *VT) ScrollView(n int) {
+	vt.mu.Lock()
+	defer vt.mu.Unlock()
+	vt.scrollView(n)
+}
+
+func (vt *VT) scrollView(n int) {
+	if vt.mode&smcup != 0 {
+		return
+	}
+	vt.setViewOffset(vt.viewOffset + n)
+}
+
+func (vt *VT) setViewOffset(offset int) {
+	switch {
+	case offset < 0:
+		offset = 0
+	case offset > len(vt.scrollback):
+		offset = len(vt.scrollback)
+	}
+	vt.viewOffset = offset
+}
+
+// ScrolledBack reports whether the view shows the scrollback
+func (vt *VT) ScrolledBack() bool {
+	vt.mu.Lock()
+	defer vt.mu.Unlock()
+	return vt.viewOffset > 0 && vt.mode&smcup == 0
+}
+
+// selected reports whether the cell drawn at row, col is selected
+func (vt *VT) selected(row int, col int) bool {
+	if !vt.selection.active || vt.mode&smcup != 0 {
+		return false
+	}
+	return vt.selection.contains(position{line: vt.viewTop() + row, col: col})
+}
+
+// ClearSelection removes the selection
+func (vt *VT) ClearSelection() {
+	vt.mu.Lock()
+	defer vt.mu.Unlock()
+	vt.selection = selection{}
+}
+
+// StartSelection clears the selection and anchors a new one at the cell
+// drawn at row, col, which ExtendSelection extends. Only the primary screen
+// can be selected
+func (vt *VT) StartSelection(row int, col int) {
+	vt.mu.Lock()
+	defer vt.mu.Unlock()
+	vt.selection = selection{anchor: vt.viewPosition(row, col)}
+}
+
+// ExtendSelection selects from the anchor to the cell drawn at row, col
+func (vt *VT) ExtendSelection(row int, col int) {
+	vt.mu.Lock()
+	defer vt.mu.Unlock()
+	if vt.mode&smcup != 0 {
+		return
+	}
+	start, end := vt.selection.anchor, vt.viewPosition(row, col)
+	if end.before(start) {
+		start, end = end, start
+	}
+	vt.selection.active, vt.selection.start, vt.selection.end = true, start, end
+}
+
+// viewPosition returns the position of the cell drawn at row, col, kept on
+// the screen
+func (vt *VT) viewPosition(row int, col int) position {
+	row = max(min(row, vt.height()-1), 0)
+	col = max(min(col, vt.width()-1), 0)
+	return position{line: vt.viewTop() + row, col: col}
+}
+
+// ClearScrollback removes the lines which scrolled off the screen
+func (vt *VT) ClearScrollback() {
+	vt.mu.Lock()
+	defer vt.mu.Unlock()
+	vt.clearScrollback()
+}
+
+// clearScrollback removes all saved lines
+func (vt *VT) clearScrollback() {
+	vt.scrollback = nil
+	vt.viewOffset = 0
+	vt.selection = selection{}
+	vt.highlights = nil
+}
+
+// SelectedText returns the selected text. Trailing blanks are removed from
+// each line, and lines which wrapped are joined
+func (vt *VT) SelectedText() string {
+	vt.mu.Lock()
+	defer vt.mu.Unlock()
+	if !vt.selection.active {
+		return ""
+	}
+	str := strings.Builder{}
+	for l := vt.selection.start.line; l <= vt.selection.end.line && l < vt.lines(); l += 1 {
+		line := vt.line(l)
+		from, to := 0, len(line)-1
+		if l == vt.selection.start.line {
+			from = vt.selection.start.col
+		}
+		if l == vt.selection.end.line && vt.selection.end.col < to {
+			to = vt.selection.end.col
+		}
+		text := strings.Builder{}
+		wrapped := false
+		for col := from; col <= to; col += 1 {
+			if line[col].content == 0 {
+				text.WriteRune(' ')
+				continue
+			}
+			text.WriteRune(line[col].content)
+			for _, r := range line[col].combining {
+				text.WriteRune(r)
+			}
+			if line[col].width == 2 {
+				// Skip the trailing cell
+				col += 1
+			}
+		}
+		if len(line) > 0 {
+			wrapped = line[len(line)-1].wrapped
+		}
+		str.WriteString(strings.TrimRight(text.String(), " "))
+		if l < vt.selection.end.line && !wrapped {
+			str.WriteRune('\n')
+		}
+	}
+	return str.String()
+}
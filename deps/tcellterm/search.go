@@ -0,0 +1,150 @@
+package tcellterm
+
+import (
+	"regexp"
+)
+
+// maxMatches is the most matches Search returns
+const maxMatches = 10000
+
+type SearchOptions struct {
+	// Regexp treats the query as a regular expression rather than literal
+	// text
+	Regexp     bool
+	IgnoreCase bool
+}
+
+// Match is text found by Search, from the start cell to the end cell,
+// inclusive. Lines count from the oldest line of the scrollback, followed by
+// the lines of the screen
+type Match struct {
+	StartLine, StartCol int
+	EndLine, EndCol     int
+}
+
+func (m Match) contains(p position) bool {
+	return !p.before(position{line: m.StartLine, col: m.StartCol}) &&
+		!(position{line: m.EndLine, col: m.EndCol}).before(p)
+}
+
+// searchRows returns the rows to search and the line of the first one. The
+// alternate screen has no scrollback
+func (vt *VT) searchRows() ([][]cell, int) {
+	if vt.mode&smcup != 0 {
+		return vt.activeScreen, len(vt.scrollback)
+	}
+	rows := make([][]cell, 0, vt.lines())
+	rows = append(rows, vt.scrollback...)
+	rows = append(rows, vt.primaryScreen...)
+	return rows, 0
+}
+
+// viewLine returns the line shown at row of the view
+func (vt *VT) viewLine(row int) int {
+	if vt.mode&smcup != 0 {
+		return len(vt.scrollback) + row
+	}
+	return vt.viewTop() + row
+}
+
+// Search finds query in the scrollback and the screen, oldest first. Lines
+// which wrapped are searched as one line
+func (vt *VT) Search(query string, opts SearchOptions) ([]Match, error) {
+	if query == "" {
+		return nil, nil
+	}
+	if !opts.Regexp {
+		query = regexp.QuoteMeta(query)
+	}
+	if opts.IgnoreCase {
+		query = "(?i)" + query
+	}
+	re, err := regexp.Compile(query)
+	if err != nil {
+		return nil, err
+	}
+	vt.mu.Lock()
+	defer vt.mu.Unlock()
+	matches := []Match{}
+	rows, first := vt.searchRows()
+	for _, line := range joinLines(rows, first) {
+		for _, m := range re.FindAllStringIndex(line.text, maxMatches-len(matches)) {
+			if m[0] == m[1] {
+				continue
+			}
+			start, end := line.at[m[0]], line.at[m[1]-1]
+			matches = append(matches, Match{
+				StartLine: start.line,
+				StartCol:  start.col,
+				EndLine:   end.line,
+				EndCol:    end.col,
+			})
+		}
+		if len(matches) >= maxMatches {
+			break
+		}
+	}
+	return matches, nil
+}
+
+// SetHighlights highlights matches of Search, with the one at current
+// standing out. Use nil to remove them
+func (vt *VT) SetHighlights(matches []Match, current int) {
+	vt.mu.Lock()
+	defer vt.mu.Unlock()
+	vt.highlights = append([]Match(nil), matches...)
+	vt.currentMatch = current
+}
+
+// highlight returns whether the cell at row, col in the view is in a match,
+// and whether that is the current match
+func (vt *VT) highlight(row int, col int) (bool, bool) {
+	p := position{line: vt.viewLine(row), col: col}
+	if vt.currentMatch >= 0 && vt.currentMatch < len(vt.highlights) && vt.highlights[vt.currentMatch].contains(p) {
+		return true, true
+	}
+	for _, m := range vt.highlights {
+		if m.StartLine > p.line {
+			break
+		}
+		if m.contains(p) {
+			return true, false
+		}
+	}
+	return false, false
+}
+
+// ShowLine scrolls the view so line is shown, in the middle of the view if
+// it was not shown already
+func (vt *VT) ShowLine(line int) {
+	vt.mu.Lock()
+	defer vt.mu.Unlock()
+	if vt.mode&smcup != 0 {
+		return
+	}
+	top := vt.viewTop()
+	if line >= top && line < top+vt.height() {
+		return
+	}
+	vt.setViewOffset(len(vt.scrollback) - line + vt.height()/2)
+}
+
+// dropHighlights moves the highlights up as n lines are dropped from the
+// scrollback. Matches on dropped lines are removed
+func (vt *VT) dropHighlights(n int) {
+	kept := vt.highlights[:0]
+	current := -1
+	for i, m := range vt.highlights {
+		if m.StartLine < n {
+			continue
+		}
+		if i == vt.currentMatch {
+			current = len(kept)
+		}
+		m.StartLine -= n
+		m.EndLine -= n
+		kept = append(kept, m)
+	}
+	vt.highlights = kept
+	vt.currentMatch = current
+}
@@ -0,0 +1,49 @@
+package tcellterm
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSearch(t *testing.T) {
+	vt := New()
+	vt.Resize(10, 3)
+	// The second Foo wraps, and the first lines scroll into the scrollback
+	feed(vt, "foo\r\nbar\r\nxxxxxxxxFoo\r\nfoo")
+
+	matches, err := vt.Search("foo", SearchOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, []Match{
+		{StartLine: 0, StartCol: 0, EndLine: 0, EndCol: 2},
+		{StartLine: 4, StartCol: 0, EndLine: 4, EndCol: 2},
+	}, matches)
+
+	matches, err = vt.Search("foo", SearchOptions{IgnoreCase: true})
+	assert.NoError(t, err)
+	assert.Equal(t, []Match{
+		{StartLine: 0, StartCol: 0, EndLine: 0, EndCol: 2},
+		{StartLine: 2, StartCol: 8, EndLine: 3, EndCol: 0},
+		{StartLine: 4, StartCol: 0, EndLine: 4, EndCol: 2},
+	}, matches)
+
+	matches, err = vt.Search("b.r", SearchOptions{Regexp: true})
+	assert.NoError(t, err)
+	assert.Equal(t, []Match{{StartLine: 1, StartCol: 0, EndLine: 1, EndCol: 2}}, matches)
+
+	matches, err = vt.Search("b.r", SearchOptions{})
+	assert.NoError(t, err)
+	assert.Empty(t, matches)
+
+	_, err = vt.Search("(", SearchOptions{Regexp: true})
+	assert.Error(t, err)
+
+	vt.ShowLine(0)
+	assert.Equal(t, 2, vt.viewOffset)
+	vt.SetHighlights([]Match{{StartLine: 0, StartCol: 0, EndLine: 0, EndCol: 2}}, 0)
+	found, current := vt.highlight(0, 1)
+	assert.True(t, found)
+	assert.True(t, current)
+	found, _ = vt.highlight(1, 1)
+	assert.False(t, found)
+}
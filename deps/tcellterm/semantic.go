@@ -0,0 +1,226 @@
+package tcellterm
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// zone is the part of a shell command a cell belongs to, as marked with OSC
+// 133
+type zone uint8
+
+const (
+	zoneNone zone = iota
+	zonePrompt
+	zoneInput
+	zoneOutput
+)
+
+// semantic is the shell integration mark of a cell
+type semantic struct {
+	zone zone
+	// prompt is set on the first cell of a prompt
+	prompt bool
+	// done and exit are set on the first cell of a prompt once the
+	// command entered at it finished
+	done bool
+	exit int
+}
+
+// shellState is the state of the shell, as reported with OSC 133
+type shellState struct {
+	zone zone
+	// promptPending marks the next printed cell as the start of a prompt
+	promptPending bool
+	// running is set while a command runs
+	running  bool
+	cmdStart time.Time
+}
+
+// mark returns the semantic mark of a printed cell
+func (vt *VT) mark() semantic {
+	sem := semantic{
+		zone:   vt.shell.zone,
+		prompt: vt.shell.promptPending,
+	}
+	vt.shell.promptPending = false
+	return sem
+}
+
+// Semantic prompt (FinalTerm) OSC 133 ; Ps [; options] ST
+//
+// A marks the start of the prompt, B the start of the command line, C the
+// start of the command's output and D ; exit the end of the command
+func (vt *VT) osc133(val string) {
+	fields := strings.Split(val, ";")
+	switch fields[0] {
+	case "A":
+		vt.shell.zone = zonePrompt
+		vt.shell.promptPending = true
+	case "B":
+		vt.shell.zone = zoneInput
+	case "C":
+		vt.shell.zone = zoneOutput
+		vt.shell.running = true
+		vt.shell.cmdStart = time.Now()
+	case "D":
+		vt.shell.zone = zoneNone
+		if !vt.shell.running {
+			// No command was entered at the prompt
+			return
+		}
+		vt.shell.running = false
+		exit := 0
+		if len(fields) > 1 {
+			exit, _ = strconv.Atoi(fields[1])
+		}
+		if pos, ok := vt.lastPrompt(vt.lines()-1, false); ok {
+			sem := &vt.line(pos.line)[pos.col].sem
+			sem.done = true
+			sem.exit = exit
+		}
+		vt.postEvent(&EventCommandDone{
+			EventTerminal: newEventTerminal(vt),
+			exit:          exit,
+			duration:      time.Since(vt.shell.cmdStart),
+		})
+	}
+}
+
+// lastPrompt returns the start of the last prompt at or before line. If done
+// is set, only prompts whose command finished are returned
+func (vt *VT) lastPrompt(line int, done bool) (position, bool) {
+	if vt.mode&smcup != 0 {
+		return position{}, false
+	}
+	for l := line; l >= 0; l -= 1 {
+		cells := vt.line(l)
+		for col := len(cells) - 1; col >= 0; col -= 1 {
+			sem := cells[col].sem
+			if sem.prompt && (sem.done || !done) {
+				return position{line: l, col: col}, true
+			}
+		}
+	}
+	return position{}, false
+}
+
+// promptLines returns the lines of the scrollback and primary screen which
+// hold the start of a prompt
+func (vt *VT) promptLines() []int {
+	lines := []int{}
+	for l := 0; l < vt.lines(); l += 1 {
+		for _, c := range vt.line(l) {
+			if c.sem.prompt {
+				lines = append(lines, l)
+				break
+			}
+		}
+	}
+	return lines
+}
+
+// PromptMark is the start of a prompt in the view
+type PromptMark struct {
+	Row int
+	// Done is set when the command entered at the prompt finished, with
+	// Exit as its exit status
+	Done bool
+	Exit int
+}
+
+// PromptMarks returns the prompts shown in the view. Shells report prompts
+// with OSC 133
+func (vt *VT) PromptMarks() []PromptMark {
+	vt.mu.Lock()
+	defer vt.mu.Unlock()
+	if vt.mode&smcup != 0 {
+		return nil
+	}
+	marks := []PromptMark{}
+	for row, line := range vt.viewRows() {
+		for _, c := range line {
+			if c.sem.prompt {
+				marks = append(marks, PromptMark{
+					Row:  row,
+					Done: c.sem.done,
+					Exit: c.sem.exit,
+				})
+				break
+			}
+		}
+	}
+	return marks
+}
+
+// ScrollToPrompt scrolls the view to show the previous prompt at the top, or
+// the next one if prev is false. It returns false if there is no such prompt
+func (vt *VT) ScrollToPrompt(prev bool) bool {
+	vt.mu.Lock()
+	defer vt.mu.Unlock()
+	if vt.mode&smcup != 0 {
+		return false
+	}
+	top := vt.viewTop()
+	target := -1
+	for _, l := range vt.promptLines() {
+		if prev && l < top {
+			target = l
+		}
+		if !prev && l > top {
+			target = l
+			break
+		}
+	}
+	if target < 0 {
+		return false
+	}
+	offset := vt.viewOffset
+	vt.setViewOffset(len(vt.scrollback) - target)
+	return vt.viewOffset != offset
+}
+
+// SelectLastOutput selects the output of the last finished command and
+// scrolls the view to show it. It returns false if there is no output
+func (vt *VT) SelectLastOutput() bool {
+	vt.mu.Lock()
+	defer vt.mu.Unlock()
+	prompt, ok := vt.lastPrompt(vt.lines()-1, true)
+	if !ok {
+		return false
+	}
+	var start, end position
+	found := false
+outer:
+	for l := prompt.line; l < vt.lines(); l += 1 {
+		cells := vt.line(l)
+		for col := range cells {
+			pos := position{line: l, col: col}
+			c := cells[col]
+			if c.sem.prompt && pos != prompt {
+				// The next prompt
+				break outer
+			}
+			if c.sem.zone != zoneOutput || c.content == 0 || c.content == ' ' {
+				continue
+			}
+			if !found {
+				start = pos
+				found = true
+			}
+			end = pos
+			if c.width == 2 {
+				end.col += 1
+			}
+		}
+	}
+	if !found {
+		return false
+	}
+	vt.selection = selection{active: true, start: start, end: end}
+	if start.line < vt.viewTop() || start.line >= vt.viewTop()+vt.height() {
+		vt.setViewOffset(len(vt.scrollback) - start.line)
+	}
+	return true
+}
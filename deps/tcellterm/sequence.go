@@ -38,6 +38,10 @@ type CSI struct {
 	Final        rune
 	Intermediate []rune
 	Parameters   []int
+	// SubParameters is nil unless a parameter has colon separated
+	// subparameters. Then it holds one entry per parameter: the parameter
+	// followed by its subparameters
+	SubParameters [][]int
 }
 
 func (seq CSI) String() string {
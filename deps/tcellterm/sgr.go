@@ -2,14 +2,48 @@ package tcellterm
 
 import "github.com/gdamore/tcell/v2"
 
+// underlineStyle is the style set with SGR 4:Ps
+type underlineStyle int
+
+const (
+	underlineNone underlineStyle = iota
+	underlineSingle
+	underlineDouble
+	underlineCurly
+	underlineDotted
+	underlineDashed
+)
+
+// extendedAttrs are the cell attributes added to its tcell.Style when drawn
+type extendedAttrs struct {
+	underline      underlineStyle
+	underlineColor tcell.Color
+	conceal        bool
+}
+
+// sgr applies SGR parameters which have no subparameters
 func (vt *VT) sgr(params []int) {
-	if len(params) == 0 {
-		params = []int{0}
+	groups := make([][]int, 0, len(params))
+	for _, param := range params {
+		groups = append(groups, []int{param})
+	}
+	vt.sgrGroups(groups)
+}
+
+// sgrGroups applies SGR parameters. Each group is a parameter followed by its
+// colon separated subparameters
+func (vt *VT) sgrGroups(groups [][]int) {
+	if len(groups) == 0 {
+		groups = [][]int{{0}}
 	}
-	for i := 0; i < len(params); i += 1 {
-		switch params[i] {
+	for i := 0; i < len(groups); i += 1 {
+		if len(groups[i]) == 0 {
+			continue
+		}
+		switch groups[i][0] {
 		case 0:
 			vt.cursor.attrs = tcell.StyleDefault
+			vt.cursor.ext = extendedAttrs{}
 		case 1:
 			vt.cursor.attrs = vt.cursor.attrs.Bold(true)
 		case 2:
@@ -17,105 +51,131 @@ func (vt *VT) sgr(params []int) {
 		case 3:
 			vt.cursor.attrs = vt.cursor.attrs.Italic(true)
 		case 4:
-			vt.cursor.attrs = vt.cursor.attrs.Underline(true)
+			style := underlineSingle
+			if len(groups[i]) > 1 {
+				style = underlineStyle(groups[i][1])
+			}
+			if style < underlineNone || style > underlineDashed {
+				// Unknown style, leave the underline as is
+				continue
+			}
+			vt.setUnderline(style)
 		case 5:
 			vt.cursor.attrs = vt.cursor.attrs.Blink(true)
 		case 7:
 			vt.cursor.attrs = vt.cursor.attrs.Reverse(true)
 		case 8:
-			// Invisible, not supported
+			vt.cursor.ext.conceal = true
 		case 9:
 			vt.cursor.attrs = vt.cursor.attrs.StrikeThrough(true)
 		case 21:
-			// Double underlined, not supported
+			vt.setUnderline(underlineDouble)
 		case 22:
 			vt.cursor.attrs = vt.cursor.attrs.Bold(false).Dim(false)
 		case 23:
 			vt.cursor.attrs = vt.cursor.attrs.Italic(false)
 		case 24:
-			vt.cursor.attrs = vt.cursor.attrs.Underline(false)
+			vt.setUnderline(underlineNone)
 		case 25:
 			vt.cursor.attrs = vt.cursor.attrs.Blink(false)
 		case 27:
 			vt.cursor.attrs = vt.cursor.attrs.Reverse(false)
 		case 28:
-			// Not invisible, not supported
+			vt.cursor.ext.conceal = false
 		case 29:
 			vt.cursor.attrs = vt.cursor.attrs.StrikeThrough(false)
 		case 30, 31, 32, 33, 34, 35, 36, 37:
-			color := tcell.PaletteColor(params[i] - 30)
+			color := tcell.PaletteColor(groups[i][0] - 30)
 			vt.cursor.attrs = vt.cursor.attrs.Foreground(color)
 		case 38:
-			var color tcell.Color
-			if len(params[i:]) < 3 {
-				// Malformed without at least 3 params. Don't
-				// set any more attributes at this point
-				return
-			}
-			switch params[i+1] {
-			case 2:
-				if len(params[i:]) < 5 {
-					// Malformed without at least5 params.
-					// Don't set any more attributes at this
-					// point
-					return
-				}
-				color = tcell.NewRGBColor(
-					int32(params[i+2]),
-					int32(params[i+3]),
-					int32(params[i+4]),
-				)
-				i += 4
-			case 5:
-				color = tcell.PaletteColor(params[i+2])
-				i += 2
-			default:
-				// Malformed
+			color, n, ok := extendedColor(groups[i:])
+			if !ok {
+				// Malformed. Don't set any more attributes at
+				// this point
 				return
 			}
+			i += n - 1
 			vt.cursor.attrs = vt.cursor.attrs.Foreground(color)
 		case 39:
 			vt.cursor.attrs = vt.cursor.attrs.Foreground(tcell.ColorDefault)
 		case 40, 41, 42, 43, 44, 45, 46, 47:
-			color := tcell.PaletteColor(params[i] - 40)
+			color := tcell.PaletteColor(groups[i][0] - 40)
 			vt.cursor.attrs = vt.cursor.attrs.Background(color)
 		case 48:
-			var color tcell.Color
-			if len(params[i:]) < 3 {
-				// Malformed without at least 3 params. Don't
-				// set any more attributes at this point
-				return
-			}
-			switch params[i+1] {
-			case 2:
-				if len(params[i:]) < 5 {
-					// Malformed without at least5 params.
-					// Don't set any more attributes at this
-					// point
-					return
-				}
-				color = tcell.NewRGBColor(
-					int32(params[i+2]),
-					int32(params[i+3]),
-					int32(params[i+4]),
-				)
-				i += 4
-			case 5:
-				color = tcell.PaletteColor(params[i+2])
-				i += 2
-			default:
-				// Malformed
+			color, n, ok := extendedColor(groups[i:])
+			if !ok {
+				// Malformed. Don't set any more attributes at
+				// this point
 				return
 			}
+			i += n - 1
 			vt.cursor.attrs = vt.cursor.attrs.Background(color)
 		case 49:
 			vt.cursor.attrs = vt.cursor.attrs.Background(tcell.ColorDefault)
+		case 58:
+			color, n, ok := extendedColor(groups[i:])
+			if !ok {
+				// Malformed. Don't set any more attributes at
+				// this point
+				return
+			}
+			i += n - 1
+			vt.cursor.ext.underlineColor = color
+		case 59:
+			vt.cursor.ext.underlineColor = tcell.ColorDefault
 		case 90, 91, 92, 93, 94, 95, 96, 97:
-			color := tcell.PaletteColor(params[i] - 90 + 8)
+			color := tcell.PaletteColor(groups[i][0] - 90 + 8)
 			vt.cursor.attrs = vt.cursor.attrs.Foreground(color)
 		case 100, 101, 102, 103, 104, 105, 106, 107:
-			color := tcell.PaletteColor(params[i] - 100 + 8)
+			color := tcell.PaletteColor(groups[i][0] - 100 + 8)
 			vt.cursor.attrs = vt.cursor.attrs.Background(color)
 		}
 	}
 }
+
+func (vt *VT) setUnderline(style underlineStyle) {
+	vt.cursor.ext.underline = style
+	vt.cursor.attrs = vt.cursor.attrs.Underline(style != underlineNone)
+}
+
+// extendedColor parses the color of an SGR 38, 48 or 58 parameter. The color
+// is either in the subparameters (38:5:Ps, 38:2::r:g:b or 38:2:r:g:b), or in
+// the parameters following it (38;5;Ps or 38;2;r;g;b). It returns the color
+// and the number of groups used
+func extendedColor(groups [][]int) (tcell.Color, int, bool) {
+	if sub := groups[0]; len(sub) > 1 {
+		switch {
+		case sub[1] == 5 && len(sub) > 2:
+			return tcell.PaletteColor(sub[2]), 1, true
+		case sub[1] == 2 && len(sub) > 5:
+			// The third value is the color space id
+			return tcell.NewRGBColor(int32(sub[3]), int32(sub[4]), int32(sub[5])), 1, true
+		case sub[1] == 2 && len(sub) == 5:
+			return tcell.NewRGBColor(int32(sub[2]), int32(sub[3]), int32(sub[4])), 1, true
+		default:
+			return tcell.ColorDefault, 0, false
+		}
+	}
+	params := make([]int, 0, 5)
+	for _, group := range groups {
+		if len(params) == 5 {
+			break
+		}
+		if len(group) != 1 {
+			// A parameter with subparameters can't be part of a
+			// semicolon separated color
+			break
+		}
+		params = append(params, group[0])
+	}
+	switch {
+	case len(params) < 3:
+		return tcell.ColorDefault, 0, false
+	case params[1] == 5:
+		return tcell.PaletteColor(params[2]), 3, true
+	case params[1] == 2 && len(params) == 5:
+		return tcell.NewRGBColor(int32(params[2]), int32(params[3]), int32(params[4])), 5, true
+	default:
+		return tcell.ColorDefault, 0, false
+	}
+}
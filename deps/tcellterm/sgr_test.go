@@ -97,3 +97,119 @@ func TestSGR(t *testing.T) {
 		})
 	}
 }
+
+func TestSGRSubParameters(t *testing.T) {
+	rgb := tcell.NewRGBColor(1, 2, 3)
+	tests := []struct {
+		name     string
+		input    string
+		attrs    tcell.Style
+		expected extendedAttrs
+	}{
+		{
+			name:     "underline",
+			input:    "\x1b[4m",
+			attrs:    tcell.StyleDefault.Underline(true),
+			expected: extendedAttrs{underline: underlineSingle},
+		},
+		{
+			name:     "curly underline",
+			input:    "\x1b[4:3m",
+			attrs:    tcell.StyleDefault.Underline(true),
+			expected: extendedAttrs{underline: underlineCurly},
+		},
+		{
+			name:  "underline style 0 is no underline",
+			input: "\x1b[4;4:0m",
+			attrs: tcell.StyleDefault,
+		},
+		{
+			name:     "4;3 is underline and italic",
+			input:    "\x1b[4;3m",
+			attrs:    tcell.StyleDefault.Underline(true).Italic(true),
+			expected: extendedAttrs{underline: underlineSingle},
+		},
+		{
+			name:     "double underline",
+			input:    "\x1b[21m",
+			attrs:    tcell.StyleDefault.Underline(true),
+			expected: extendedAttrs{underline: underlineDouble},
+		},
+		{
+			name:     "underline color RGB",
+			input:    "\x1b[58:2::1:2:3m",
+			attrs:    tcell.StyleDefault,
+			expected: extendedAttrs{underlineColor: rgb},
+		},
+		{
+			name:     "underline color 256",
+			input:    "\x1b[58;5;9m",
+			attrs:    tcell.StyleDefault,
+			expected: extendedAttrs{underlineColor: tcell.PaletteColor(9)},
+		},
+		{
+			name:  "underline color reset",
+			input: "\x1b[58;5;9;59m",
+			attrs: tcell.StyleDefault,
+		},
+		{
+			name:     "colon RGB without colorspace and bold",
+			input:    "\x1b[38:2:1:2:3;1m",
+			attrs:    tcell.StyleDefault.Foreground(rgb).Bold(true),
+			expected: extendedAttrs{},
+		},
+		{
+			name:     "conceal",
+			input:    "\x1b[8m",
+			attrs:    tcell.StyleDefault,
+			expected: extendedAttrs{conceal: true},
+		},
+		{
+			name:  "reveal",
+			input: "\x1b[8;28m",
+			attrs: tcell.StyleDefault,
+		},
+		{
+			name:  "reset",
+			input: "\x1b[4:3;58:5:1;8;0m",
+			attrs: tcell.StyleDefault,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			vt := New()
+			vt.Resize(4, 1)
+			feed(vt, test.input)
+			assert.Equal(t, test.attrs, vt.cursor.attrs)
+			assert.Equal(t, test.expected, vt.cursor.ext)
+		})
+	}
+}
+
+func TestConceal(t *testing.T) {
+	vt := New()
+	vt.Resize(2, 1)
+	srf := newCountingSurface(2, 1)
+	vt.SetSurface(srf)
+
+	feed(vt, "\x1b[8ma")
+	vt.Draw()
+	assert.Equal(t, 'a', vt.activeScreen[0][0].content)
+	assert.Equal(t, ' ', srf.cells[[2]int{0, 0}])
+}
+
+func TestUnderlineIsDrawn(t *testing.T) {
+	screen := tcell.NewSimulationScreen("")
+	assert.NoError(t, screen.Init())
+	screen.SetSize(2, 1)
+	vt := New()
+	vt.Resize(2, 1)
+	vt.SetSurface(screen)
+
+	feed(vt, "\x1b[4:3;58:2::255:0:0ma\x1b[4:5;59mb")
+	vt.Draw()
+	_, _, style, _ := screen.GetContent(0, 0)
+	assert.Equal(t, tcell.StyleDefault.Underline(tcell.UnderlineStyleCurly, tcell.NewRGBColor(255, 0, 0)), style)
+	_, _, style, _ = screen.GetContent(1, 0)
+	assert.Equal(t, tcell.StyleDefault.Underline(tcell.UnderlineStyleDashed), style)
+}
@@ -0,0 +1,233 @@
+package tcellterm
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// NewHeadless returns a terminal of w columns and h rows which isn't connected
+// to a program. Output is fed to it with Feed
+func NewHeadless(w int, h int) *VT {
+	vt := New()
+	vt.Resize(w, h)
+	return vt
+}
+
+// Feed runs the terminal on program output read from r, until r returns an
+// error or io.EOF. It needs no program or pty, so recorded output can be
+// replayed. Events go to the handler set with Attach, and replies to the
+// program are dropped. Sequences split between two calls are not joined
+func (vt *VT) Feed(r io.Reader) {
+	parser := NewParser(r)
+	for {
+		seq := parser.Next()
+		if _, ok := seq.(EOF); ok || seq == nil {
+			return
+		}
+		vt.update(seq)
+		for drained := false; !drained; {
+			select {
+			case ev := <-vt.events:
+				vt.eventHandler(ev)
+			default:
+				drained = true
+			}
+		}
+	}
+}
+
+// modeNames are the names of the modes, in the order of their bits
+var modeNames = []string{
+	"kam", "irm", "srm", "lnm",
+	"decckm", "decanm", "deccolm", "decsclm", "decom", "decawm", "decarm",
+	"decpff", "decpex", "dectcem", "decnrcm",
+	"smcup", "paste", "mouseButtons", "mouseDrag", "mouseMotion", "mouseSGR",
+	"altScroll", "focusEvents", "synchronized", "declrmm", "graphemes",
+}
+
+var cursorStyleNames = []string{
+	"default", "blinking-block", "steady-block", "blinking-underline",
+	"steady-underline", "blinking-bar", "steady-bar",
+}
+
+var underlineNames = []string{"none", "single", "double", "curly", "dotted", "dashed"}
+
+// Snapshot returns the state of the screen as text, for comparing with an
+// expected state. For example:
+//
+//	size 10x2
+//	cursor 1,3 visible steady-block
+//	margins 0-1 0-9
+//	modes decawm dectcem
+//	screen
+//	|foo       |
+//	|界 c      |
+//	attrs
+//	0:0-2 fg=1 bold
+//	1:0 wide
+//
+// The cursor and margins are given as row,col and as row and column ranges,
+// counting from 0. Each row of the screen is between bars. The attrs list the
+// cells which have other than default attributes by row:col or
+// row:firstcol-lastcol
+func (vt *VT) Snapshot() string {
+	vt.mu.Lock()
+	defer vt.mu.Unlock()
+	str := strings.Builder{}
+	fmt.Fprintf(&str, "size %dx%d\n", vt.width(), vt.height())
+	vis := "hidden"
+	if vt.mode&dectcem != 0 {
+		vis = "visible"
+	}
+	style := fmt.Sprintf("%d", vt.cursor.style)
+	if int(vt.cursor.style) < len(cursorStyleNames) {
+		style = cursorStyleNames[vt.cursor.style]
+	}
+	fmt.Fprintf(&str, "cursor %d,%d %s %s\n", vt.cursor.row, vt.cursor.col, vis, style)
+	fmt.Fprintf(&str, "margins %d-%d %d-%d\n", vt.margin.top, vt.margin.bottom, vt.margin.left, vt.margin.right)
+	str.WriteString("modes")
+	for i, name := range modeNames {
+		if vt.mode&(1<<i) != 0 {
+			str.WriteString(" " + name)
+		}
+	}
+	str.WriteString("\nscreen\n")
+	for _, line := range vt.activeScreen {
+		str.WriteRune('|')
+		for col := 0; col < len(line); col += 1 {
+			str.WriteRune(line[col].rune())
+			for _, r := range line[col].combining {
+				str.WriteRune(r)
+			}
+			if line[col].width == 2 {
+				// The character fills the trailing cell
+				col += 1
+			}
+		}
+		str.WriteString("|\n")
+	}
+	str.WriteString("attrs\n")
+	for row, line := range vt.activeScreen {
+		for col := 0; col < len(line); {
+			desc := line[col].describe()
+			end := col
+			for end+1 < len(line) && line[end+1].describe() == desc {
+				end += 1
+			}
+			switch {
+			case desc == "":
+			case end == col:
+				fmt.Fprintf(&str, "%d:%d %s\n", row, col, desc)
+			default:
+				fmt.Fprintf(&str, "%d:%d-%d %s\n", row, col, end, desc)
+			}
+			col = end + 1
+		}
+	}
+	return str.String()
+}
+
+// describe returns the attributes of the cell which are not the default, as
+// space separated words
+func (c *cell) describe() string {
+	words := []string{}
+	if c.width == 2 {
+		words = append(words, "wide")
+	}
+	fg, bg, attrs := c.attrs.Decompose()
+	if fg != tcell.ColorDefault {
+		words = append(words, "fg="+colorName(fg))
+	}
+	if bg != tcell.ColorDefault {
+		words = append(words, "bg="+colorName(bg))
+	}
+	for _, a := range []struct {
+		mask tcell.AttrMask
+		name string
+	}{
+		{tcell.AttrBold, "bold"},
+		{tcell.AttrDim, "dim"},
+		{tcell.AttrItalic, "italic"},
+		{tcell.AttrBlink, "blink"},
+		{tcell.AttrReverse, "reverse"},
+		{tcell.AttrStrikeThrough, "strike"},
+	} {
+		if attrs&a.mask != 0 {
+			words = append(words, a.name)
+		}
+	}
+	switch {
+	case c.ext.underline > underlineSingle && int(c.ext.underline) < len(underlineNames):
+		words = append(words, "underline="+underlineNames[c.ext.underline])
+	case attrs&tcell.AttrUnderline != 0:
+		words = append(words, "underline")
+	}
+	if c.ext.underlineColor != tcell.ColorDefault {
+		words = append(words, "ulcolor="+colorName(c.ext.underlineColor))
+	}
+	if c.ext.conceal {
+		words = append(words, "conceal")
+	}
+	if c.link.url != "" {
+		words = append(words, "link="+c.link.url)
+	}
+	if c.wrapped {
+		words = append(words, "wrapped")
+	}
+	return strings.Join(words, " ")
+}
+
+// colorName returns the palette index of a color, or #rrggbb
+func colorName(color tcell.Color) string {
+	if color.IsRGB() {
+		return fmt.Sprintf("#%06x", color.Hex())
+	}
+	return fmt.Sprintf("%d", color-tcell.ColorValid)
+}
+
+// UpdateGoldenEnv is the environment variable which makes CheckGolden write
+// golden files rather than compare with them
+const UpdateGoldenEnv = "TCELLTERM_UPDATE_GOLDEN"
+
+// CheckGolden compares a snapshot with the golden file at path. The file is
+// written instead if UpdateGoldenEnv is set, and a missing one is an error
+// otherwise. The error shows the first line which differs
+func CheckGolden(path string, snapshot string) error {
+	if os.Getenv(UpdateGoldenEnv) != "" {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return err
+		}
+		return os.WriteFile(path, []byte(snapshot), 0o644)
+	}
+	golden, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("%s is missing, set %s=1 to write it", path, UpdateGoldenEnv)
+	}
+	if err != nil {
+		return err
+	}
+	if string(golden) == snapshot {
+		return nil
+	}
+	want := strings.Split(string(golden), "\n")
+	got := strings.Split(snapshot, "\n")
+	for i := 0; i < len(want) || i < len(got); i += 1 {
+		var w, g string
+		if i < len(want) {
+			w = want[i]
+		}
+		if i < len(got) {
+			g = got[i]
+		}
+		if w != g {
+			return fmt.Errorf("%s:%d: want %q, got %q", path, i+1, w, g)
+		}
+	}
+	return fmt.Errorf("%s: snapshot differs", path)
+}
@@ -0,0 +1,73 @@
+package tcellterm
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestModeNames(t *testing.T) {
+	assert.Equal(t, graphemes, mode(1<<(len(modeNames)-1)))
+}
+
+func TestSnapshot(t *testing.T) {
+	vt := NewHeadless(10, 3)
+	vt.Feed(strings.NewReader("\x1b[1;31mfoo\x1b[m\r\n界 \x1b[4:3;58:2::255:0:0mc\x1b[m\r\n\x1b[2 qlong line\x1b[?25l"))
+	assert.Equal(t, `size 10x3
+cursor 2,9 hidden steady-block
+margins 0-2 0-9
+modes decawm graphemes
+screen
+|foo       |
+|界 c      |
+|long line |
+attrs
+0:0-2 fg=1 bold
+1:0 wide
+1:3 underline=curly ulcolor=#ff0000
+`, vt.Snapshot())
+}
+
+func TestCheckGolden(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "screen.golden")
+	vt := NewHeadless(4, 1)
+	vt.Feed(strings.NewReader("ab"))
+	// A missing file is only written when updating
+	assert.EqualError(t, CheckGolden(path, vt.Snapshot()), path+" is missing, set "+UpdateGoldenEnv+"=1 to write it")
+	t.Setenv(UpdateGoldenEnv, "1")
+	assert.NoError(t, CheckGolden(path, vt.Snapshot()))
+	os.Unsetenv(UpdateGoldenEnv)
+	assert.NoError(t, CheckGolden(path, vt.Snapshot()))
+
+	vt.Feed(strings.NewReader("c"))
+	err := CheckGolden(path, vt.Snapshot())
+	assert.EqualError(t, err, path+`:2: want "cursor 0,2 visible default", got "cursor 0,3 visible default"`)
+
+	os.Setenv(UpdateGoldenEnv, "1")
+	assert.NoError(t, CheckGolden(path, vt.Snapshot()))
+	os.Unsetenv(UpdateGoldenEnv)
+	assert.NoError(t, CheckGolden(path, vt.Snapshot()))
+}
+
+// TestGoldenScreens replays the recorded output in testdata/*.out and
+// compares the screen with testdata/*.golden. Set TCELLTERM_UPDATE_GOLDEN=1
+// to record the screens again after an intended change
+func TestGoldenScreens(t *testing.T) {
+	recordings, err := filepath.Glob("testdata/*.out")
+	assert.NoError(t, err)
+	assert.NotEmpty(t, recordings)
+	for _, recording := range recordings {
+		f, err := os.Open(recording)
+		if !assert.NoError(t, err) {
+			continue
+		}
+		vt := NewHeadless(40, 6)
+		vt.Feed(f)
+		f.Close()
+		golden := strings.TrimSuffix(recording, ".out") + ".golden"
+		assert.NoError(t, CheckGolden(golden, vt.Snapshot()), recording)
+	}
+}
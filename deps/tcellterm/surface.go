@@ -13,3 +13,13 @@ type Surface interface {
 	// Size represents the visible size.
 	Size() (int, int)
 }
+
+// ReadableSurface is a Surface whose content can be read back, such as a
+// tcell.Screen. When drawing to one, cells which someone else drew over since
+// the last Draw are drawn again
+type ReadableSurface interface {
+	Surface
+
+	// GetContent returns the content of the Surface at the given location.
+	GetContent(x, y int) (primary rune, combining []rune, style tcell.Style, width int)
+}
@@ -1,6 +1,66 @@
 package tcellterm
 
-import "github.com/gdamore/tcell/v2/terminfo"
+import (
+	_ "embed"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/gdamore/tcell/v2/terminfo"
+)
+
+// TuitopTerminfo is the terminfo source of the tuitop and tuitop-direct
+// entries, which describe exactly what VT implements. Compile it with
+// tic -x
+//
+//go:embed tuitop.info
+var TuitopTerminfo []byte
+
+// fallbackTERM is the TERM used when the tuitop entry is not installed
+const fallbackTERM = "xterm-256color"
+
+// defaultTERM returns the TERM used when none is set
+func defaultTERM() string {
+	if TerminfoInstalled("tuitop") {
+		return "tuitop"
+	}
+	return fallbackTERM
+}
+
+// TerminfoInstalled reports whether a compiled terminfo entry for name is in
+// one of the directories ncurses searches
+func TerminfoInstalled(name string) bool {
+	if name == "" {
+		return false
+	}
+	dirs := []string{os.Getenv("TERMINFO")}
+	if home, err := os.UserHomeDir(); err == nil {
+		dirs = append(dirs, filepath.Join(home, ".terminfo"))
+	}
+	dirs = append(dirs, filepath.SplitList(os.Getenv("TERMINFO_DIRS"))...)
+	dirs = append(dirs,
+		"/etc/terminfo",
+		"/lib/terminfo",
+		"/usr/share/terminfo",
+		"/usr/lib/terminfo",
+		"/usr/local/share/terminfo",
+	)
+	for _, dir := range dirs {
+		if dir == "" {
+			continue
+		}
+		// Entries are filed by their first letter, or its hex code on
+		// case insensitive filesystems
+		for _, sub := range []string{name[:1], fmt.Sprintf("%x", name[0])} {
+			if _, err := os.Stat(filepath.Join(dir, sub, name)); err == nil {
+				return true
+			}
+		}
+	}
+	return false
+}
 
 // extended terminfo defines additional keys in a singular place, if missing
 // from the terminfo.Terminfo struct
@@ -287,3 +347,190 @@ var info = &terminfo.Terminfo{
 	ExitUrl:                 "\x1b]8;;\x1b\\",
 	SetWindowSize:           "",
 }
+
+// tuitopEntries are the capabilities of the entries of TuitopTerminfo, by name
+var tuitopEntries = parseTerminfo(string(TuitopTerminfo))
+
+// termcaps returns the name and capabilities of the entry of TuitopTerminfo
+// named name, or else of the tuitop entry, as those describe what VT
+// implements whatever TERM says. Boolean capabilities have an empty value,
+// numbers are in decimal
+func termcaps(name string) (string, map[string]string) {
+	if caps, ok := tuitopEntries[name]; ok {
+		return name, caps
+	}
+	return "tuitop", tuitopEntries["tuitop"]
+}
+
+// parseTerminfo parses terminfo source, such as tic reads or infocmp prints,
+// into the capabilities of each entry by name. Entries using another get its
+// capabilities, unless they cancel them
+func parseTerminfo(src string) map[string]map[string]string {
+	type entry struct {
+		caps      map[string]string
+		cancelled map[string]bool
+		uses      []string
+	}
+	entries := map[string]*entry{}
+	var e *entry
+	for _, line := range strings.Split(src, "\n") {
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := splitCapabilities(line)
+		if !strings.HasPrefix(line, "\t") && !strings.HasPrefix(line, " ") {
+			// The names of a new entry
+			e = &entry{caps: map[string]string{}, cancelled: map[string]bool{}}
+			names := strings.Split(fields[0], "|")
+			for _, name := range names[:max(len(names)-1, 1)] {
+				entries[name] = e
+			}
+			continue
+		}
+		if e == nil {
+			continue
+		}
+		for _, field := range fields {
+			switch name, val, _ := strings.Cut(field, "="); {
+			case name == "use":
+				e.uses = append(e.uses, val)
+			case strings.HasSuffix(field, "@"):
+				e.cancelled[strings.TrimSuffix(field, "@")] = true
+			default:
+				parseCapability(e.caps, field)
+			}
+		}
+	}
+	parsed := map[string]map[string]string{}
+	var resolve func(name string, seen map[string]bool) map[string]string
+	resolve = func(name string, seen map[string]bool) map[string]string {
+		if caps, ok := parsed[name]; ok {
+			return caps
+		}
+		e, ok := entries[name]
+		if !ok || seen[name] {
+			return nil
+		}
+		seen[name] = true
+		caps := map[string]string{}
+		// Capabilities given before a use= win, as do those of the entry
+		for i := len(e.uses) - 1; i >= 0; i -= 1 {
+			for k, v := range resolve(e.uses[i], seen) {
+				caps[k] = v
+			}
+		}
+		for k := range e.cancelled {
+			delete(caps, k)
+		}
+		for k, v := range e.caps {
+			caps[k] = v
+		}
+		parsed[name] = caps
+		return caps
+	}
+	for name := range entries {
+		resolve(name, map[string]bool{})
+	}
+	return parsed
+}
+
+// splitCapabilities splits a line of terminfo source at its unescaped commas
+func splitCapabilities(line string) []string {
+	fields := []string{}
+	start := 0
+	for i := 0; i < len(line); i += 1 {
+		switch line[i] {
+		case '\\', '^':
+			i += 1
+		case ',':
+			fields = append(fields, strings.TrimSpace(line[start:i]))
+			start = i + 1
+		}
+	}
+	if rest := strings.TrimSpace(line[start:]); rest != "" {
+		fields = append(fields, rest)
+	}
+	n := 0
+	for _, f := range fields {
+		if f != "" {
+			fields[n] = f
+			n += 1
+		}
+	}
+	return fields[:n]
+}
+
+// parseCapability adds the capability field, such as am, colors#256 or
+// cr=^M, to caps
+func parseCapability(caps map[string]string, field string) {
+	if name, val, ok := strings.Cut(field, "="); ok {
+		caps[name] = unescapeTerminfo(val)
+		return
+	}
+	if name, val, ok := strings.Cut(field, "#"); ok {
+		n, err := strconv.ParseInt(val, 0, 64)
+		if err == nil {
+			caps[name] = strconv.FormatInt(n, 10)
+		}
+		return
+	}
+	caps[field] = ""
+}
+
+// unescapeTerminfo decodes the escapes of a terminfo string capability
+func unescapeTerminfo(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i += 1 {
+		switch {
+		case s[i] == '^' && i+1 < len(s):
+			i += 1
+			if s[i] == '?' {
+				b.WriteByte(0x7F)
+				continue
+			}
+			b.WriteByte(s[i] & 0x1F)
+		case s[i] == '\\' && i+1 < len(s):
+			i += 1
+			switch c := s[i]; c {
+			case 'E', 'e':
+				b.WriteByte(0x1B)
+			case 'n', 'l':
+				b.WriteByte('\n')
+			case 'r':
+				b.WriteByte('\r')
+			case 't':
+				b.WriteByte('\t')
+			case 'b':
+				b.WriteByte('\b')
+			case 'f':
+				b.WriteByte('\f')
+			case 's':
+				b.WriteByte(' ')
+			case '0', '1', '2', '3':
+				// Octal. A lone \0 is a null, which terminfo
+				// stores as \200
+				if i+2 < len(s) && isOctal(s[i+1]) && isOctal(s[i+2]) {
+					n, _ := strconv.ParseUint(s[i:i+3], 8, 8)
+					b.WriteByte(byte(n))
+					i += 2
+					continue
+				}
+				if c == '0' {
+					b.WriteByte(0x80)
+					continue
+				}
+				b.WriteByte(c)
+			default:
+				// \\, \^, \, and \: are the character itself
+				b.WriteByte(c)
+			}
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String()
+}
+
+func isOctal(c byte) bool {
+	return c >= '0' && c <= '7'
+}
@@ -0,0 +1,182 @@
+package tcellterm
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/gdamore/tcell/v2/terminfo"
+	"github.com/stretchr/testify/assert"
+)
+
+// tuitopCaps returns the capabilities of the tuitop entry in tuitop.info
+func tuitopCaps(t *testing.T) map[string]string {
+	caps := tuitopEntries["tuitop"]
+	assert.NotEmpty(t, caps)
+	return caps
+}
+
+// switchCases returns the case labels of the switch statements in the
+// function fn in file
+func switchCases(t *testing.T, file string, fn string) map[string]bool {
+	f, err := parser.ParseFile(token.NewFileSet(), file, nil, 0)
+	assert.NoError(t, err)
+	cases := map[string]bool{}
+	for _, decl := range f.Decls {
+		fd, ok := decl.(*ast.FuncDecl)
+		if !ok || fd.Name.Name != fn {
+			continue
+		}
+		ast.Inspect(fd, func(n ast.Node) bool {
+			clause, ok := n.(*ast.CaseClause)
+			if !ok {
+				return true
+			}
+			for _, expr := range clause.List {
+				lit, ok := expr.(*ast.BasicLit)
+				if !ok {
+					continue
+				}
+				switch lit.Kind {
+				case token.STRING:
+					s, err := strconv.Unquote(lit.Value)
+					assert.NoError(t, err)
+					cases[s] = true
+				case token.INT:
+					n, err := strconv.ParseInt(lit.Value, 0, 32)
+					assert.NoError(t, err)
+					cases[strconv.Itoa(int(n))] = true
+				}
+			}
+			return true
+		})
+	}
+	assert.NotEmpty(t, cases, "no cases in %s", fn)
+	return cases
+}
+
+func TestTuitopTerminfoKeys(t *testing.T) {
+	caps := tuitopCaps(t)
+	keys := map[string]*tcell.EventKey{
+		"kbs":   tcell.NewEventKey(tcell.KeyBackspace, 0, tcell.ModNone),
+		"kcbt":  tcell.NewEventKey(tcell.KeyBacktab, 0, tcell.ModNone),
+		"kcub1": tcell.NewEventKey(tcell.KeyLeft, 0, tcell.ModNone),
+		"kcud1": tcell.NewEventKey(tcell.KeyDown, 0, tcell.ModNone),
+		"kcuf1": tcell.NewEventKey(tcell.KeyRight, 0, tcell.ModNone),
+		"kcuu1": tcell.NewEventKey(tcell.KeyUp, 0, tcell.ModNone),
+		"kdch1": tcell.NewEventKey(tcell.KeyDelete, 0, tcell.ModNone),
+		"kend":  tcell.NewEventKey(tcell.KeyEnd, 0, tcell.ModNone),
+		"khome": tcell.NewEventKey(tcell.KeyHome, 0, tcell.ModNone),
+		"kich1": tcell.NewEventKey(tcell.KeyInsert, 0, tcell.ModNone),
+		"knp":   tcell.NewEventKey(tcell.KeyPgDn, 0, tcell.ModNone),
+		"kpp":   tcell.NewEventKey(tcell.KeyPgUp, 0, tcell.ModNone),
+		"kDC":   tcell.NewEventKey(tcell.KeyDelete, 0, tcell.ModShift),
+		"kEND":  tcell.NewEventKey(tcell.KeyEnd, 0, tcell.ModShift),
+		"kHOM":  tcell.NewEventKey(tcell.KeyHome, 0, tcell.ModShift),
+		"kIC":   tcell.NewEventKey(tcell.KeyInsert, 0, tcell.ModShift),
+		"kLFT":  tcell.NewEventKey(tcell.KeyLeft, 0, tcell.ModShift),
+		"kNXT":  tcell.NewEventKey(tcell.KeyPgDn, 0, tcell.ModShift),
+		"kPRV":  tcell.NewEventKey(tcell.KeyPgUp, 0, tcell.ModShift),
+		"kRIT":  tcell.NewEventKey(tcell.KeyRight, 0, tcell.ModShift),
+		"kind":  tcell.NewEventKey(tcell.KeyDown, 0, tcell.ModShift),
+		"kri":   tcell.NewEventKey(tcell.KeyUp, 0, tcell.ModShift),
+	}
+	for i := 1; i <= 63; i += 1 {
+		keys["kf"+strconv.Itoa(i)] = tcell.NewEventKey(tcell.KeyF1+tcell.Key(i-1), 0, tcell.ModNone)
+	}
+	// Not keys
+	reports := map[string]bool{"kmous": true, "kxIN": true, "kxOUT": true}
+
+	vt := New()
+	vt.Resize(4, 4)
+	for name, val := range caps {
+		if !strings.HasPrefix(name, "k") || reports[name] {
+			continue
+		}
+		ev, ok := keys[name]
+		if !assert.True(t, ok, "%s is not a known key", name) {
+			continue
+		}
+		assert.Equal(t, val, vt.encodeKey(ev), name)
+	}
+	for name := range keys {
+		assert.Contains(t, caps, name)
+	}
+	assert.Equal(t, "\x1b[<", caps["kmous"])
+	assert.Equal(t, "\x1b[I", caps["kxIN"])
+	assert.Equal(t, "\x1b[O", caps["kxOUT"])
+}
+
+func TestTuitopTerminfoSequences(t *testing.T) {
+	caps := tuitopCaps(t)
+	c0s := switchCases(t, "c0.go", "c0")
+	escs := switchCases(t, "esc.go", "esc")
+	csis := switchCases(t, "csi.go", "csi")
+	sgrs := switchCases(t, "sgr.go", "sgrGroups")
+
+	// Sent by the terminal rather than to it
+	skip := map[string]bool{"xm": true, "PS": true, "PE": true}
+	ti := &terminfo.Terminfo{}
+	for name, val := range caps {
+		if val == "" || strings.HasPrefix(name, "k") || skip[name] {
+			continue
+		}
+		// The first parameter is printable, for rep
+		seq := ti.TParm(val, int('A'), 2, 3, 4, 5, 6, 7, 8, 9)
+		p := NewParser(strings.NewReader(seq))
+		for {
+			s := p.Next()
+			if _, ok := s.(EOF); ok || s == nil {
+				break
+			}
+			switch s := s.(type) {
+			case C0:
+				assert.True(t, c0s[strconv.Itoa(int(s))], "%s: C0 %#x not handled", name, rune(s))
+			case ESC:
+				id := string(append(s.Intermediate, s.Final))
+				assert.True(t, escs[id], "%s: ESC %q not handled", name, id)
+			case CSI:
+				id := string(append(s.Intermediate, s.Final))
+				if !assert.True(t, csis[id], "%s: CSI %q not handled", name, id) {
+					continue
+				}
+				switch id {
+				case "?h", "?l":
+					for _, param := range s.Parameters {
+						_, ok := decModes[param]
+						assert.True(t, ok, "%s: DEC mode %d not handled", name, param)
+					}
+				case "h", "l":
+					for _, param := range s.Parameters {
+						_, ok := ansiModes[param]
+						assert.True(t, ok, "%s: ANSI mode %d not handled", name, param)
+					}
+				case "m":
+					groups := s.SubParameters
+					if groups == nil {
+						for _, param := range s.Parameters {
+							groups = append(groups, []int{param})
+						}
+					}
+					for i := 0; i < len(groups); i += 1 {
+						group := groups[i]
+						assert.True(t, sgrs[strconv.Itoa(group[0])], "%s: SGR %d not handled", name, group[0])
+						if group[0] != 38 && group[0] != 48 && group[0] != 58 {
+							continue
+						}
+						if _, n, ok := extendedColor(groups[i:]); ok {
+							// Skip the color's parameters
+							i += n - 1
+						}
+					}
+				}
+			case error:
+				t.Errorf("%s: %v", name, s)
+			}
+		}
+	}
+}
@@ -10,10 +10,13 @@ import (
 	"strings"
 	"sync"
 	"syscall"
+	"time"
 
 	"github.com/creack/pty"
 	"github.com/gdamore/tcell/v2"
 	"github.com/mattn/go-runewidth"
+	"github.com/rivo/uniseg"
+	"golang.org/x/sys/unix"
 )
 
 type (
@@ -21,15 +24,25 @@ type (
 	row    int
 )
 
+// syncTimeout is the longest a synchronized update may hold back the screen.
+// Applications which crash mid-update will not freeze the window for longer
+// than this
+const syncTimeout = time.Second
+
 // VT models a virtual terminal
 type VT struct {
 	Logger *log.Logger
 	// If true, OSC8 enables the output of OSC8 strings. Otherwise, any OSC8
-	// sequences will be stripped
+	// sequences will be stripped. Links are available from Links either way
 	OSC8 bool
 	// Set the TERM environment variable to be passed to the command's
-	// environment. If not set, xterm-256color will be used
+	// environment. If not set, tuitop will be used if its terminfo entry is
+	// installed, xterm-256color otherwise
 	TERM string
+	// CellWidth and CellHeight are the size of a cell in pixels, as
+	// reported to applications. If not set, 8x16 will be used
+	CellWidth  int
+	CellHeight int
 
 	mu sync.Mutex
 
@@ -40,26 +53,70 @@ type VT struct {
 	charsets charsets
 	cursor   cursor
 	margin   margin
+	keyboard keyboard
 	mode     mode
 	sShift   charset
 	tabStop  []column
 	// lastCol is a flag indicating we printed in the last col
 	lastCol bool
+	// cluster is the cell holding the last printed grapheme cluster, which
+	// the next printed rune may extend
+	cluster clusterPos
+	// dcs is the device control string being received
+	dcs *dcsString
+	// workingDir is the working directory reported with OSC 7
+	workingDir string
+	// shell is the state reported by the shell with OSC 133
+	shell shellState
+
+	// scrollback holds the lines which scrolled off the top of the
+	// primary screen, oldest first
+	scrollback [][]cell
+	// viewOffset is the number of lines the view is scrolled back
+	viewOffset int
+	selection  selection
+	// highlights are search matches to highlight, ordered by line
+	highlights   []Match
+	currentMatch int
+	// hover is the link under the mouse, if hovering
+	hover    Link
+	hovering bool
+	// hoverLinks are the links SetHover looks through, found at hoverOffset
+	// and nil once the screen changed
+	hoverLinks  []Link
+	hoverOffset int
 
 	primaryState cursorState
 	altState     cursorState
 
-	cmd          *exec.Cmd
-	dirty        bool
+	cmd     *exec.Cmd
+	dirty   bool
+	focused bool
+	// drawn is the frame last drawn to the surface, used to only draw
+	// cells which changed. It is nil when a full draw is required
+	drawn [][]cell
+	// syncFrame is the frame presented while a synchronized update is in
+	// progress
+	syncFrame    [][]cell
+	syncStart    time.Time
 	eventHandler func(tcell.Event)
 	parser       *Parser
-	pty          *os.File
-	surface      Surface
-	events       chan tcell.Event
+	// pty is the connection to the program, and resize tells the other
+	// end the size of the screen
+	pty     io.ReadWriteCloser
+	resize  func(w int, h int)
+	surface Surface
+	events  chan tcell.Event
 
 	mouseBtn tcell.ButtonMask
 }
 
+type clusterPos struct {
+	row   row
+	col   column
+	valid bool
+}
+
 type cursorState struct {
 	cursor   cursor
 	decawm   bool
@@ -90,7 +147,7 @@ func New() *VT {
 				g3: ascii,
 			},
 		},
-		mode: decawm | dectcem,
+		mode: defaultModes,
 		primaryState: cursorState{
 			charsets: charsets{
 				designations: map[charsetDesignator]charset{
@@ -114,6 +171,7 @@ func New() *VT {
 			decawm: true,
 		},
 		tabStop:      tabs,
+		focused:      true,
 		eventHandler: func(ev tcell.Event) { return },
 		// Buffering to 2 events. If there is ever a case where one
 		// sequence can trigger two events, this should be increased
@@ -127,13 +185,12 @@ func (vt *VT) Start(cmd *exec.Cmd) error {
 	if cmd == nil {
 		return fmt.Errorf("no command to run")
 	}
-	vt.cmd = cmd
 	vt.mu.Lock()
 	w, h := vt.surface.Size()
 	vt.mu.Unlock()
 
 	if vt.TERM == "" {
-		vt.TERM = "xterm-256color"
+		vt.TERM = defaultTERM()
 	}
 
 	env := os.Environ()
@@ -143,12 +200,11 @@ func (vt *VT) Start(cmd *exec.Cmd) error {
 	cmd.Env = append(env, "TERM="+vt.TERM)
 
 	// Start the command with a pty.
-	var err error
 	winsize := pty.Winsize{
 		Cols: uint16(w),
 		Rows: uint16(h),
 	}
-	vt.pty, err = pty.StartWithAttrs(
+	f, err := pty.StartWithAttrs(
 		cmd,
 		&winsize,
 		&syscall.SysProcAttr{
@@ -159,7 +215,41 @@ func (vt *VT) Start(cmd *exec.Cmd) error {
 	if err != nil {
 		return err
 	}
+	vt.mu.Lock()
+	vt.cmd = cmd
+	vt.mu.Unlock()
+
+	vt.run(f, func(w int, h int) {
+		_ = pty.Setsize(f, &pty.Winsize{
+			Cols: uint16(w),
+			Rows: uint16(h),
+		})
+	}, w, h)
+	return nil
+}
 
+// StartIO starts the terminal on a connection to a program which is not a
+// local command, such as an SSH channel or a serial port. resize is called
+// with the screen size in columns and rows when it changes, and may be nil.
+// The terminal is closed when reading from rw fails
+func (vt *VT) StartIO(rw io.ReadWriteCloser, resize func(w int, h int)) error {
+	if rw == nil {
+		return fmt.Errorf("no connection")
+	}
+	vt.mu.Lock()
+	w, h := vt.surface.Size()
+	vt.mu.Unlock()
+	if resize == nil {
+		resize = func(int, int) {}
+	}
+	vt.run(rw, resize, w, h)
+	return nil
+}
+
+// run processes the output of the program until reading from rw fails
+func (vt *VT) run(rw io.ReadWriteCloser, resize func(w int, h int), w int, h int) {
+	vt.pty = rw
+	vt.resize = resize
 	vt.Resize(w, h)
 	vt.parser = NewParser(vt.pty)
 	go func() {
@@ -172,7 +262,7 @@ func (vt *VT) Start(cmd *exec.Cmd) error {
 				seq := vt.parser.Next()
 				switch seq := seq.(type) {
 				case EOF:
-					log.Print("EOF, eventhandler=", vt.eventHandler)
+					log.Printf("EOF, eventhandler=%p", vt.eventHandler)
 					vt.eventHandler(&EventClosed{
 						EventTerminal: newEventTerminal(vt),
 					})
@@ -183,12 +273,16 @@ func (vt *VT) Start(cmd *exec.Cmd) error {
 			}
 		}
 	}()
-	return nil
 }
 
 func (vt *VT) update(seq Sequence) {
 	vt.mu.Lock()
 	defer vt.mu.Unlock()
+	vt.hoverLinks = nil
+	if _, ok := seq.(Print); !ok {
+		// Anything but text ends the grapheme cluster
+		vt.cluster.valid = false
+	}
 	switch seq := seq.(type) {
 	case Print:
 		vt.print(rune(seq))
@@ -199,14 +293,23 @@ func (vt *VT) update(seq Sequence) {
 		vt.esc(string(esc))
 	case CSI:
 		csi := append(seq.Intermediate, seq.Final)
+		if string(csi) == "m" && seq.SubParameters != nil {
+			vt.sgrGroups(seq.SubParameters)
+			break
+		}
 		vt.csi(string(csi), seq.Parameters)
 	case OSC:
 		vt.osc(string(seq.Payload))
 	case DCS:
+		vt.hook(seq)
 	case DCSData:
+		vt.put(rune(seq))
 	case DCSEndOfData:
+		vt.unhook()
+	}
+	if vt.syncing() {
+		return
 	}
-	// TODO optimize when we post EventRedraw
 	if !vt.dirty {
 		vt.dirty = true
 		vt.postEvent(&EventRedraw{
@@ -215,6 +318,27 @@ func (vt *VT) update(seq Sequence) {
 	}
 }
 
+// syncing reports whether a synchronized update is holding back drawing
+func (vt *VT) syncing() bool {
+	return vt.syncFrame != nil && time.Since(vt.syncStart) < syncTimeout
+}
+
+// syncTimedOut asks for a redraw if the synchronized update begun at start
+// is still holding back the screen, the program having never ended it
+func (vt *VT) syncTimedOut(start time.Time) {
+	vt.mu.Lock()
+	pending := vt.syncFrame != nil && vt.syncStart.Equal(start) && !vt.dirty
+	if pending {
+		vt.dirty = true
+	}
+	vt.mu.Unlock()
+	if pending {
+		vt.postEvent(&EventRedraw{
+			EventTerminal: newEventTerminal(vt),
+		})
+	}
+}
+
 func (vt *VT) String() string {
 	vt.mu.Lock()
 	defer vt.mu.Unlock()
@@ -255,25 +379,48 @@ func (vt *VT) recover() {
 func (vt *VT) Cursor() (int, int, tcell.CursorStyle, bool) {
 	vt.mu.Lock()
 	defer vt.mu.Unlock()
-	vis := vt.mode&dectcem > 0
-	return int(vt.cursor.row), int(vt.cursor.col), vt.cursor.style, vis
+	vis := vt.mode&dectcem > 0 && vt.focused
+	rw := int(vt.cursor.row)
+	if vt.viewOffset > 0 && vt.mode&smcup == 0 {
+		rw += vt.viewOffset
+		vis = vis && rw < vt.height()
+	}
+	return rw, int(vt.cursor.col), vt.cursor.style, vis
 }
 
-/*
-func (vt *VT) ShowCursor() {
+// Focus tells the terminal it has gained focus. The cursor is shown again and,
+// if the application enabled focus reporting, CSI I is sent
+func (vt *VT) Focus() {
 	vt.mu.Lock()
-	vt.cursor.style = tcell.CursorStyleSteadyBlock
-	vt.mu.Unlock()
-	vt.Draw()
+	defer vt.mu.Unlock()
+	vt.setFocus(true)
 }
-func (vt *VT) HideCursor() {
+
+// Blur tells the terminal it has lost focus. The cursor is hidden and, if the
+// application enabled focus reporting, CSI O is sent
+func (vt *VT) Blur() {
 	vt.mu.Lock()
-	vt.cursor.style = tcell.CursorStyleSteadyUnderline
-	vt.mu.Unlock()
-	vt.Draw()
-}*/
+	defer vt.mu.Unlock()
+	vt.setFocus(false)
+}
+
+func (vt *VT) setFocus(focused bool) {
+	if vt.focused == focused {
+		return
+	}
+	vt.focused = focused
+	if vt.mode&focusEvents != 0 {
+		switch focused {
+		case true:
+			vt.writeString("\x1b[I")
+		case false:
+			vt.writeString("\x1b[O")
+		}
+	}
+}
 
 func (vt *VT) Resize(w int, h int) {
+	vt.hoverLinks = nil
 	primary := vt.primaryScreen
 	vt.altScreen = make([][]cell, h)
 	vt.primaryScreen = make([][]cell, h)
@@ -282,12 +429,16 @@ func (vt *VT) Resize(w int, h int) {
 		vt.primaryScreen[i] = make([]cell, w)
 	}
 	last := vt.cursor.row
-	vt.margin.bottom = row(h) - 1
-	vt.margin.right = column(w) - 1
+	vt.margin = margin{
+		bottom: row(h) - 1,
+		right:  column(w) - 1,
+	}
 	vt.cursor.row = 0
 	vt.cursor.col = 0
 	vt.lastCol = false
 	vt.activeScreen = vt.primaryScreen
+	vt.drawn = nil
+	vt.syncFrame = nil
 
 	// transfer primary to new, skipping the last row
 	for row := 0; row < len(primary); row += 1 {
@@ -298,7 +449,15 @@ func (vt *VT) Resize(w int, h int) {
 		for col := 0; col < len(primary[0]); col += 1 {
 			cell := primary[row][col]
 			vt.cursor.attrs = cell.attrs
+			vt.cursor.ext = cell.ext
+			vt.cursor.link = cell.link
 			vt.print(cell.content)
+			if cell.content != 0 && vt.cluster.valid {
+				vt.activeScreen[vt.cluster.row][vt.cluster.col].sem = cell.sem
+			}
+			for _, r := range cell.combining {
+				vt.print(r)
+			}
 			wrapped = cell.wrapped
 		}
 		if !wrapped {
@@ -312,10 +471,17 @@ func (vt *VT) Resize(w int, h int) {
 		vt.activeScreen = vt.altScreen
 	}
 
-	_ = pty.Setsize(vt.pty, &pty.Winsize{
-		Cols: uint16(w),
-		Rows: uint16(h),
-	})
+	if vt.resize != nil {
+		vt.resize(w, h)
+	}
+}
+
+// writeString sends s to the program
+func (vt *VT) writeString(s string) {
+	if vt.pty == nil {
+		return
+	}
+	_, _ = io.WriteString(vt.pty, s)
 }
 
 func (vt *VT) width() int {
@@ -329,6 +495,43 @@ func (vt *VT) height() int {
 	return len(vt.activeScreen)
 }
 
+// leftEdge returns the leftmost column the cursor may move to on its own. The
+// left margin only applies when the cursor is not already left of it
+func (vt *VT) leftEdge() column {
+	if vt.cursor.col < vt.margin.left {
+		return 0
+	}
+	return vt.margin.left
+}
+
+// rightEdge returns the rightmost column the cursor may move to on its own.
+// The right margin only applies when the cursor is not already right of it
+func (vt *VT) rightEdge() column {
+	if vt.cursor.col > vt.margin.right {
+		return column(vt.width()) - 1
+	}
+	return vt.margin.right
+}
+
+// copyRow copies the cells between the left and right margins from one row
+// to another
+func (vt *VT) copyRow(dst int, src int) {
+	left, right := vt.margin.left, vt.margin.right+1
+	copy(vt.activeScreen[dst][left:right], vt.activeScreen[src][left:right])
+}
+
+// home moves the cursor to the home position. In origin mode, that is the top
+// left corner of the margins
+func (vt *VT) home() {
+	vt.lastCol = false
+	vt.cursor.row = 0
+	vt.cursor.col = 0
+	if vt.mode&decom != 0 {
+		vt.cursor.row = vt.margin.top
+		vt.cursor.col = vt.margin.left
+	}
+}
+
 // print sets the current cell contents to the given rune. The attributes will
 // be copied from the current cursor attributes
 func (vt *VT) print(r rune) {
@@ -344,7 +547,11 @@ func (vt *VT) print(r rune) {
 		vt.charsets.selected = vt.charsets.saved
 	}
 
-	if vt.cursor.col == vt.margin.right && vt.lastCol {
+	if vt.mode&graphemes != 0 && vt.extendCluster(r) {
+		return
+	}
+
+	if vt.cursor.col == vt.rightEdge() && vt.lastCol {
 		col := vt.cursor.col
 		rw := vt.cursor.row
 		vt.activeScreen[rw][col].wrapped = true
@@ -354,10 +561,14 @@ func (vt *VT) print(r rune) {
 	col := vt.cursor.col
 	rw := vt.cursor.row
 	w := runewidth.RuneWidth(r)
+	if vt.mode&graphemes != 0 {
+		w = uniseg.StringWidth(string(r))
+	}
+	right := vt.rightEdge()
 
 	if vt.mode&irm != 0 {
 		line := vt.activeScreen[rw]
-		for i := vt.margin.right; i > col; i -= 1 {
+		for i := right; i > col; i -= 1 {
 			line[i] = line[i-column(w)]
 		}
 	}
@@ -379,32 +590,82 @@ func (vt *VT) print(r rune) {
 		content: r,
 		width:   w,
 		attrs:   vt.cursor.attrs,
+		ext:     vt.cursor.ext,
+		link:    vt.cursor.link,
+		sem:     vt.mark(),
 	}
 
 	vt.activeScreen[rw][col] = cell
+	vt.cluster = clusterPos{row: rw, col: col, valid: true}
 
 	// Set trailing cells to a space if wide rune
 	for i := column(1); i < column(w); i += 1 {
-		if col+i > vt.margin.right {
+		if col+i > right {
 			break
 		}
 		vt.activeScreen[rw][col+i].content = ' '
 		vt.activeScreen[rw][col+i].attrs = vt.cursor.attrs
+		vt.activeScreen[rw][col+i].ext = vt.cursor.ext
+		vt.activeScreen[rw][col+i].link = vt.cursor.link
+		vt.activeScreen[rw][col+i].sem = semantic{zone: cell.sem.zone}
 	}
 
 	switch {
-	case vt.mode&decawm != 0 && col == vt.margin.right:
+	case vt.mode&decawm != 0 && col == right:
 		vt.lastCol = true
-	case col == vt.margin.right:
+	case col == right:
 		// don't move the cursor
 	default:
 		vt.cursor.col += column(w)
 	}
 }
 
+// extendCluster appends r to the last printed grapheme cluster if it does not
+// start a new cluster, as with combining marks, variation selectors, zero
+// width joiners and the second half of a flag. A cluster which becomes wider,
+// such as text turned into an emoji by VS16, takes up the next cell as well
+func (vt *VT) extendCluster(r rune) bool {
+	if !vt.cluster.valid {
+		return false
+	}
+	c := &vt.activeScreen[vt.cluster.row][vt.cluster.col]
+	str := string(c.content) + string(c.combining) + string(r)
+	cluster, _, w, _ := uniseg.FirstGraphemeClusterInString(str, -1)
+	if len(cluster) != len(str) {
+		return false
+	}
+	c.combining = append(c.combining, r)
+	if w <= c.width {
+		return true
+	}
+	// Widen the cluster, if there is room
+	next := vt.cluster.col + column(c.width)
+	if next > vt.margin.right || next >= column(vt.width()) {
+		return true
+	}
+	line := vt.activeScreen[vt.cluster.row]
+	line[next].content = ' '
+	line[next].combining = nil
+	line[next].attrs = c.attrs
+	line[next].ext = c.ext
+	line[next].link = c.link
+	c.width = w
+	if vt.cursor.row == vt.cluster.row && vt.cursor.col == next {
+		switch {
+		case next == vt.margin.right && vt.mode&decawm != 0:
+			vt.lastCol = true
+		case next == vt.margin.right:
+		default:
+			vt.cursor.col += 1
+		}
+	}
+	return true
+}
+
 // scrollUp shifts all text upward by n rows. Semantically, this is backwards -
 // usually scroll up would mean you shift rows down
 func (vt *VT) scrollUp(n int) {
+	vt.pushScrollback(n)
 	for row := range vt.activeScreen {
 		if row > int(vt.margin.bottom) {
 			continue
@@ -418,7 +679,7 @@ func (vt *VT) scrollUp(n int) {
 			}
 			continue
 		}
-		copy(vt.activeScreen[row], vt.activeScreen[row+n])
+		vt.copyRow(row, row+n)
 	}
 }
 
@@ -431,7 +692,7 @@ func (vt *VT) scrollDown(n int) {
 			}
 			continue
 		}
-		copy(vt.activeScreen[r], vt.activeScreen[r-row(n)])
+		vt.copyRow(int(r), int(r)-n)
 	}
 }
 
@@ -442,7 +703,43 @@ func (vt *VT) Close() {
 		vt.cmd.Process.Kill()
 		vt.cmd.Wait()
 	}
-	vt.pty.Close()
+	if vt.pty != nil {
+		vt.pty.Close()
+	}
+}
+
+// Pid returns the process ID of the command, or 0 if the terminal is not
+// running a local command.
+func (vt *VT) Pid() int {
+	vt.mu.Lock()
+	defer vt.mu.Unlock()
+	if vt.cmd == nil || vt.cmd.Process == nil {
+		return 0
+	}
+	return vt.cmd.Process.Pid
+}
+
+// ForegroundPgrp returns the process group in the foreground of the pty,
+// which gets the keyboard and its signals, or 0 if it is unknown.
+func (vt *VT) ForegroundPgrp() int {
+	vt.mu.Lock()
+	f, ok := vt.pty.(*os.File)
+	vt.mu.Unlock()
+	if !ok {
+		return 0
+	}
+	conn, err := f.SyscallConn()
+	if err != nil {
+		return 0
+	}
+	pgrp := 0
+	conn.Control(func(fd uintptr) {
+		pgrp, err = unix.IoctlGetInt(int(fd), unix.TIOCGPGRP)
+	})
+	if err != nil {
+		return 0
+	}
+	return pgrp
 }
 
 func (vt *VT) Attach(fn func(ev tcell.Event)) {
@@ -466,9 +763,24 @@ func (vt *VT) postEvent(ev tcell.Event) {
 func (vt *VT) SetSurface(srf Surface) {
 	vt.mu.Lock()
 	defer vt.mu.Unlock()
+	if srf != vt.surface {
+		vt.drawn = nil
+	}
 	vt.surface = srf
 }
 
+// Invalidate forces the next Draw to draw every cell, for example when the
+// surface has been cleared by someone else
+func (vt *VT) Invalidate() {
+	vt.mu.Lock()
+	defer vt.mu.Unlock()
+	vt.drawn = nil
+}
+
+// Draw draws the terminal to the surface. Only cells which changed since the
+// last Draw are set, unless Invalidate was called or, on a ReadableSurface,
+// someone else drew over them. While a synchronized update is in progress,
+// the frame from before the update is drawn
 func (vt *VT) Draw() {
 	vt.mu.Lock()
 	defer vt.mu.Unlock()
@@ -476,11 +788,49 @@ func (vt *VT) Draw() {
 	if vt.surface == nil {
 		return
 	}
-	for row := 0; row < vt.height(); row += 1 {
-		for col := 0; col < vt.width(); {
-			cell := vt.activeScreen[row][col]
+	screen := vt.viewRows()
+	if vt.syncing() {
+		screen = vt.syncFrame
+	}
+	if len(vt.drawn) != len(screen) || (len(screen) > 0 && len(vt.drawn[0]) != len(screen[0])) {
+		vt.drawn = make([][]cell, len(screen))
+		for row := range vt.drawn {
+			vt.drawn[row] = make([]cell, len(screen[row]))
+			for col := range vt.drawn[row] {
+				// Never equal to a real cell
+				vt.drawn[row][col].width = -1
+			}
+		}
+	}
+	for row := 0; row < len(screen); row += 1 {
+		for col := 0; col < len(screen[row]); {
+			cell := screen[row][col]
 			w := cell.width
-			vt.surface.SetContent(col, row, cell.content, cell.combining, cell.attrs)
+			if vt.selected(row, col) {
+				_, _, attrs := cell.attrs.Decompose()
+				cell.attrs = cell.attrs.Reverse(attrs&tcell.AttrReverse == 0)
+			}
+			if found, current := vt.highlight(row, col); found {
+				bg := tcell.ColorYellow
+				if current {
+					bg = tcell.ColorOrange
+				}
+				cell.attrs = cell.attrs.Background(bg).Foreground(tcell.ColorBlack)
+			}
+			if vt.hovering && vt.hover.Contains(row, col) {
+				cell.attrs = cell.attrs.Underline(true)
+			}
+			if vt.OSC8 && cell.link.url != "" {
+				cell.attrs = cell.attrs.Url(cell.link.url).UrlId(cell.link.id)
+			}
+			if !vt.drawn[row][col].equal(&cell) || vt.drawnOver(row, col, &cell) {
+				content, combining, style := cell.visible()
+				vt.surface.SetContent(col, row, content, combining, style)
+				vt.drawn[row][col] = cell
+				if cell.combining != nil {
+					vt.drawn[row][col].combining = append([]rune(nil), cell.combining...)
+				}
+			}
 			if w == 0 {
 				w = 1
 			}
@@ -497,27 +847,76 @@ func (vt *VT) Draw() {
 	// }
 }
 
+// drawnOver reports whether the surface no longer shows c at row, col, when
+// it can be read back
+func (vt *VT) drawnOver(row, col int, c *cell) bool {
+	surface, ok := vt.surface.(ReadableSurface)
+	if !ok {
+		return false
+	}
+	content, combining, style := c.visible()
+	// Control characters read back as spaces
+	if content < ' ' {
+		content = ' '
+	}
+	shown, shownCombining, shownStyle, _ := surface.GetContent(col, row)
+	if shown != content || shownStyle != style || len(shownCombining) != len(combining) {
+		return true
+	}
+	for i := range combining {
+		if shownCombining[i] != combining[i] {
+			return true
+		}
+	}
+	return false
+}
+
+// Paste sends text to the program as if it was typed, bracketed when the
+// program asked for it. Line feeds are sent as returns, like the Enter key
+func (vt *VT) Paste(text string) {
+	vt.mu.Lock()
+	defer vt.mu.Unlock()
+	vt.viewOffset = 0
+	text = strings.ReplaceAll(strings.ReplaceAll(text, "\r\n", "\r"), "\n", "\r")
+	// Escape sequences are dropped, so the text cannot end a bracketed
+	// paste early and run what follows
+	text = strings.NewReplacer("\x1b", "", "\u009b", "").Replace(text)
+	if vt.mode&paste != 0 {
+		text = info.PasteStart + text + info.PasteEnd
+	}
+	vt.writeString(text)
+}
+
+// MouseReporting reports whether the program asked for mouse events, in
+// which case the terminal doesn't use the mouse itself
+func (vt *VT) MouseReporting() bool {
+	vt.mu.Lock()
+	defer vt.mu.Unlock()
+	return vt.mode&(mouseButtons|mouseDrag|mouseMotion|mouseSGR) != 0
+}
+
 func (vt *VT) HandleEvent(e tcell.Event) bool {
 	vt.mu.Lock()
 	defer vt.mu.Unlock()
 	switch e := e.(type) {
 	case *tcell.EventKey:
-		vt.pty.WriteString(keyCode(e))
+		vt.viewOffset = 0
+		vt.writeString(vt.encodeKey(e))
 		return true
 	case *tcell.EventPaste:
 		switch {
 		case vt.mode&paste == 0:
 			return false
 		case e.Start():
-			vt.pty.WriteString(info.PasteStart)
+			vt.writeString(info.PasteStart)
 			return true
 		case e.End():
-			vt.pty.WriteString(info.PasteEnd)
+			vt.writeString(info.PasteEnd)
 			return true
 		}
 	case *tcell.EventMouse:
 		str := vt.handleMouse(e)
-		vt.pty.WriteString(str)
+		vt.writeString(str)
 	}
 	return false
 }
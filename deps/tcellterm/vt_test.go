@@ -1,8 +1,15 @@
 package tcellterm
 
 import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
 	"testing"
+	"time"
 
+	"github.com/gdamore/tcell/v2"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -146,3 +153,388 @@ func TestCombiningRunes(t *testing.T) {
 
 	assert.Equal(t, "h̷̗ \n  ", vt.String())
 }
+
+func TestFocus(t *testing.T) {
+	vt := New()
+	vt.Resize(2, 2)
+	r, w, err := os.Pipe()
+	assert.NoError(t, err)
+	defer r.Close()
+	vt.pty = w
+
+	// Nothing is reported until the application asks for it
+	vt.Blur()
+	_, _, _, vis := vt.Cursor()
+	assert.False(t, vis)
+	vt.Focus()
+	_, _, _, vis = vt.Cursor()
+	assert.True(t, vis)
+
+	vt.decset([]int{1004})
+	vt.Focus()
+	vt.Blur()
+	vt.Blur()
+	vt.Focus()
+	vt.decrst([]int{1004})
+	vt.Blur()
+	w.Close()
+
+	out, err := io.ReadAll(r)
+	assert.NoError(t, err)
+	assert.Equal(t, "\x1b[O\x1b[I", string(out))
+}
+
+// countingSurface records the number of SetContent calls
+type countingSurface struct {
+	w, h  int
+	cells map[[2]int]rune
+	sets  int
+}
+
+func newCountingSurface(w, h int) *countingSurface {
+	return &countingSurface{w: w, h: h, cells: map[[2]int]rune{}}
+}
+
+func (s *countingSurface) SetContent(x int, y int, ch rune, comb []rune, style tcell.Style) {
+	s.cells[[2]int{x, y}] = ch
+	s.sets += 1
+}
+
+func (s *countingSurface) Size() (int, int) {
+	return s.w, s.h
+}
+
+func TestDrawDamage(t *testing.T) {
+	vt := New()
+	vt.Resize(4, 2)
+	srf := newCountingSurface(4, 2)
+	vt.SetSurface(srf)
+
+	vt.Draw()
+	assert.Equal(t, 8, srf.sets)
+
+	srf.sets = 0
+	vt.Draw()
+	assert.Equal(t, 0, srf.sets)
+
+	vt.print('x')
+	vt.Draw()
+	assert.Equal(t, 1, srf.sets)
+	assert.Equal(t, 'x', srf.cells[[2]int{0, 0}])
+
+	srf.sets = 0
+	vt.Invalidate()
+	vt.Draw()
+	assert.Equal(t, 8, srf.sets)
+}
+
+func TestDrawOver(t *testing.T) {
+	screen := tcell.NewSimulationScreen("")
+	assert.NoError(t, screen.Init())
+	screen.SetSize(4, 2)
+	vt := New()
+	vt.Resize(4, 2)
+	vt.SetSurface(screen)
+	vt.print('x')
+	vt.Draw()
+
+	// A window below the terminal draws over it
+	screen.SetContent(0, 0, '#', nil, tcell.StyleDefault)
+	screen.SetContent(1, 1, '#', nil, tcell.StyleDefault)
+	vt.Draw()
+	for _, pos := range [][3]int{{0, 0, 'x'}, {1, 1, ' '}} {
+		r, _, _, _ := screen.GetContent(pos[0], pos[1])
+		assert.Equal(t, rune(pos[2]), r)
+	}
+}
+
+func TestSynchronizedOutput(t *testing.T) {
+	vt := New()
+	vt.Resize(3, 1)
+	srf := newCountingSurface(3, 1)
+	vt.SetSurface(srf)
+
+	vt.print('a')
+	vt.decset([]int{2026})
+	vt.print('b')
+	vt.Draw()
+	assert.Equal(t, 'a', srf.cells[[2]int{0, 0}])
+	assert.Equal(t, rune(0), srf.cells[[2]int{1, 0}])
+
+	// No redraws are requested during the update
+	vt.update(Print('c'))
+	assert.Len(t, vt.events, 0)
+
+	vt.update(CSI{Final: 'l', Intermediate: []rune{'?'}, Parameters: []int{2026}})
+	assert.Len(t, vt.events, 1)
+	vt.Draw()
+	assert.Equal(t, 'a', srf.cells[[2]int{0, 0}])
+	assert.Equal(t, 'b', srf.cells[[2]int{1, 0}])
+	assert.Equal(t, 'c', srf.cells[[2]int{2, 0}])
+
+	// An update which never ends stops holding back the screen, and asks
+	// for a redraw when it times out
+	vt.decset([]int{2026})
+	vt.syncTimedOut(vt.syncStart)
+	assert.Len(t, vt.events, 2)
+	vt.syncStart = vt.syncStart.Add(-syncTimeout)
+	vt.cursor.col = 0
+	vt.print('d')
+	vt.Draw()
+	assert.Equal(t, 'd', srf.cells[[2]int{0, 0}])
+}
+
+// benchmarkInput is a mix of text, colors and cursor movement resembling the
+// output of a busy program
+func benchmarkInput() []byte {
+	buf := bytes.Buffer{}
+	for i := 0; buf.Len() < 1<<20; i += 1 {
+		fmt.Fprintf(&buf, "\x1b[%d;1H\x1b[3%dm%04d \x1b[1mlorem ipsum dolor sit amet\x1b[m つ \r\n", i%24+1, i%8, i)
+	}
+	return buf.Bytes()
+}
+
+func BenchmarkParse(b *testing.B) {
+	input := benchmarkInput()
+	b.SetBytes(int64(len(input)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i += 1 {
+		parser := NewParser(bytes.NewReader(input))
+		for {
+			seq := parser.Next()
+			if _, ok := seq.(EOF); ok || seq == nil {
+				break
+			}
+		}
+	}
+}
+
+func BenchmarkParseAndUpdate(b *testing.B) {
+	input := benchmarkInput()
+	b.SetBytes(int64(len(input)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i += 1 {
+		vt := New()
+		vt.Resize(80, 24)
+		vt.SetSurface(newCountingSurface(80, 24))
+		parser := NewParser(bytes.NewReader(input))
+		for n := 1; ; n += 1 {
+			seq := parser.Next()
+			if _, ok := seq.(EOF); ok || seq == nil {
+				break
+			}
+			vt.update(seq)
+			// Draw a frame now and then, like a coalesced redraw
+			if n%4096 == 0 {
+				select {
+				case <-vt.events:
+				default:
+				}
+				vt.Draw()
+			}
+		}
+	}
+}
+
+func TestGraphemeClusters(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		// expected is the content of each cell, "" for the trailing
+		// cells of wide clusters
+		expected []string
+		cursor   column
+	}{
+		{
+			name:     "combining marks",
+			input:    "éx",
+			expected: []string{"é", "x", " ", " "},
+			cursor:   2,
+		},
+		{
+			name:     "ZWJ sequence",
+			input:    "👨‍👩‍👧x",
+			expected: []string{"👨‍👩‍👧", "", "x", " "},
+			cursor:   3,
+		},
+		{
+			name:     "flag",
+			input:    "🇺🇸x",
+			expected: []string{"🇺🇸", "", "x", " "},
+			cursor:   3,
+		},
+		{
+			name:     "emoji presentation selector",
+			input:    "❤️x",
+			expected: []string{"❤️", "", "x", " "},
+			cursor:   3,
+		},
+		{
+			name:     "app name",
+			input:    "🌐 E",
+			expected: []string{"🌐", "", " ", "E"},
+			cursor:   3,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			vt := New()
+			vt.Resize(4, 1)
+			feed(vt, test.input)
+			actual := []string{}
+			for col := 0; col < vt.width(); {
+				c := vt.activeScreen[0][col]
+				actual = append(actual, string(c.rune())+string(c.combining))
+				for i := 1; i < c.width; i += 1 {
+					actual = append(actual, "")
+				}
+				if c.width == 0 {
+					col += 1
+					continue
+				}
+				col += c.width
+			}
+			assert.Equal(t, test.expected, actual)
+			assert.Equal(t, test.cursor, vt.cursor.col)
+		})
+	}
+
+	t.Run("clusters do not span sequences", func(t *testing.T) {
+		vt := New()
+		vt.Resize(4, 1)
+		feed(vt, "🇺\x1b[C🇸")
+		assert.Equal(t, "🇺", string(vt.activeScreen[0][0].rune())+string(vt.activeScreen[0][0].combining))
+	})
+
+	t.Run("per codepoint widths when reset", func(t *testing.T) {
+		vt := New()
+		vt.Resize(6, 1)
+		feed(vt, "\x1b[?2027l👨‍👩x")
+		assert.Equal(t, column(5), vt.cursor.col)
+	})
+}
+
+func TestScrollback(t *testing.T) {
+	vt := New()
+	vt.Resize(4, 3)
+	feed(vt, "1\r\n2\r\n3\r\n4\r\n5")
+	assert.Equal(t, 2, len(vt.scrollback))
+	assert.Equal(t, '1', vt.scrollback[0][0].content)
+
+	vt.ScrollView(1)
+	assert.Equal(t, '2', vt.viewRows()[0][0].content)
+	assert.Equal(t, '3', vt.viewRows()[1][0].content)
+	_, _, _, vis := vt.Cursor()
+	assert.False(t, vis)
+	vt.ScrollView(10)
+	assert.Equal(t, 2, vt.viewOffset)
+	vt.ScrollView(-10)
+	assert.Equal(t, 0, vt.viewOffset)
+
+	// Scrolling a region doesn't save lines
+	feed(vt, "\x1b[2;3r\x1b[3;1H\n")
+	assert.Equal(t, 2, len(vt.scrollback))
+
+	feed(vt, "\x1b[3J")
+	assert.Empty(t, vt.scrollback)
+}
+
+func TestSelectWithMouse(t *testing.T) {
+	vt := New()
+	vt.Resize(5, 2)
+	feed(vt, "ab\r\ncdefg\r\nhi")
+	// Dragging up from the bottom row, in the view scrolled back a line
+	vt.ScrollView(1)
+	vt.StartSelection(1, 4)
+	assert.Equal(t, "", vt.SelectedText())
+	vt.ExtendSelection(0, 1)
+	assert.Equal(t, "b\ncdefg", vt.SelectedText())
+	// Past the edges is kept on the screen
+	vt.ExtendSelection(5, 9)
+	assert.Equal(t, "g", vt.SelectedText())
+
+	vt.ClearScrollback()
+	assert.Empty(t, vt.scrollback)
+	assert.Equal(t, "", vt.SelectedText())
+
+	// The alternate screen can't be selected
+	vt.decset([]int{1049})
+	vt.StartSelection(0, 0)
+	vt.ExtendSelection(1, 1)
+	assert.Equal(t, "", vt.SelectedText())
+}
+
+func TestPaste(t *testing.T) {
+	vt := New()
+	vt.Resize(2, 2)
+	r, w, err := os.Pipe()
+	assert.NoError(t, err)
+	defer r.Close()
+	vt.pty = w
+
+	assert.False(t, vt.MouseReporting())
+	vt.decset([]int{1000})
+	assert.True(t, vt.MouseReporting())
+
+	vt.Paste("a\nb\r\n")
+	vt.decset([]int{2004})
+	vt.Paste("c\x1b[201~d")
+	w.Close()
+
+	out, err := io.ReadAll(r)
+	assert.NoError(t, err)
+	assert.Equal(t, "a\rb\r\x1b[200~c[201~d\x1b[201~", string(out))
+}
+
+// pipeConn joins the ends of two pipes into a connection
+type pipeConn struct {
+	io.Reader
+	io.WriteCloser
+}
+
+func TestStartIO(t *testing.T) {
+	vtIn, progOut := io.Pipe()
+	progIn, vtOut := io.Pipe()
+	vt := New()
+	vt.SetSurface(newCountingSurface(10, 2))
+	closed := make(chan bool)
+	vt.Attach(func(ev tcell.Event) {
+		if _, ok := ev.(*EventClosed); ok {
+			close(closed)
+		}
+	})
+	var size [2]int
+	assert.NoError(t, vt.StartIO(pipeConn{vtIn, vtOut}, func(w int, h int) {
+		size = [2]int{w, h}
+	}))
+	assert.Equal(t, [2]int{10, 2}, size)
+
+	go progOut.Write([]byte("hi\x1b[6n"))
+	buf := make([]byte, 16)
+	n, err := progIn.Read(buf)
+	assert.NoError(t, err)
+	assert.Equal(t, "\x1b[1;3R", string(buf[:n]))
+
+	progOut.Close()
+	<-closed
+	assert.Equal(t, "hi        \n          ", vt.String())
+}
+
+func TestForegroundPgrp(t *testing.T) {
+	vt := New()
+	vt.SetSurface(newCountingSurface(10, 2))
+	vt.Attach(func(ev tcell.Event) {})
+	assert.Equal(t, 0, vt.Pid())
+	assert.Equal(t, 0, vt.ForegroundPgrp())
+
+	// The shell runs sleep in the foreground in a process group of its own
+	assert.NoError(t, vt.Start(exec.Command("sh", "-ic", "sleep 10; true")))
+	defer vt.Close()
+	pid := vt.Pid()
+	assert.NotZero(t, pid)
+	assert.Eventually(t, func() bool {
+		pgrp := vt.ForegroundPgrp()
+		return pgrp != 0 && pgrp != pid
+	}, 5*time.Second, 10*time.Millisecond)
+}
@@ -6,13 +6,21 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"os"
 
-	"github.com/gdamore/tcell/v2"
 	"github.com/snadrus/tuitop/deps/cview"
 	"github.com/snadrus/tuitop/tui/tuiwm"
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "notify" {
+		if err := runNotify(os.Args[2:]); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	// The application.
 	var app = cview.NewApplication()
 
@@ -38,12 +46,11 @@ func main() {
 
 	app.EnableMouse(true)
 
-	// Shortcuts to navigate the slides.
-	app.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
-		return event
-	})
-
 	xp := tuiwm.MakeXP(app)
+	defer xp.Close()
+
+	// Desktop shortcuts.
+	app.SetInputCapture(xp.HandleShortcut)
 
 	// Start the application.
 	app.SetRoot(xp, true)
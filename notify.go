@@ -0,0 +1,42 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// runNotify implements `tuitop notify [-title title] body...`, which shows a
+// desktop notification from a script running in a TuiTop window. It sends
+// OSC 777 to the terminal, which other terminals understand too.
+func runNotify(args []string) error {
+	fs := flag.NewFlagSet("notify", flag.ContinueOnError)
+	title := fs.String("title", "", "notification title")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	body := strings.Join(fs.Args(), " ")
+	if body == "" && *title == "" {
+		return fmt.Errorf("usage: tuitop notify [-title title] body")
+	}
+	tty, err := os.OpenFile("/dev/tty", os.O_WRONLY, 0)
+	if err != nil {
+		return fmt.Errorf("cannot open terminal: %w", err)
+	}
+	defer tty.Close()
+	// The title ends at the first semicolon
+	t := strings.ReplaceAll(oscText(*title), ";", ",")
+	_, err = fmt.Fprintf(tty, "\x1b]777;notify;%s;%s\x1b\\", t, oscText(body))
+	return err
+}
+
+// oscText removes control characters, which would end the OSC string early.
+func oscText(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f || (r >= 0x80 && r < 0xa0) {
+			return -1
+		}
+		return r
+	}, s)
+}
@@ -0,0 +1,331 @@
+// Package client is the reference client of the TuiTop app protocol. Programs
+// running on the desktop use it to open windows made of widgets.
+package client
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"sync"
+
+	"github.com/snadrus/tuitop/tui/appproto"
+	"golang.org/x/xerrors"
+)
+
+// ErrClosed is returned by calls on a closed client.
+var ErrClosed = errors.New("client closed")
+
+// Client is a connection to the desktop.
+type Client struct {
+	rw     io.ReadWriteCloser
+	events chan appproto.Event
+	done   chan struct{}
+
+	wmu sync.Mutex
+	enc *json.Encoder
+
+	mu      sync.Mutex
+	nextID  int64
+	nextObj int
+	calls   map[int64]chan appproto.Response
+	// answers take the events answering dialogs and menus
+	answers map[answerKey]chan appproto.Event
+	err     error
+}
+
+type answerKey struct {
+	menu bool
+	id   int
+}
+
+// Dial connects to the desktop at the socket in $TUITOP, as the program
+// named name.
+func Dial(name string) (*Client, error) {
+	path := os.Getenv(appproto.EnvVar)
+	if path == "" {
+		return nil, xerrors.Errorf("$%s is not set, not running on TuiTop", appproto.EnvVar)
+	}
+	conn, err := net.Dial("unix", path)
+	if err != nil {
+		return nil, xerrors.Errorf("cannot connect to TuiTop: %w", err)
+	}
+	return New(conn, name)
+}
+
+// New talks to the desktop over rw, as the program named name.
+func New(rw io.ReadWriteCloser, name string) (*Client, error) {
+	c := &Client{
+		rw:      rw,
+		events:  make(chan appproto.Event, 256),
+		done:    make(chan struct{}),
+		enc:     json.NewEncoder(rw),
+		calls:   map[int64]chan appproto.Response{},
+		answers: map[answerKey]chan appproto.Event{},
+	}
+	go c.read()
+	err := c.call("hello", map[string]any{"name": name, "version": appproto.Version}, nil)
+	if err != nil {
+		rw.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// Events returns the events of the client's windows and tray items. It is
+// closed when the connection is.
+func (c *Client) Events() <-chan appproto.Event {
+	return c.events
+}
+
+// Close disconnects, which closes the client's windows and tray items.
+func (c *Client) Close() error {
+	return c.rw.Close()
+}
+
+// Done is closed once the connection is, with Err telling why.
+func (c *Client) Done() <-chan struct{} {
+	return c.done
+}
+
+// Err returns why the connection closed.
+func (c *Client) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.err
+}
+
+func (c *Client) read() {
+	scanner := bufio.NewScanner(c.rw)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var msg struct {
+			appproto.Response
+			appproto.Event
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &msg); err != nil {
+			continue
+		}
+		if msg.Event.Event == "" {
+			c.mu.Lock()
+			ch := c.calls[msg.ID]
+			delete(c.calls, msg.ID)
+			c.mu.Unlock()
+			if ch != nil {
+				ch <- msg.Response
+			}
+			continue
+		}
+		ev := msg.Event
+		if ev.Event == appproto.EventDialog || ev.Event == appproto.EventMenu {
+			key := answerKey{menu: ev.Event == appproto.EventMenu, id: ev.Dialog}
+			if key.menu {
+				key.id = ev.Menu
+			}
+			c.mu.Lock()
+			ch := c.answers[key]
+			delete(c.answers, key)
+			c.mu.Unlock()
+			if ch != nil {
+				ch <- ev
+			}
+			continue
+		}
+		select {
+		case c.events <- ev:
+		case <-c.done:
+		}
+	}
+	c.mu.Lock()
+	c.err = scanner.Err()
+	if c.err == nil {
+		c.err = io.EOF
+	}
+	// Wake up whoever waits
+	for id, ch := range c.calls {
+		close(ch)
+		delete(c.calls, id)
+	}
+	for key, ch := range c.answers {
+		close(ch)
+		delete(c.answers, key)
+	}
+	c.mu.Unlock()
+	close(c.done)
+	close(c.events)
+}
+
+// call sends a request and waits for its response. The result is decoded into
+// result unless it is nil.
+func (c *Client) call(method string, params any, result any) error {
+	raw, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	ch := make(chan appproto.Response, 1)
+	c.mu.Lock()
+	if c.err != nil {
+		c.mu.Unlock()
+		return ErrClosed
+	}
+	c.nextID += 1
+	id := c.nextID
+	c.calls[id] = ch
+	c.mu.Unlock()
+
+	c.wmu.Lock()
+	err = c.enc.Encode(appproto.Request{ID: id, Method: method, Params: raw})
+	c.wmu.Unlock()
+	if err != nil {
+		c.mu.Lock()
+		delete(c.calls, id)
+		c.mu.Unlock()
+		return xerrors.Errorf("cannot send %s: %w", method, err)
+	}
+	resp, ok := <-ch
+	if !ok {
+		return ErrClosed
+	}
+	if resp.Error != "" {
+		return xerrors.Errorf("%s: %s", method, resp.Error)
+	}
+	if result != nil {
+		return json.Unmarshal(resp.Result, result)
+	}
+	return nil
+}
+
+// newObj returns an ID for a window, tray item, dialog or menu.
+func (c *Client) newObj() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.nextObj += 1
+	return c.nextObj
+}
+
+// Notify shows a desktop notification.
+func (c *Client) Notify(title, body string) error {
+	return c.call("notify", map[string]any{"title": title, "body": body}, nil)
+}
+
+// Window is a window opened by the client.
+type Window struct {
+	c  *Client
+	id int
+}
+
+// OpenWindow opens a window showing root. A zero size lets the desktop pick
+// one.
+func (c *Client) OpenWindow(title string, width, height int, root appproto.Widget) (*Window, error) {
+	w := &Window{c: c, id: c.newObj()}
+	err := c.call("window.open", map[string]any{
+		"window": w.id,
+		"title":  title,
+		"width":  width,
+		"height": height,
+		"root":   root,
+	}, nil)
+	if err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// ID returns the ID of the window in its events.
+func (w *Window) ID() int {
+	return w.id
+}
+
+func (w *Window) SetTitle(title string) error {
+	return w.c.call("window.title", map[string]any{"window": w.id, "title": title}, nil)
+}
+
+// SetContent replaces the widgets of the window.
+func (w *Window) SetContent(root appproto.Widget) error {
+	return w.c.call("window.content", map[string]any{"window": w.id, "root": root}, nil)
+}
+
+// Update replaces the widget of the window with the same ID as widget.
+func (w *Window) Update(widget appproto.Widget) error {
+	return w.c.call("widget.update", map[string]any{"window": w.id, "widget": widget}, nil)
+}
+
+func (w *Window) Close() error {
+	return w.c.call("window.close", map[string]any{"window": w.id}, nil)
+}
+
+// Tray is an item the client added to the tray.
+type Tray struct {
+	c  *Client
+	id int
+}
+
+// AddTray adds a tray item showing text, a few cells wide.
+func (c *Client) AddTray(text string) (*Tray, error) {
+	t := &Tray{c: c, id: c.newObj()}
+	if err := c.call("tray.add", map[string]any{"tray": t.id, "text": text}, nil); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// ID returns the ID of the tray item in its events.
+func (t *Tray) ID() int {
+	return t.id
+}
+
+func (t *Tray) SetText(text string) error {
+	return t.c.call("tray.set", map[string]any{"tray": t.id, "text": text}, nil)
+}
+
+func (t *Tray) Remove() error {
+	return t.c.call("tray.remove", map[string]any{"tray": t.id}, nil)
+}
+
+// Dialog asks the user with a dialog and returns the button pressed, "" if
+// the dialog was dismissed. Without buttons, the dialog has an OK button.
+func (c *Client) Dialog(title, text string, buttons ...string) (string, error) {
+	id := c.newObj()
+	ev, err := c.ask(answerKey{id: id}, "dialog.show", map[string]any{
+		"dialog":  id,
+		"title":   title,
+		"text":    text,
+		"buttons": buttons,
+	})
+	return ev.Button, err
+}
+
+// Menu shows a menu and returns the index of the item chosen, -1 if the menu
+// was dismissed.
+func (c *Client) Menu(items ...string) (int, error) {
+	id := c.newObj()
+	ev, err := c.ask(answerKey{menu: true, id: id}, "menu.show", map[string]any{
+		"menu":  id,
+		"items": items,
+	})
+	if err != nil || ev.Index == nil {
+		return -1, err
+	}
+	return *ev.Index, nil
+}
+
+// ask shows a dialog or menu and waits for its answer.
+func (c *Client) ask(key answerKey, method string, params map[string]any) (appproto.Event, error) {
+	ch := make(chan appproto.Event, 1)
+	c.mu.Lock()
+	c.answers[key] = ch
+	c.mu.Unlock()
+	if err := c.call(method, params, nil); err != nil {
+		c.mu.Lock()
+		delete(c.answers, key)
+		c.mu.Unlock()
+		return appproto.Event{}, err
+	}
+	ev, ok := <-ch
+	if !ok {
+		return appproto.Event{}, ErrClosed
+	}
+	return ev, nil
+}
@@ -0,0 +1,180 @@
+package client
+
+import (
+	"net"
+	"os"
+	"path"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/snadrus/tuitop/tui/appproto"
+)
+
+// desk is a backend answering dialogs with their last button, menus with
+// their first item, and selecting the first item of new windows.
+type desk struct {
+	mu      sync.Mutex
+	windows map[int]string
+	notes   []string
+	trays   map[int]appproto.Sink
+}
+
+func newDesk() *desk {
+	return &desk{windows: map[int]string{}, trays: map[int]appproto.Sink{}}
+}
+
+func (d *desk) OpenWindow(id int, title string, width, height int, root appproto.Widget, events appproto.Sink) error {
+	d.mu.Lock()
+	d.windows[id] = title
+	d.mu.Unlock()
+	go events(appproto.SelectEvent(root.ID, 0))
+	return nil
+}
+func (d *desk) SetTitle(id int, title string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.windows[id] = title
+}
+func (d *desk) SetContent(id int, root appproto.Widget) {}
+func (d *desk) UpdateWidget(id int, w appproto.Widget)  {}
+func (d *desk) CloseWindow(id int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.windows, id)
+}
+func (d *desk) Notify(title, body string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.notes = append(d.notes, title+": "+body)
+}
+func (d *desk) AddTray(id int, text string, events appproto.Sink) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.trays[id] = events
+}
+func (d *desk) SetTray(id int, text string) {}
+func (d *desk) RemoveTray(id int)           {}
+func (d *desk) ShowDialog(id int, title, text string, buttons []string, events appproto.Sink) {
+	go events(appproto.DialogEvent(buttons[len(buttons)-1]))
+}
+func (d *desk) ShowMenu(id int, items []string, events appproto.Sink) {
+	go events(appproto.MenuEvent(0))
+}
+func (d *desk) Dismiss(id int) {}
+
+func (d *desk) openWindows() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return len(d.windows)
+}
+
+func nextEvent(t *testing.T, c *Client) appproto.Event {
+	t.Helper()
+	select {
+	case ev := <-c.Events():
+		return ev
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for an event")
+	}
+	return appproto.Event{}
+}
+
+func TestClient(t *testing.T) {
+	d := newDesk()
+	server, err := appproto.Listen(path.Join(t.TempDir(), "tuitop.sock"), d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+	if info, err := os.Stat(server.Path()); err != nil {
+		t.Fatal(err)
+	} else if info.Mode().Perm() != 0o600 {
+		t.Fatalf("socket mode %v", info.Mode())
+	}
+	t.Setenv(appproto.EnvVar, server.Path())
+	c, err := Dial("test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	w, err := c.OpenWindow("Pick", 30, 10, appproto.Widget{ID: "fruit", Type: appproto.WidgetList, Items: []string{"apple"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ev := nextEvent(t, c)
+	if ev.Event != appproto.EventSelect || ev.Window != w.ID() || ev.Widget != "fruit" || *ev.Index != 0 {
+		t.Fatalf("got event %+v", ev)
+	}
+	if err := w.Update(appproto.Widget{ID: "fruit", Type: appproto.WidgetText}); err == nil {
+		t.Fatal("changed the type of a widget")
+	}
+
+	button, err := c.Dialog("Quit", "Sure?", "No", "Yes")
+	if err != nil || button != "Yes" {
+		t.Fatalf("got %q, %v, want Yes", button, err)
+	}
+	index, err := c.Menu("Open", "Close")
+	if err != nil || index != 0 {
+		t.Fatalf("got %d, %v, want 0", index, err)
+	}
+
+	tray, err := c.AddTray("T")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.mu.Lock()
+	for _, sink := range d.trays {
+		sink(appproto.TrayClickEvent())
+	}
+	d.mu.Unlock()
+	if ev := nextEvent(t, c); ev.Event != appproto.EventTrayClick || ev.Tray != tray.ID() {
+		t.Fatalf("got event %+v", ev)
+	}
+
+	if err := c.Notify("Hi", "there"); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if n := d.openWindows(); n != 0 {
+		t.Fatalf("%d windows still open", n)
+	}
+
+	// Windows are closed when the client disconnects
+	if _, err := c.OpenWindow("Left open", 0, 0, appproto.Widget{Type: appproto.WidgetText}); err != nil {
+		t.Fatal(err)
+	}
+	c.Close()
+	<-c.Done()
+	for deadline := time.Now().Add(5 * time.Second); d.openWindows() != 0; {
+		if time.Now().After(deadline) {
+			t.Fatal("window left open after disconnecting")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if err := c.Notify("Hi", "again"); err != ErrClosed {
+		t.Fatalf("got %v, want ErrClosed", err)
+	}
+}
+
+func TestServerClose(t *testing.T) {
+	server := appproto.NewServer(newDesk())
+	a, b := net.Pipe()
+	go server.Serve(a)
+	c, err := New(b, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server.Close()
+	select {
+	case <-c.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("client still connected")
+	}
+	if c.Err() == nil {
+		t.Fatal("no error after disconnection")
+	}
+}
@@ -0,0 +1,230 @@
+package appproto
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// The conformance suite replays the scripts in testdata/conformance against
+// a server with a recording backend. Each line of a script is one of
+//
+//	> message the client sends
+//	< message the client must receive next. An "error" of "*" matches any
+//	  error
+//	= call the backend must receive next
+//	! action of the user: close W, select W WIDGET INDEX,
+//	  submit W WIDGET BUTTON [K=V,...], tray T, dialog D [BUTTON], menu M INDEX
+//	  or disconnect
+//
+// IDs in calls and actions are those the server passes to the backend, which
+// it numbers from 1 in the order things are opened.
+
+// recorder is a backend recording its calls.
+type recorder struct {
+	calls chan string
+	mu    sync.Mutex
+	sinks map[int]Sink
+}
+
+func newRecorder() *recorder {
+	return &recorder{calls: make(chan string, 100), sinks: map[int]Sink{}}
+}
+
+func (r *recorder) record(format string, args ...any) {
+	r.calls <- fmt.Sprintf(format, args...)
+}
+
+func (r *recorder) sink(id int, s Sink) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sinks[id] = s
+}
+
+func compact(v any) string {
+	data, _ := json.Marshal(v)
+	return string(data)
+}
+
+func (r *recorder) OpenWindow(id int, title string, width, height int, root Widget, events Sink) error {
+	r.sink(id, events)
+	r.record("OpenWindow %d %q %dx%d %s", id, title, width, height, compact(root))
+	return nil
+}
+func (r *recorder) SetTitle(id int, title string) { r.record("SetTitle %d %q", id, title) }
+func (r *recorder) SetContent(id int, root Widget) {
+	r.record("SetContent %d %s", id, compact(root))
+}
+func (r *recorder) UpdateWidget(id int, w Widget) {
+	r.record("UpdateWidget %d %s", id, compact(w))
+}
+func (r *recorder) CloseWindow(id int)        { r.record("CloseWindow %d", id) }
+func (r *recorder) Notify(title, body string) { r.record("Notify %q %q", title, body) }
+func (r *recorder) AddTray(id int, text string, events Sink) {
+	r.sink(id, events)
+	r.record("AddTray %d %q", id, text)
+}
+func (r *recorder) SetTray(id int, text string) { r.record("SetTray %d %q", id, text) }
+func (r *recorder) RemoveTray(id int)           { r.record("RemoveTray %d", id) }
+func (r *recorder) ShowDialog(id int, title, text string, buttons []string, events Sink) {
+	r.sink(id, events)
+	r.record("ShowDialog %d %q %q %s", id, title, text, compact(buttons))
+}
+func (r *recorder) ShowMenu(id int, items []string, events Sink) {
+	r.sink(id, events)
+	r.record("ShowMenu %d %s", id, compact(items))
+}
+func (r *recorder) Dismiss(id int) { r.record("Dismiss %d", id) }
+
+// act performs the action of the user in a script line
+func (r *recorder) act(t *testing.T, line string) {
+	t.Helper()
+	fields := strings.Fields(line)
+	if fields[0] == "disconnect" {
+		return
+	}
+	id, _ := strconv.Atoi(fields[1])
+	r.mu.Lock()
+	sink := r.sinks[id]
+	r.mu.Unlock()
+	if sink == nil {
+		t.Fatalf("%s: nothing with id %d", line, id)
+	}
+	arg := func(i int) string {
+		if i < len(fields) {
+			return fields[i]
+		}
+		return ""
+	}
+	switch fields[0] {
+	case "close":
+		sink(CloseEvent())
+	case "select":
+		index, _ := strconv.Atoi(arg(3))
+		sink(SelectEvent(arg(2), index))
+	case "submit":
+		values := map[string]string{}
+		for _, kv := range strings.Split(arg(4), ",") {
+			if k, v, ok := strings.Cut(kv, "="); ok {
+				values[k] = v
+			}
+		}
+		sink(SubmitEvent(arg(2), arg(3), values))
+	case "tray":
+		sink(TrayClickEvent())
+	case "dialog":
+		sink(DialogEvent(arg(2)))
+	case "menu":
+		index, _ := strconv.Atoi(arg(2))
+		sink(MenuEvent(index))
+	default:
+		t.Fatalf("unknown action %s", line)
+	}
+}
+
+// sameJSON reports whether got is the message want. An error of "*" in want
+// matches any error.
+func sameJSON(got, want string) bool {
+	var g, w map[string]any
+	if json.Unmarshal([]byte(got), &g) != nil || json.Unmarshal([]byte(want), &w) != nil {
+		return false
+	}
+	if w["error"] == "*" {
+		if e, ok := g["error"].(string); ok && e != "" {
+			g["error"] = "*"
+		}
+	}
+	return reflect.DeepEqual(g, w)
+}
+
+func TestConformance(t *testing.T) {
+	scripts, err := filepath.Glob("testdata/conformance/*.txt")
+	if err != nil || len(scripts) == 0 {
+		t.Fatal("no conformance scripts", err)
+	}
+	for _, script := range scripts {
+		t.Run(filepath.Base(script), func(t *testing.T) {
+			runScript(t, script)
+		})
+	}
+}
+
+func runScript(t *testing.T, script string) {
+	data, err := os.ReadFile(script)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := newRecorder()
+	server := NewServer(rec)
+	client, conn := net.Pipe()
+	served := make(chan struct{})
+	go func() {
+		server.Serve(conn)
+		close(served)
+	}()
+	defer client.Close()
+	lines := make(chan string)
+	go func() {
+		scanner := bufio.NewScanner(client)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+		close(lines)
+	}()
+
+	for n, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		where := fmt.Sprintf("%s:%d", filepath.Base(script), n+1)
+		kind, rest := line[0], strings.TrimSpace(line[1:])
+		switch kind {
+		case '>':
+			if _, err := client.Write([]byte(rest + "\n")); err != nil {
+				t.Fatalf("%s: %v", where, err)
+			}
+		case '<':
+			select {
+			case got := <-lines:
+				if !sameJSON(got, rest) {
+					t.Fatalf("%s: got %s, want %s", where, got, rest)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatalf("%s: timed out waiting for %s", where, rest)
+			}
+		case '=':
+			select {
+			case got := <-rec.calls:
+				if got != rest {
+					t.Fatalf("%s: got call %s, want %s", where, got, rest)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatalf("%s: timed out waiting for call %s", where, rest)
+			}
+		case '!':
+			if rest == "disconnect" {
+				client.Close()
+				<-served
+				continue
+			}
+			rec.act(t, rest)
+		default:
+			t.Fatalf("%s: bad line %q", where, line)
+		}
+	}
+	select {
+	case got := <-rec.calls:
+		t.Fatalf("unexpected call %s", got)
+	default:
+	}
+}
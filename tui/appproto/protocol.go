@@ -0,0 +1,192 @@
+// Package appproto lets programs outside TuiTop open windows on the desktop
+// and drive them with widgets, over the socket named by $TUITOP. See
+// PROTOCOL.md for the wire format.
+package appproto
+
+import (
+	"encoding/json"
+
+	"golang.org/x/xerrors"
+)
+
+// Version is the protocol version spoken by the server.
+const Version = 1
+
+// EnvVar names the socket in the environment of programs run on the desktop.
+const EnvVar = "TUITOP"
+
+// Request is a call from a client. ID is echoed in the response.
+type Request struct {
+	ID     int64           `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params,omitempty"`
+}
+
+// Response answers a request, with either a result or an error.
+type Response struct {
+	ID     int64           `json:"id"`
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  string          `json:"error,omitempty"`
+}
+
+// Event tells a client what the user did. Window, Tray, Dialog and Menu are
+// the IDs the client chose, which are never 0.
+type Event struct {
+	Event  string `json:"event"`
+	Window int    `json:"window,omitempty"`
+	Tray   int    `json:"tray,omitempty"`
+	Dialog int    `json:"dialog,omitempty"`
+	Menu   int    `json:"menu,omitempty"`
+	Widget string `json:"widget,omitempty"`
+	// Index is the list item or table row selected, or the menu item
+	// chosen, -1 if the menu was dismissed
+	Index  *int              `json:"index,omitempty"`
+	Button string            `json:"button,omitempty"`
+	Values map[string]string `json:"values,omitempty"`
+}
+
+// Events sent to clients.
+const (
+	EventClose     = "close"
+	EventSelect    = "select"
+	EventSubmit    = "submit"
+	EventTrayClick = "tray.click"
+	EventDialog    = "dialog.done"
+	EventMenu      = "menu.done"
+)
+
+// CloseEvent tells that the user closed a window.
+func CloseEvent() Event {
+	return Event{Event: EventClose}
+}
+
+// SelectEvent tells that the user chose item or row index of a list or table.
+func SelectEvent(widget string, index int) Event {
+	return Event{Event: EventSelect, Widget: widget, Index: &index}
+}
+
+// SubmitEvent tells that the user pressed a button of a form, with the
+// values of its fields. Checkboxes are "true" or "false".
+func SubmitEvent(widget string, button string, values map[string]string) Event {
+	return Event{Event: EventSubmit, Widget: widget, Button: button, Values: values}
+}
+
+// TrayClickEvent tells that the user clicked a tray item.
+func TrayClickEvent() Event {
+	return Event{Event: EventTrayClick}
+}
+
+// DialogEvent tells which button of a dialog was pressed, "" if it was
+// dismissed.
+func DialogEvent(button string) Event {
+	return Event{Event: EventDialog, Button: button}
+}
+
+// MenuEvent tells which item of a menu was chosen, -1 if it was dismissed.
+func MenuEvent(index int) Event {
+	return Event{Event: EventMenu, Index: &index}
+}
+
+// Widget types.
+const (
+	WidgetText  = "text"
+	WidgetList  = "list"
+	WidgetTable = "table"
+	WidgetForm  = "form"
+	WidgetFlex  = "flex"
+)
+
+// Widget describes a widget and, for flex, the widgets it lays out. Only
+// the fields of its type are used.
+type Widget struct {
+	// ID names the widget in events and updates. It is optional, but must
+	// be unique in the window
+	ID   string `json:"id,omitempty"`
+	Type string `json:"type"`
+	// Title is shown in a border around the widget, if set
+	Title string `json:"title,omitempty"`
+
+	// Text of a text widget, which may use cview color tags
+	Text string `json:"text,omitempty"`
+	// Items of a list
+	Items []string `json:"items,omitempty"`
+	// Rows of a table, the first being the header
+	Rows [][]string `json:"rows,omitempty"`
+	// Fields and Buttons of a form
+	Fields  []Field  `json:"fields,omitempty"`
+	Buttons []string `json:"buttons,omitempty"`
+	// Direction of a flex, "row" to stack children top to bottom or
+	// "column", the default, to put them side by side
+	Direction string  `json:"direction,omitempty"`
+	Children  []Child `json:"children,omitempty"`
+}
+
+// Child is a widget laid out by a flex. It takes Size cells, or if Size is
+// 0 a share of the space left proportional to Weight.
+type Child struct {
+	Widget Widget `json:"widget"`
+	Size   int    `json:"size,omitempty"`
+	Weight int    `json:"weight,omitempty"`
+}
+
+// Field types.
+const (
+	FieldInput    = "input"
+	FieldPassword = "password"
+	FieldCheckbox = "checkbox"
+	FieldDropdown = "dropdown"
+)
+
+// Field is a field of a form. Value is the text of inputs, "true" for
+// checked checkboxes, or the option selected in a dropdown.
+type Field struct {
+	ID      string   `json:"id"`
+	Type    string   `json:"type"`
+	Label   string   `json:"label,omitempty"`
+	Value   string   `json:"value,omitempty"`
+	Options []string `json:"options,omitempty"`
+}
+
+// widgetTypes returns the type of each widget with an ID in the tree of w,
+// checking the tree is valid.
+func widgetTypes(w Widget) (map[string]string, error) {
+	types := map[string]string{}
+	return types, collectTypes(w, types)
+}
+
+func collectTypes(w Widget, types map[string]string) error {
+	if w.ID != "" {
+		if _, ok := types[w.ID]; ok {
+			return xerrors.Errorf("widget id %q used twice", w.ID)
+		}
+		types[w.ID] = w.Type
+	}
+	switch w.Type {
+	case WidgetText, WidgetList, WidgetTable:
+	case WidgetForm:
+		ids := map[string]bool{}
+		for _, f := range w.Fields {
+			switch f.Type {
+			case FieldInput, FieldPassword, FieldCheckbox, FieldDropdown:
+			default:
+				return xerrors.Errorf("unknown field type %q", f.Type)
+			}
+			if f.ID == "" || ids[f.ID] {
+				return xerrors.Errorf("form fields need unique ids")
+			}
+			ids[f.ID] = true
+		}
+	case WidgetFlex:
+		if w.Direction != "" && w.Direction != "row" && w.Direction != "column" {
+			return xerrors.Errorf("unknown flex direction %q", w.Direction)
+		}
+		for _, c := range w.Children {
+			if err := collectTypes(c.Widget, types); err != nil {
+				return err
+			}
+		}
+	default:
+		return xerrors.Errorf("unknown widget type %q", w.Type)
+	}
+	return nil
+}
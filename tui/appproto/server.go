@@ -0,0 +1,543 @@
+package appproto
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net"
+	"os"
+	"sync"
+
+	"golang.org/x/xerrors"
+)
+
+// Sink passes an event to the client owning a window, tray item, dialog or
+// menu. It never blocks, and may be called from any goroutine.
+type Sink func(ev Event)
+
+// Backend shows on the desktop what clients ask for. IDs are unique across
+// clients. Methods are called from the goroutines of the connections.
+type Backend interface {
+	// OpenWindow opens a window showing root. events takes the select,
+	// submit and close events of the window.
+	OpenWindow(id int, title string, width, height int, root Widget, events Sink) error
+	SetTitle(id int, title string)
+	SetContent(id int, root Widget)
+	// UpdateWidget replaces the widget with the ID of w in the window.
+	UpdateWidget(id int, w Widget)
+	// CloseWindow closes a window, without a close event.
+	CloseWindow(id int)
+
+	Notify(title, body string)
+
+	// AddTray adds an item to the tray. events takes its clicks.
+	AddTray(id int, text string, events Sink)
+	SetTray(id int, text string)
+	RemoveTray(id int)
+
+	// ShowDialog and ShowMenu pass the answer to events, then close the
+	// dialog or menu.
+	ShowDialog(id int, title, text string, buttons []string, events Sink)
+	ShowMenu(id int, items []string, events Sink)
+	// Dismiss closes a dialog or menu which was not answered, without an
+	// event.
+	Dismiss(id int)
+}
+
+// DefaultWidth and DefaultHeight are the size of windows opened without one.
+const DefaultWidth, DefaultHeight = 60, 16
+
+// outQueue is how many messages wait for a client before it is dropped as
+// not reading.
+const outQueue = 256
+
+// Server serves clients.
+type Server struct {
+	backend Backend
+	l       net.Listener
+	path    string
+
+	mu     sync.Mutex
+	nextID int
+	conns  map[*conn]bool
+}
+
+// NewServer returns a server showing windows with backend. Connections are
+// passed to Serve.
+func NewServer(backend Backend) *Server {
+	return &Server{backend: backend, conns: map[*conn]bool{}}
+}
+
+// Listen serves clients connecting to the unix socket at path, replacing
+// what is there. Only the user may connect.
+func Listen(path string, backend Backend) (*Server, error) {
+	s := NewServer(backend)
+	os.Remove(path)
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, xerrors.Errorf("cannot listen on %s: %w", path, err)
+	}
+	if err := os.Chmod(path, 0o600); err != nil {
+		l.Close()
+		return nil, xerrors.Errorf("cannot restrict %s: %w", path, err)
+	}
+	s.l, s.path = l, path
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go s.Serve(c)
+		}
+	}()
+	return s, nil
+}
+
+// Path returns the socket the server listens on.
+func (s *Server) Path() string {
+	return s.path
+}
+
+// Close stops listening and disconnects the clients, closing their windows.
+func (s *Server) Close() error {
+	var err error
+	if s.l != nil {
+		err = s.l.Close()
+		os.Remove(s.path)
+	}
+	s.mu.Lock()
+	conns := s.conns
+	s.conns = map[*conn]bool{}
+	s.mu.Unlock()
+	for c := range conns {
+		c.rw.Close()
+	}
+	return err
+}
+
+func (s *Server) newID() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nextID += 1
+	return s.nextID
+}
+
+// Serve talks to a client until it disconnects.
+func (s *Server) Serve(rw io.ReadWriteCloser) {
+	c := &conn{
+		s:       s,
+		rw:      rw,
+		out:     make(chan any, outQueue),
+		done:    make(chan struct{}),
+		windows: map[int]*window{},
+		trays:   map[int]int{},
+		pending: map[pendingKey]int{},
+	}
+	s.mu.Lock()
+	s.conns[c] = true
+	s.mu.Unlock()
+	go c.write()
+	c.read()
+	c.cleanup()
+	s.mu.Lock()
+	delete(s.conns, c)
+	s.mu.Unlock()
+}
+
+// conn is a connected client. The IDs of its maps are those chosen by the
+// client, and their values those passed to the backend.
+type conn struct {
+	s    *Server
+	rw   io.ReadWriteCloser
+	out  chan any
+	done chan struct{}
+	once sync.Once
+
+	mu      sync.Mutex
+	hello   bool
+	windows map[int]*window
+	trays   map[int]int
+	// pending are the dialogs and menus waiting for an answer
+	pending map[pendingKey]int
+}
+
+type pendingKey struct {
+	menu bool
+	id   int
+}
+
+type window struct {
+	id   int
+	root Widget
+	// types are the types of the widgets with an ID
+	types map[string]string
+}
+
+func (c *conn) read() {
+	scanner := bufio.NewScanner(c.rw)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var req Request
+		if err := json.Unmarshal(scanner.Bytes(), &req); err != nil {
+			c.send(Response{Error: "cannot parse request: " + err.Error()})
+			continue
+		}
+		result, err := c.handle(req)
+		resp := Response{ID: req.ID}
+		if err != nil {
+			resp.Error = err.Error()
+		} else {
+			if result == nil {
+				result = struct{}{}
+			}
+			resp.Result, _ = json.Marshal(result)
+		}
+		c.send(resp)
+	}
+}
+
+func (c *conn) write() {
+	w := bufio.NewWriter(c.rw)
+	enc := json.NewEncoder(w)
+	for {
+		select {
+		case msg := <-c.out:
+			if err := enc.Encode(msg); err != nil {
+				c.close()
+				return
+			}
+			if len(c.out) == 0 {
+				if err := w.Flush(); err != nil {
+					c.close()
+					return
+				}
+			}
+		case <-c.done:
+			return
+		}
+	}
+}
+
+// send queues a message for the client, dropping the client if it doesn't
+// keep up.
+func (c *conn) send(msg any) {
+	select {
+	case <-c.done:
+		return
+	default:
+	}
+	select {
+	case c.out <- msg:
+	default:
+		log.Print("appproto: dropping client which doesn't read")
+		c.close()
+	}
+}
+
+func (c *conn) close() {
+	c.once.Do(func() {
+		close(c.done)
+		c.rw.Close()
+	})
+}
+
+// cleanup closes what the client left open.
+func (c *conn) cleanup() {
+	c.close()
+	c.mu.Lock()
+	windows, trays, pending := c.windows, c.trays, c.pending
+	c.windows, c.trays, c.pending = map[int]*window{}, map[int]int{}, map[pendingKey]int{}
+	c.mu.Unlock()
+	for _, w := range windows {
+		c.s.backend.CloseWindow(w.id)
+	}
+	for _, id := range trays {
+		c.s.backend.RemoveTray(id)
+	}
+	for _, id := range pending {
+		c.s.backend.Dismiss(id)
+	}
+}
+
+// params are the parameters of all methods, each using some of them.
+type params struct {
+	Name    string   `json:"name"`
+	Version int      `json:"version"`
+	Window  int      `json:"window"`
+	Tray    int      `json:"tray"`
+	Dialog  int      `json:"dialog"`
+	Menu    int      `json:"menu"`
+	Title   string   `json:"title"`
+	Body    string   `json:"body"`
+	Text    string   `json:"text"`
+	Width   int      `json:"width"`
+	Height  int      `json:"height"`
+	Root    *Widget  `json:"root"`
+	Widget  *Widget  `json:"widget"`
+	Buttons []string `json:"buttons"`
+	Items   []string `json:"items"`
+}
+
+var (
+	errNoHello = errors.New("say hello first")
+	errNoRoot  = errors.New("root widget missing")
+	// errZeroID refuses ID 0, which events leave out
+	errZeroID = errors.New("IDs must not be 0")
+)
+
+func (c *conn) handle(req Request) (any, error) {
+	var p params
+	if len(req.Params) > 0 {
+		if err := json.Unmarshal(req.Params, &p); err != nil {
+			return nil, xerrors.Errorf("cannot parse params: %w", err)
+		}
+	}
+	if req.Method == "hello" {
+		if p.Version != Version {
+			return nil, xerrors.Errorf("unsupported version %d, the server speaks %d", p.Version, Version)
+		}
+		c.mu.Lock()
+		c.hello = true
+		c.mu.Unlock()
+		return map[string]int{"version": Version}, nil
+	}
+	c.mu.Lock()
+	hello := c.hello
+	c.mu.Unlock()
+	if !hello {
+		return nil, errNoHello
+	}
+	b := c.s.backend
+	switch req.Method {
+	case "window.open":
+		if p.Window == 0 {
+			return nil, errZeroID
+		}
+		if p.Root == nil {
+			return nil, errNoRoot
+		}
+		types, err := widgetTypes(*p.Root)
+		if err != nil {
+			return nil, err
+		}
+		if p.Width <= 0 || p.Height <= 0 {
+			p.Width, p.Height = DefaultWidth, DefaultHeight
+		}
+		c.mu.Lock()
+		if _, ok := c.windows[p.Window]; ok {
+			c.mu.Unlock()
+			return nil, xerrors.Errorf("window %d is already open", p.Window)
+		}
+		w := &window{id: c.s.newID(), root: *p.Root, types: types}
+		c.windows[p.Window] = w
+		c.mu.Unlock()
+		if err := b.OpenWindow(w.id, p.Title, p.Width, p.Height, w.root, c.windowSink(p.Window, w.id)); err != nil {
+			c.mu.Lock()
+			delete(c.windows, p.Window)
+			c.mu.Unlock()
+			return nil, err
+		}
+	case "window.title":
+		w, err := c.window(p.Window)
+		if err != nil {
+			return nil, err
+		}
+		b.SetTitle(w.id, p.Title)
+	case "window.content":
+		if p.Root == nil {
+			return nil, errNoRoot
+		}
+		types, err := widgetTypes(*p.Root)
+		if err != nil {
+			return nil, err
+		}
+		c.mu.Lock()
+		w, ok := c.windows[p.Window]
+		if ok {
+			w.root, w.types = *p.Root, types
+		}
+		c.mu.Unlock()
+		if !ok {
+			return nil, xerrors.Errorf("unknown window %d", p.Window)
+		}
+		b.SetContent(w.id, *p.Root)
+	case "widget.update":
+		if p.Widget == nil || p.Widget.ID == "" {
+			return nil, errors.New("widget with an id missing")
+		}
+		id, err := c.update(p.Window, *p.Widget)
+		if err != nil {
+			return nil, err
+		}
+		b.UpdateWidget(id, *p.Widget)
+	case "window.close":
+		c.mu.Lock()
+		w, ok := c.windows[p.Window]
+		delete(c.windows, p.Window)
+		c.mu.Unlock()
+		if !ok {
+			return nil, xerrors.Errorf("unknown window %d", p.Window)
+		}
+		b.CloseWindow(w.id)
+	case "notify":
+		b.Notify(p.Title, p.Body)
+	case "tray.add":
+		if p.Tray == 0 {
+			return nil, errZeroID
+		}
+		c.mu.Lock()
+		if _, ok := c.trays[p.Tray]; ok {
+			c.mu.Unlock()
+			return nil, xerrors.Errorf("tray item %d already added", p.Tray)
+		}
+		id := c.s.newID()
+		c.trays[p.Tray] = id
+		c.mu.Unlock()
+		tray := p.Tray
+		b.AddTray(id, p.Text, func(ev Event) {
+			ev.Tray = tray
+			c.send(ev)
+		})
+	case "tray.set", "tray.remove":
+		c.mu.Lock()
+		id, ok := c.trays[p.Tray]
+		if req.Method == "tray.remove" {
+			delete(c.trays, p.Tray)
+		}
+		c.mu.Unlock()
+		if !ok {
+			return nil, xerrors.Errorf("unknown tray item %d", p.Tray)
+		}
+		if req.Method == "tray.set" {
+			b.SetTray(id, p.Text)
+		} else {
+			b.RemoveTray(id)
+		}
+	case "dialog.show":
+		if p.Dialog == 0 {
+			return nil, errZeroID
+		}
+		if len(p.Buttons) == 0 {
+			p.Buttons = []string{"OK"}
+		}
+		id, sink, err := c.addPending(pendingKey{id: p.Dialog}, func(ev *Event) { ev.Dialog = p.Dialog })
+		if err != nil {
+			return nil, err
+		}
+		b.ShowDialog(id, p.Title, p.Text, p.Buttons, sink)
+	case "menu.show":
+		if p.Menu == 0 {
+			return nil, errZeroID
+		}
+		if len(p.Items) == 0 {
+			return nil, errors.New("menu items missing")
+		}
+		id, sink, err := c.addPending(pendingKey{menu: true, id: p.Menu}, func(ev *Event) { ev.Menu = p.Menu })
+		if err != nil {
+			return nil, err
+		}
+		b.ShowMenu(id, p.Items, sink)
+	default:
+		return nil, xerrors.Errorf("unknown method %q", req.Method)
+	}
+	return nil, nil
+}
+
+func (c *conn) window(clientID int) (*window, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	w, ok := c.windows[clientID]
+	if !ok {
+		return nil, xerrors.Errorf("unknown window %d", clientID)
+	}
+	return w, nil
+}
+
+// windowSink passes on the events of a window. The window is forgotten once
+// the user closed it.
+func (c *conn) windowSink(clientID int, id int) Sink {
+	return func(ev Event) {
+		ev.Window = clientID
+		if ev.Event == EventClose {
+			c.mu.Lock()
+			if w, ok := c.windows[clientID]; ok && w.id == id {
+				delete(c.windows, clientID)
+			}
+			c.mu.Unlock()
+		}
+		c.send(ev)
+	}
+}
+
+// update replaces a widget in the tree of a window, returning the window's
+// backend ID.
+func (c *conn) update(clientID int, widget Widget) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	w, ok := c.windows[clientID]
+	if !ok {
+		return 0, xerrors.Errorf("unknown window %d", clientID)
+	}
+	typ, ok := w.types[widget.ID]
+	if !ok {
+		return 0, xerrors.Errorf("unknown widget %q", widget.ID)
+	}
+	if typ != widget.Type {
+		return 0, xerrors.Errorf("widget %q is a %s, not a %s", widget.ID, typ, widget.Type)
+	}
+	root := w.root
+	replaceWidget(&root, widget)
+	types, err := widgetTypes(root)
+	if err != nil {
+		return 0, err
+	}
+	w.root, w.types = root, types
+	return w.id, nil
+}
+
+// replaceWidget replaces the widget in the tree of root with the ID of w.
+func replaceWidget(root *Widget, w Widget) bool {
+	if root.ID == w.ID {
+		*root = w
+		return true
+	}
+	// Copy the children rather than change the tree the backend was given
+	children := append([]Child(nil), root.Children...)
+	for i := range children {
+		if replaceWidget(&children[i].Widget, w) {
+			root.Children = children
+			return true
+		}
+	}
+	return false
+}
+
+// addPending registers a dialog or menu, returning its backend ID and the
+// sink taking its answer.
+func (c *conn) addPending(key pendingKey, setID func(ev *Event)) (int, Sink, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.pending[key]; ok {
+		if key.menu {
+			return 0, nil, xerrors.Errorf("menu %d is already shown", key.id)
+		}
+		return 0, nil, xerrors.Errorf("dialog %d is already shown", key.id)
+	}
+	id := c.s.newID()
+	c.pending[key] = id
+	return id, func(ev Event) {
+		c.mu.Lock()
+		if c.pending[key] != id {
+			// Answered already
+			c.mu.Unlock()
+			return
+		}
+		delete(c.pending, key)
+		c.mu.Unlock()
+		setID(&ev)
+		c.send(ev)
+	}, nil
+}
@@ -0,0 +1,69 @@
+// Package clipboard copies text to the system clipboard and pastes it.
+package clipboard
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// tools copy their standard input to the clipboard.
+var tools = [][]string{
+	{"wl-copy"},
+	{"xclip", "-selection", "clipboard"},
+	{"xsel", "--clipboard", "--input"},
+	{"pbcopy"},
+}
+
+// Copy puts text on the clipboard with the first clipboard tool found. Without
+// one, as over SSH, the terminal TuiTop runs in is asked to with OSC 52.
+func Copy(text string) error {
+	for _, tool := range tools {
+		if _, err := exec.LookPath(tool[0]); err != nil {
+			continue
+		}
+		cmd := exec.Command(tool[0], tool[1:]...)
+		cmd.Stdin = strings.NewReader(text)
+		if err := cmd.Run(); err == nil {
+			return nil
+		}
+	}
+	tty, err := os.OpenFile("/dev/tty", os.O_WRONLY, 0)
+	if err != nil {
+		return fmt.Errorf("cannot open terminal: %w", err)
+	}
+	defer tty.Close()
+	_, err = fmt.Fprintf(tty, "\x1b]52;c;%s\x07", base64.StdEncoding.EncodeToString([]byte(text)))
+	return err
+}
+
+// pasteTools print the clipboard.
+var pasteTools = [][]string{
+	{"wl-paste", "--no-newline"},
+	{"xclip", "-selection", "clipboard", "-out"},
+	{"xsel", "--clipboard", "--output"},
+	{"pbpaste"},
+}
+
+// Paste returns the text on the clipboard, read with the first clipboard
+// tool found.
+func Paste() (string, error) {
+	var errs error
+	for _, tool := range pasteTools {
+		if _, err := exec.LookPath(tool[0]); err != nil {
+			continue
+		}
+		out, err := exec.Command(tool[0], tool[1:]...).Output()
+		if err == nil {
+			return string(out), nil
+		}
+		errs = errors.Join(errs, fmt.Errorf("%s: %w", tool[0], err))
+	}
+	if errs == nil {
+		return "", errors.New("no clipboard tool found, such as wl-paste or xclip")
+	}
+	return "", errs
+}
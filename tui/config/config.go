@@ -0,0 +1,140 @@
+// Package config reads the user's settings from config.yaml in the TuiTop
+// config folder.
+package config
+
+import (
+	"errors"
+	"os"
+	"path"
+	"time"
+
+	"golang.org/x/xerrors"
+	"gopkg.in/yaml.v3"
+)
+
+type Config struct {
+	Links    Links    `yaml:"links"`
+	Tray     Tray     `yaml:"tray"`
+	Clock    Clock    `yaml:"clock"`
+	Desktop  Desktop  `yaml:"desktop"`
+	Terminal Terminal `yaml:"terminal"`
+}
+
+type Links struct {
+	// Open is how clicked links are opened: "xdg-open", "clipboard" to copy
+	// them, or "app:" followed by the name of an app from the catalogue,
+	// such as "app:browser". The default is xdg-open.
+	Open string `yaml:"open"`
+}
+
+// Tray is what the tray shows.
+type Tray struct {
+	// Widgets are shown left to right, out of cpu, memory, load, battery,
+	// network, disk, keyboard and clock.
+	Widgets []string `yaml:"widgets"`
+	// Disk is the filesystem of the disk widget, / by default.
+	Disk string `yaml:"disk"`
+	// Intervals override how often widgets update, such as "network: 5s".
+	Intervals map[string]time.Duration `yaml:"intervals"`
+}
+
+// Clock is how the time is shown in the tray and the Clock app.
+type Clock struct {
+	// Hours is 24, the default, or 12 for AM and PM.
+	Hours int `yaml:"hours"`
+	// Seconds shows the seconds in the tray.
+	Seconds bool `yaml:"seconds"`
+	// World are clocks of other timezones shown under the calendar.
+	World []WorldClock `yaml:"world"`
+}
+
+// WorldClock is the clock of another timezone.
+type WorldClock struct {
+	// Name labels the clock, by default the city of Zone.
+	Name string `yaml:"name"`
+	// Zone is a timezone such as America/New_York.
+	Zone string `yaml:"zone"`
+}
+
+// Desktop is the area behind the windows. The icons on it are saved in
+// icons.yaml as they are pinned and moved.
+type Desktop struct {
+	Wallpaper Wallpaper `yaml:"wallpaper"`
+}
+
+// Wallpaper is drawn behind the icons. File is used if set, else Gradient,
+// else Color. Colors are names such as "navy" or hex such as "#1d3557".
+type Wallpaper struct {
+	// Color fills the desktop, and is around ANSI art smaller than it. The
+	// default is the terminal's background.
+	Color string `yaml:"color"`
+	// Gradient are two colors or more blended from top to bottom.
+	Gradient []string `yaml:"gradient"`
+	// Horizontal blends the gradient from left to right instead.
+	Horizontal bool `yaml:"horizontal"`
+	// File is ANSI art, such as a .ans file, or a PNG or JPEG picture
+	// scaled to cover the desktop. ~/ is the home folder.
+	File string `yaml:"file"`
+}
+
+// Terminal is how terminal windows behave.
+type Terminal struct {
+	// WindowOps are the programs allowed to resize, raise, minimize and
+	// maximize their window with XTWINOPS, by name such as "vim", or "*"
+	// for all. The program is the one in the foreground of the window,
+	// such as vim run from a shell. None are by default.
+	WindowOps []string `yaml:"windowOps"`
+	// CellWidth and CellHeight are the size of a cell in pixels reported to
+	// programs, 8 by 16 by default.
+	CellWidth  int `yaml:"cellWidth"`
+	CellHeight int `yaml:"cellHeight"`
+}
+
+// AllowsWindowOps reports whether the program cmd, a name or a path, may use
+// XTWINOPS to change its window.
+func (t Terminal) AllowsWindowOps(cmd string) bool {
+	for _, name := range t.WindowOps {
+		if name == "*" || name == path.Base(cmd) {
+			return true
+		}
+	}
+	return false
+}
+
+// Dir returns the TuiTop config folder.
+func Dir() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", xerrors.Errorf("cannot get user config directory: %w", err)
+	}
+	return path.Join(dir, "tuitop"), nil
+}
+
+// Load reads the settings. Settings which are not set have their defaults,
+// including when there is no config file.
+func Load() (*Config, error) {
+	cfg := &Config{
+		Links: Links{Open: "xdg-open"},
+		Tray: Tray{
+			Widgets: []string{"cpu", "memory", "battery", "network", "keyboard", "clock"},
+			Disk:    "/",
+		},
+		Clock: Clock{Hours: 24},
+	}
+	dir, err := Dir()
+	if err != nil {
+		return cfg, err
+	}
+	f, err := os.Open(path.Join(dir, "config.yaml"))
+	if errors.Is(err, os.ErrNotExist) {
+		return cfg, nil
+	}
+	if err != nil {
+		return cfg, xerrors.Errorf("cannot read config: %w", err)
+	}
+	defer f.Close()
+	if err := yaml.NewDecoder(f).Decode(cfg); err != nil {
+		return cfg, xerrors.Errorf("cannot parse %s: %w", f.Name(), err)
+	}
+	return cfg, nil
+}
@@ -0,0 +1,67 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path"
+
+	"golang.org/x/xerrors"
+	"gopkg.in/yaml.v3"
+)
+
+// Icon is an app pinned to the desktop.
+type Icon struct {
+	// App is the name of the app in the catalogue, such as "clock".
+	App string `yaml:"app"`
+	// X and Y are the column and row of its top left corner.
+	X int `yaml:"x"`
+	Y int `yaml:"y"`
+}
+
+// defaultIcons are on the desktop until icons are pinned or moved.
+var defaultIcons = []Icon{
+	{App: "tasks", X: 2, Y: 1},
+	{App: "clock", X: 2, Y: 5},
+	{App: "ssh", X: 2, Y: 9},
+}
+
+// LoadIcons reads the icons on the desktop from icons.yaml in the config
+// folder.
+func LoadIcons() ([]Icon, error) {
+	icons := append([]Icon{}, defaultIcons...)
+	dir, err := Dir()
+	if err != nil {
+		return icons, err
+	}
+	data, err := os.ReadFile(path.Join(dir, "icons.yaml"))
+	if errors.Is(err, os.ErrNotExist) {
+		return icons, nil
+	}
+	if err != nil {
+		return icons, xerrors.Errorf("cannot read icons: %w", err)
+	}
+	icons = []Icon{}
+	if err := yaml.Unmarshal(data, &icons); err != nil {
+		return append([]Icon{}, defaultIcons...), xerrors.Errorf("cannot parse icons.yaml: %w", err)
+	}
+	return icons, nil
+}
+
+// SaveIcons writes the icons on the desktop to icons.yaml.
+func SaveIcons(icons []Icon) error {
+	dir, err := Dir()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return xerrors.Errorf("cannot create config folder: %w", err)
+	}
+	data, err := yaml.Marshal(icons)
+	if err != nil {
+		return xerrors.Errorf("cannot encode icons: %w", err)
+	}
+	if err := os.WriteFile(path.Join(dir, "icons.yaml"), data, 0o644); err != nil {
+		return xerrors.Errorf("cannot save icons: %w", err)
+	}
+	return nil
+}
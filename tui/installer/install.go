@@ -22,6 +22,10 @@ func New(createWindow tuiwindow.CreateWindow) *Installer {
 	if err != nil {
 		fmt.Println(err)
 	}
+	err = EnsureTerminfo()
+	if err != nil {
+		fmt.Println(err)
+	}
 	i := &Installer{
 		createWindow: createWindow,
 	}
@@ -35,6 +39,25 @@ type InstallerYaml struct {
 	CLI      string      `yaml:"cli"`
 	Get      AvailableAt `yaml:"get"`
 	Alt      altStruct   `yaml:"alt"`
+	// Window names a window type built into TuiTop, such as "ssh", or a
+	// native app, such as "clock", for apps which need nothing installed.
+	Window string `yaml:"window"`
+	// Menus are items opening the app added to the desktop's context menus.
+	Menus []MenuEntry `yaml:"menus"`
+}
+
+// MenuEntry is an item of a context menu opening an app.
+type MenuEntry struct {
+	// Menu is the menu the item is added to: "desktop", "window" for title
+	// bars or "terminal" for the inside of terminal windows.
+	Menu string `yaml:"menu"`
+	// Label is the text of the item, by default the name of the app.
+	Label string `yaml:"label"`
+	// Args are added to the command line. In them {link} is the link
+	// right-clicked, {selection} the selected text and {dir} the working
+	// directory of the terminal. Items needing one which is unknown, or
+	// starts with "-" like an option, are left out.
+	Args []string `yaml:"args"`
 }
 type AvailableAt struct {
 	Source             string        `yaml:"source"`
@@ -51,10 +74,26 @@ type altStruct struct {
 // First detecting it, then pkg, then source, then alt (similarly).
 // Returns the path and error.
 func (i *Installer) Ensure(yamlReader io.Reader) (path string, err error) {
-	// read yaml
+	y, err := ReadYaml(yamlReader)
+	if err != nil {
+		return "", err
+	}
+	return i.EnsureYaml(y)
+}
+
+// ReadYaml reads a program's yaml file definition.
+func ReadYaml(yamlReader io.Reader) (InstallerYaml, error) {
 	y := InstallerYaml{}
 	if err := yaml.NewDecoder(yamlReader).Decode(&y); err != nil {
-		return "", xerrors.Errorf("cannot unmarshal yaml: %w", err)
+		return y, xerrors.Errorf("cannot unmarshal yaml: %w", err)
+	}
+	return y, nil
+}
+
+// EnsureYaml is Ensure for a definition already read.
+func (i *Installer) EnsureYaml(y InstallerYaml) (path string, err error) {
+	if y.Window != "" {
+		return "", xerrors.Errorf("%s is built into TuiTop", y.Name)
 	}
 	// detect
 	if y.CLI == "" {
@@ -0,0 +1,42 @@
+package installer
+
+import (
+	"os"
+	"os/exec"
+	"path"
+
+	"github.com/snadrus/tuitop/deps/tcellterm"
+	"golang.org/x/xerrors"
+)
+
+// EnsureTerminfo compiles the tuitop terminfo entry into ~/.terminfo, unless
+// it is already installed. Windows use TERM=tuitop once it is.
+func EnsureTerminfo() error {
+	if tcellterm.TerminfoInstalled("tuitop") {
+		return nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return xerrors.Errorf("cannot get user home directory: %w", err)
+	}
+	tic, err := exec.LookPath("tic")
+	if err != nil {
+		return xerrors.Errorf("cannot install terminfo, tic not found: %w", err)
+	}
+	src, err := os.CreateTemp("", "tuitop-*.info")
+	if err != nil {
+		return xerrors.Errorf("cannot write terminfo source: %w", err)
+	}
+	defer os.Remove(src.Name())
+	if _, err := src.Write(tcellterm.TuitopTerminfo); err != nil {
+		src.Close()
+		return xerrors.Errorf("cannot write terminfo source: %w", err)
+	}
+	src.Close()
+
+	out, err := exec.Command(tic, "-x", "-o", path.Join(home, ".terminfo"), src.Name()).CombinedOutput()
+	if err != nil {
+		return xerrors.Errorf("tic: %s: %w", out, err)
+	}
+	return nil
+}
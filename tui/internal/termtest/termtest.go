@@ -0,0 +1,37 @@
+// Package termtest helps testing the packages connecting terminals.
+package termtest
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Expect reads from r until want was read, failing t if it isn't within 5
+// seconds.
+func Expect(t testing.TB, r io.Reader, want string) {
+	t.Helper()
+	// done is buffered so that the reader doesn't leak if it is given up on
+	done := make(chan string, 1)
+	go func() {
+		got := ""
+		buf := make([]byte, 256)
+		for !strings.Contains(got, want) {
+			n, err := r.Read(buf)
+			got += string(buf[:n])
+			if err != nil {
+				break
+			}
+		}
+		done <- got
+	}()
+	select {
+	case got := <-done:
+		if !strings.Contains(got, want) {
+			t.Fatalf("read %q, want %q", got, want)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timed out reading %q", want)
+	}
+}
@@ -0,0 +1,119 @@
+package clock
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/snadrus/tuitop/tui/config"
+)
+
+// calendarWidth is the width of Month: the week numbers and seven days.
+const calendarWidth = 23
+
+// Layout returns the time layout set in cfg, with seconds if asked.
+func Layout(cfg config.Clock, seconds bool) string {
+	layout := "15:04"
+	if cfg.Hours == 12 {
+		layout = "3:04"
+	}
+	if seconds {
+		layout += ":05"
+	}
+	if cfg.Hours == 12 {
+		layout += " PM"
+	}
+	return layout
+}
+
+// Month returns the calendar of the month of selected, weeks starting on
+// Monday with their ISO week number. selected is highlighted, and today is
+// yellow.
+func Month(selected, today time.Time) string {
+	var text strings.Builder
+	title := selected.Format("January 2006")
+	fmt.Fprintf(&text, "%*s\n", (calendarWidth+len(title))/2, title)
+	text.WriteString("[gray]Wk[-] Mo Tu We Th Fr Sa Su\n")
+
+	first := time.Date(selected.Year(), selected.Month(), 1, 0, 0, 0, 0, selected.Location())
+	// Days from Monday
+	offset := (int(first.Weekday()) + 6) % 7
+	for d := first; d.Month() == selected.Month(); d = d.AddDate(0, 0, 1) {
+		if d.Day() == 1 || d.Weekday() == time.Monday {
+			_, week := d.ISOWeek()
+			fmt.Fprintf(&text, "[gray]%2d[-] ", week)
+		}
+		if d.Day() == 1 {
+			text.WriteString(strings.Repeat("   ", offset))
+		}
+		isToday := sameDay(d, today)
+		switch {
+		case d.Day() == selected.Day() && isToday:
+			fmt.Fprintf(&text, "[black:yellow]%2d[-:-]", d.Day())
+		case d.Day() == selected.Day():
+			fmt.Fprintf(&text, "[black:white]%2d[-:-]", d.Day())
+		case isToday:
+			fmt.Fprintf(&text, "[yellow]%2d[-]", d.Day())
+		default:
+			fmt.Fprintf(&text, "%2d", d.Day())
+		}
+		if d.Weekday() == time.Sunday {
+			text.WriteString("\n")
+		} else {
+			text.WriteString(" ")
+		}
+	}
+	return strings.TrimRight(text.String(), " \n")
+}
+
+// describeDay tells the date of day, its week, and how far it is from today.
+func describeDay(day, today time.Time) string {
+	_, week := day.ISOWeek()
+	text := fmt.Sprintf("%s, week %d\n", day.Format("Mon 2 Jan 2006"), week)
+	// Count in dates, so daylight saving time doesn't matter
+	days := int(time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.UTC).Sub(
+		time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, time.UTC)).Hours() / 24)
+	switch {
+	case days == 0:
+		text += "Today"
+	case days == 1:
+		text += "Tomorrow"
+	case days == -1:
+		text += "Yesterday"
+	case days > 0:
+		text += fmt.Sprintf("In %d days", days)
+	default:
+		text += fmt.Sprintf("%d days ago", -days)
+	}
+	return text
+}
+
+func sameDay(a, b time.Time) bool {
+	return a.Year() == b.Year() && a.YearDay() == b.YearDay()
+}
+
+// worldClock is a clock of another timezone.
+type worldClock struct {
+	name string
+	loc  *time.Location
+}
+
+// worldClocks loads the timezones of clocks. Clocks of unknown timezones are
+// labelled as such.
+func worldClocks(clocks []config.WorldClock) []worldClock {
+	found := []worldClock{}
+	for _, c := range clocks {
+		name := c.Name
+		if name == "" {
+			name = strings.ReplaceAll(path.Base(c.Zone), "_", " ")
+		}
+		loc, err := time.LoadLocation(c.Zone)
+		if err != nil {
+			name += " (unknown zone)"
+			loc = nil
+		}
+		found = append(found, worldClock{name, loc})
+	}
+	return found
+}
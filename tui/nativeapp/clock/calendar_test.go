@@ -0,0 +1,83 @@
+package clock
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/snadrus/tuitop/tui/config"
+)
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 12, 0, 0, 0, time.UTC)
+}
+
+func TestMonth(t *testing.T) {
+	elsewhen := date(2000, 6, 15)
+	for _, test := range []struct {
+		name            string
+		selected, today time.Time
+		// line is the line of the calendar checked, counting the title and
+		// the day names
+		line int
+		want string
+	}{
+		{"title", date(2020, 12, 31), elsewhen, 0, "     December 2020"},
+		{"days", date(2020, 12, 31), elsewhen, 1, "[gray]Wk[-] Mo Tu We Th Fr Sa Su"},
+		{"first week", date(2020, 12, 31), elsewhen, 2, "[gray]49[-]     1  2  3  4  5  6"},
+		{"week 53", date(2020, 12, 31), elsewhen, 6, "[gray]53[-] 28 29 30 [black:white]31[-:-]"},
+		{"january in week 53", date(2021, 1, 10), elsewhen, 2, "[gray]53[-]              1  2  3"},
+		{"week 1 after week 53", date(2021, 1, 10), elsewhen, 3, "[gray] 1[-]  4  5  6  7  8  9 [black:white]10[-:-]"},
+		{"january in week 52", date(2022, 1, 20), elsewhen, 2, "[gray]52[-]                 1  2"},
+		{"week 1 after week 52", date(2022, 1, 20), elsewhen, 3, "[gray] 1[-]  3  4  5  6  7  8  9"},
+		{"starting on Sunday", date(2021, 8, 2), elsewhen, 2, "[gray]30[-]                    1"},
+		{"today", date(2021, 8, 2), date(2021, 8, 3), 3, "[gray]31[-] [black:white] 2[-:-] [yellow] 3[-]  4  5  6  7  8"},
+		{"selected today", date(2021, 8, 3), date(2021, 8, 3), 3, "[gray]31[-]  2 [black:yellow] 3[-:-]  4  5  6  7  8"},
+	} {
+		lines := strings.Split(Month(test.selected, test.today), "\n")
+		if test.line >= len(lines) {
+			t.Errorf("%s: got %d lines", test.name, len(lines))
+			continue
+		}
+		if got := lines[test.line]; got != test.want {
+			t.Errorf("%s: got %q, want %q", test.name, got, test.want)
+		}
+	}
+}
+
+func TestDescribeDay(t *testing.T) {
+	today := date(2021, 1, 1)
+	for _, test := range []struct {
+		day  time.Time
+		want string
+	}{
+		{today, "Fri 1 Jan 2021, week 53\nToday"},
+		{date(2021, 1, 2), "Sat 2 Jan 2021, week 53\nTomorrow"},
+		{date(2020, 12, 31), "Thu 31 Dec 2020, week 53\nYesterday"},
+		{date(2021, 1, 4), "Mon 4 Jan 2021, week 1\nIn 3 days"},
+		{date(2020, 12, 27), "Sun 27 Dec 2020, week 52\n5 days ago"},
+		// Late at night in another zone is still the day shown
+		{time.Date(2021, 1, 11, 23, 30, 0, 0, time.FixedZone("", -10*3600)), "Mon 11 Jan 2021, week 2\nIn 10 days"},
+	} {
+		if got := describeDay(test.day, today); got != test.want {
+			t.Errorf("%v: got %q, want %q", test.day, got, test.want)
+		}
+	}
+}
+
+func TestLayout(t *testing.T) {
+	for _, test := range []struct {
+		hours   int
+		seconds bool
+		want    string
+	}{
+		{0, false, "15:04"},
+		{24, true, "15:04:05"},
+		{12, false, "3:04 PM"},
+		{12, true, "3:04:05 PM"},
+	} {
+		if got := Layout(config.Clock{Hours: test.hours}, test.seconds); got != test.want {
+			t.Errorf("%d hours, seconds %v: got %q, want %q", test.hours, test.seconds, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,243 @@
+// Package clock is the Clock/Calendar app: the time, a month calendar, world
+// clocks, a stopwatch and a timer.
+package clock
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/snadrus/tuitop/deps/cview"
+	"github.com/snadrus/tuitop/tui/config"
+	"github.com/snadrus/tuitop/tui/nativeapp"
+)
+
+func init() {
+	nativeapp.Register(clock{})
+}
+
+type clock struct{}
+
+func (clock) Info() nativeapp.Info {
+	return nativeapp.Info{
+		Name:   "clock",
+		Title:  "🕒 Clock",
+		Width:  calendarWidth + 8,
+		Height: 22,
+	}
+}
+
+// view is a Clock window. Keys change the selected day and timers on the UI
+// goroutine while a ticker redraws it, hence the lock.
+type view struct {
+	cfg   config.Clock
+	world []worldClock
+	d     nativeapp.Desktop
+	time  *cview.TextView
+	body  *cview.TextView
+
+	mu       sync.Mutex
+	selected time.Time
+	// entry is the length of a new timer while it is being typed
+	entry    string
+	entering bool
+	entryErr string
+}
+
+// Start shows the time over the calendar of the month, world clocks, the
+// stopwatch and the timer, updated every second. Escape or q closes the
+// window.
+func (clock) Start(w nativeapp.Window, d nativeapp.Desktop) (cview.Primitive, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		log.Print(err)
+	}
+	v := &view{
+		cfg:      cfg.Clock,
+		world:    worldClocks(cfg.Clock.World),
+		d:        d,
+		time:     cview.NewTextView(),
+		body:     cview.NewTextView(),
+		selected: time.Now(),
+	}
+	v.time.SetTextAlign(cview.AlignCenter)
+	v.body.SetDynamicColors(true)
+	v.body.SetPadding(0, 0, 1, 0)
+	v.update(time.Now())
+	if len(v.world) > 0 {
+		info := clock{}.Info()
+		w.Resize(info.Width, info.Height+len(v.world)+1)
+	}
+
+	ticker := time.NewTicker(time.Second)
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case now := <-ticker.C:
+				v.update(now)
+				d.Redraw()
+			case <-done:
+				return
+			}
+		}
+	}()
+	w.SetCloseHandler(func() {
+		ticker.Stop()
+		close(done)
+	})
+
+	root := cview.NewFlex()
+	root.SetDirection(cview.FlexRow)
+	root.AddItem(v.time, 3, 0, false)
+	root.AddItem(v.body, 0, 1, true)
+	root.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if v.typing(event) {
+			v.update(time.Now())
+			return nil
+		}
+		if event.Key() == tcell.KeyEscape || (event.Key() == tcell.KeyRune && event.Rune() == 'q') {
+			w.Close()
+			return nil
+		}
+		if !v.handleKey(event) {
+			return event
+		}
+		v.update(time.Now())
+		return nil
+	})
+	return root, nil
+}
+
+// handleKey moves through the calendar and drives the stopwatch and timer,
+// returning whether it used the key.
+func (v *view) handleKey(event *tcell.EventKey) bool {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	switch event.Key() {
+	case tcell.KeyLeft:
+		v.selected = v.selected.AddDate(0, 0, -1)
+	case tcell.KeyRight:
+		v.selected = v.selected.AddDate(0, 0, 1)
+	case tcell.KeyUp:
+		v.selected = v.selected.AddDate(0, 0, -7)
+	case tcell.KeyDown:
+		v.selected = v.selected.AddDate(0, 0, 7)
+	case tcell.KeyPgUp:
+		v.selected = addMonths(v.selected, -1)
+	case tcell.KeyPgDn:
+		v.selected = addMonths(v.selected, 1)
+	case tcell.KeyHome:
+		v.selected = time.Now()
+	case tcell.KeyRune:
+		switch event.Rune() {
+		case 't':
+			v.selected = time.Now()
+		case 's':
+			toggleStopwatch(time.Now())
+		case 'r':
+			resetStopwatch()
+		case 'n':
+			v.entering, v.entry, v.entryErr = true, "", ""
+		case 'c':
+			cancelTimer()
+		default:
+			return false
+		}
+	default:
+		return false
+	}
+	return true
+}
+
+// typing takes the keys typing the length of a new timer, returning whether
+// it used the key.
+func (v *view) typing(event *tcell.EventKey) bool {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if !v.entering {
+		return false
+	}
+	switch event.Key() {
+	case tcell.KeyEscape:
+		v.entering = false
+	case tcell.KeyBackspace, tcell.KeyBackspace2:
+		if v.entry != "" {
+			v.entry = v.entry[:len(v.entry)-1]
+		}
+	case tcell.KeyEnter:
+		length, err := parseTimer(v.entry)
+		if err != nil {
+			v.entryErr = err.Error()
+			return true
+		}
+		v.entering = false
+		startTimer(length, func() {
+			v.d.Notify("⏰ Timer", formatDuration(length)+" timer done")
+		})
+	case tcell.KeyRune:
+		if len(v.entry) < 10 && strings.ContainsRune("0123456789.hms", event.Rune()) {
+			v.entry += string(event.Rune())
+			v.entryErr = ""
+		}
+	}
+	return true
+}
+
+// addMonths moves day by months, keeping to the last day of shorter months.
+func addMonths(day time.Time, months int) time.Time {
+	first := time.Date(day.Year(), day.Month()+time.Month(months), 1, 0, 0, 0, 0, day.Location())
+	last := first.AddDate(0, 1, -1).Day()
+	return first.AddDate(0, 0, min(day.Day(), last)-1)
+}
+
+func (v *view) update(now time.Time) {
+	v.time.SetText(now.Format(Layout(v.cfg, true) + "\nMonday 2 January"))
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	var text strings.Builder
+	text.WriteString(Month(v.selected, now))
+	text.WriteString("\n\n")
+	text.WriteString(describeDay(v.selected, now))
+	text.WriteString("\n\n")
+
+	if len(v.world) > 0 {
+		for _, c := range v.world {
+			if c.loc == nil {
+				fmt.Fprintf(&text, "%s\n", c.name)
+				continue
+			}
+			there := now.In(c.loc)
+			day := ""
+			if !sameDay(there, now) {
+				day = there.Format(" Mon")
+			}
+			fmt.Fprintf(&text, "%-12.12s %s%s\n", c.name, there.Format(Layout(v.cfg, false)), day)
+		}
+		text.WriteString("\n")
+	}
+
+	stopwatch, ticking := stopwatchElapsed(now)
+	state := "paused"
+	if ticking {
+		state = "running"
+	}
+	fmt.Fprintf(&text, "Stopwatch %s %s\n", formatDuration(stopwatch), state)
+	switch left, length, ok := timerLeft(now); {
+	case v.entering && v.entryErr != "":
+		fmt.Fprintf(&text, "Timer [red]%s[-]\n", v.entryErr)
+	case v.entering:
+		fmt.Fprintf(&text, "Timer length: %s_\n", v.entry)
+	case ok:
+		// Round up so the timer ends as it shows 0:00:00
+		fmt.Fprintf(&text, "Timer     %s of %s\n", formatDuration(left+time.Second-1), formatDuration(length))
+	default:
+		text.WriteString("Timer     off\n")
+	}
+	text.WriteString("\n[gray]←→↑↓ PgUp PgDn t: calendar\ns r: stopwatch  n c: timer[-]")
+	v.body.SetText(text.String())
+}
@@ -0,0 +1,112 @@
+package clock
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang.org/x/xerrors"
+)
+
+// The stopwatch and timer are shared by the Clock windows, so they keep
+// running when the window is closed and are there when it is opened again.
+var (
+	timersMu sync.Mutex
+	// elapsed is the time of the stopwatch before it last started
+	elapsed time.Duration
+	started time.Time
+	running bool
+
+	timer       *time.Timer
+	timerEnd    time.Time
+	timerLength time.Duration
+)
+
+// toggleStopwatch starts or pauses the stopwatch.
+func toggleStopwatch(now time.Time) {
+	timersMu.Lock()
+	defer timersMu.Unlock()
+	if running {
+		elapsed += now.Sub(started)
+	} else {
+		started = now
+	}
+	running = !running
+}
+
+func resetStopwatch() {
+	timersMu.Lock()
+	defer timersMu.Unlock()
+	elapsed, running = 0, false
+}
+
+func stopwatchElapsed(now time.Time) (time.Duration, bool) {
+	timersMu.Lock()
+	defer timersMu.Unlock()
+	if running {
+		return elapsed + now.Sub(started), true
+	}
+	return elapsed, false
+}
+
+// startTimer replaces the timer with one calling done after length.
+func startTimer(length time.Duration, done func()) {
+	timersMu.Lock()
+	defer timersMu.Unlock()
+	if timer != nil {
+		timer.Stop()
+	}
+	var t *time.Timer
+	t = time.AfterFunc(length, func() {
+		timersMu.Lock()
+		current := timer == t
+		if current {
+			timer = nil
+		}
+		timersMu.Unlock()
+		if current {
+			done()
+		}
+	})
+	timer, timerEnd, timerLength = t, time.Now().Add(length), length
+}
+
+func cancelTimer() {
+	timersMu.Lock()
+	defer timersMu.Unlock()
+	if timer != nil {
+		timer.Stop()
+		timer = nil
+	}
+}
+
+// timerLeft returns the time left on the timer and its length, or false
+// without a timer.
+func timerLeft(now time.Time) (left, length time.Duration, ok bool) {
+	timersMu.Lock()
+	defer timersMu.Unlock()
+	if timer == nil {
+		return 0, 0, false
+	}
+	return max(timerEnd.Sub(now), 0), timerLength, true
+}
+
+// parseTimer reads the length of a timer, such as 90s or 1h30m. A plain
+// number is minutes.
+func parseTimer(text string) (time.Duration, error) {
+	if minutes, err := strconv.Atoi(text); err == nil {
+		text = fmt.Sprintf("%dm", minutes)
+	}
+	d, err := time.ParseDuration(text)
+	if err != nil || d <= 0 {
+		return 0, xerrors.Errorf("bad length %q", text)
+	}
+	return d, nil
+}
+
+// formatDuration shows d as h:mm:ss, rounded down to the second.
+func formatDuration(d time.Duration) string {
+	s := int(d / time.Second)
+	return fmt.Sprintf("%d:%02d:%02d", s/3600, s/60%60, s%60)
+}
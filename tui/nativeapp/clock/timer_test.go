@@ -0,0 +1,49 @@
+package clock
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseTimer(t *testing.T) {
+	for _, test := range []struct {
+		text string
+		want time.Duration
+	}{
+		{"5", 5 * time.Minute},
+		{"90s", 90 * time.Second},
+		{"1h30m", 90 * time.Minute},
+		{"0", 0},
+		{"-5", 0},
+		{"-1m", 0},
+		{"", 0},
+		{"soon", 0},
+	} {
+		got, err := parseTimer(test.text)
+		if test.want == 0 {
+			if err == nil {
+				t.Errorf("%q: got %v, want an error", test.text, got)
+			}
+			continue
+		}
+		if err != nil || got != test.want {
+			t.Errorf("%q: got %v, %v, want %v", test.text, got, err, test.want)
+		}
+	}
+}
+
+func TestFormatDuration(t *testing.T) {
+	for _, test := range []struct {
+		d    time.Duration
+		want string
+	}{
+		{0, "0:00:00"},
+		{59*time.Second + 999*time.Millisecond, "0:00:59"},
+		{time.Hour + time.Minute + time.Second, "1:01:01"},
+		{25 * time.Hour, "25:00:00"},
+	} {
+		if got := formatDuration(test.d); got != test.want {
+			t.Errorf("%v: got %q, want %q", test.d, got, test.want)
+		}
+	}
+}
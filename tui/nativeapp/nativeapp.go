@@ -0,0 +1,120 @@
+// Package nativeapp is the API of apps running inside TuiTop, drawing their
+// windows with cview widgets rather than through a terminal.
+//
+// Apps register themselves, usually from an init function, and the desktop
+// starts one in a new window each time the app is launched:
+//
+//	func init() {
+//		nativeapp.Register(clockApp{})
+//	}
+package nativeapp
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/snadrus/tuitop/deps/cview"
+)
+
+// Info describes an app to the desktop.
+type Info struct {
+	// Name is what the app is launched as, such as "clock".
+	Name string
+	// Title is shown in menus and as the title of its windows.
+	Title string
+	// Width and Height are the size of new windows, including the border.
+	Width, Height int
+}
+
+// App is an app drawn with cview widgets in TuiTop's process.
+type App interface {
+	Info() Info
+	// Start builds a new instance of the app in w, returning the root of its
+	// content. It is called on the UI goroutine. Changes made from other
+	// goroutines must be followed by Desktop.Redraw.
+	Start(w Window, d Desktop) (cview.Primitive, error)
+}
+
+// Window is the window of an app instance.
+type Window interface {
+	SetTitle(title string)
+	// SetRoot replaces the content of the window.
+	SetRoot(root cview.Primitive)
+	// Resize changes the size of the window, including its border.
+	Resize(width, height int)
+	Size() (width, height int)
+	// Close removes the window. The close handler is called.
+	Close()
+	// SetCloseHandler sets a function called once the window closed, to
+	// stop what the app runs in the background.
+	SetCloseHandler(f func())
+}
+
+// Desktop gives apps access to the desktop's services.
+type Desktop interface {
+	// Notify shows a notification.
+	Notify(title, body string)
+	// Copy puts text on the clipboard.
+	Copy(text string) error
+	// Launch starts the app named name, or else runs the program name with
+	// args in a terminal window.
+	Launch(name string, args ...string) error
+	// Redraw draws the desktop again soon. It is safe to call from any
+	// goroutine.
+	Redraw()
+	// Terminals lists the windows running local programs in a terminal.
+	Terminals() []Terminal
+}
+
+// Terminal is a window of the desktop running a local program.
+type Terminal interface {
+	Title() string
+	// Pid is the process the window runs, usually a shell, or 0 before it
+	// started.
+	Pid() int
+	// ForegroundPgrp is the process group in the foreground of the
+	// window's pty, or 0 if it is unknown.
+	ForegroundPgrp() int
+	// Raise brings the window to the front and focuses it. It is called
+	// on the UI goroutine.
+	Raise()
+}
+
+var (
+	mu   sync.Mutex
+	apps = map[string]App{}
+)
+
+// Register makes an app available to the desktop under its name. It panics if
+// the name is taken, as that is a programming error.
+func Register(app App) {
+	mu.Lock()
+	defer mu.Unlock()
+	name := app.Info().Name
+	if _, ok := apps[name]; ok {
+		panic("nativeapp: app " + name + " registered twice")
+	}
+	apps[name] = app
+}
+
+// Lookup returns the app registered as name.
+func Lookup(name string) (App, bool) {
+	mu.Lock()
+	defer mu.Unlock()
+	app, ok := apps[name]
+	return app, ok
+}
+
+// Apps returns the registered apps, sorted by name.
+func Apps() []App {
+	mu.Lock()
+	defer mu.Unlock()
+	list := make([]App, 0, len(apps))
+	for _, app := range apps {
+		list = append(list, app)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Info().Name < list[j].Info().Name
+	})
+	return list
+}
@@ -0,0 +1,53 @@
+package nativeapp
+
+import (
+	"testing"
+
+	"github.com/snadrus/tuitop/deps/cview"
+)
+
+type testApp string
+
+func (a testApp) Info() Info {
+	return Info{Name: string(a), Title: "Test " + string(a)}
+}
+
+func (a testApp) Start(w Window, d Desktop) (cview.Primitive, error) {
+	return nil, nil
+}
+
+func TestRegistry(t *testing.T) {
+	for _, name := range []string{"zeta", "alpha", "mid"} {
+		Register(testApp(name))
+	}
+	for _, test := range []struct {
+		name  string
+		found bool
+	}{
+		{"alpha", true},
+		{"mid", true},
+		{"zeta", true},
+		{"Alpha", false},
+		{"", false},
+	} {
+		app, ok := Lookup(test.name)
+		if ok != test.found || ok && app.Info().Name != test.name {
+			t.Errorf("Lookup(%q) = %v, %v", test.name, app, ok)
+		}
+	}
+
+	names := ""
+	for _, app := range Apps() {
+		names += app.Info().Name + " "
+	}
+	if names != "alpha mid zeta " {
+		t.Errorf("got apps %s", names)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("registering a name twice didn't panic")
+		}
+	}()
+	Register(testApp("mid"))
+}
@@ -0,0 +1,350 @@
+// Package tasks is the Tasks app, a process manager for the programs running
+// in the desktop's windows.
+package tasks
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/snadrus/tuitop/deps/cview"
+	"github.com/snadrus/tuitop/tui/nativeapp"
+	"github.com/snadrus/tuitop/tui/procs"
+	"github.com/snadrus/tuitop/tui/sysstat"
+)
+
+func init() {
+	nativeapp.Register(tasks{})
+}
+
+// refreshInterval is how often the processes are read again.
+const refreshInterval = 2 * time.Second
+
+// help is shown at the bottom of the window.
+const help = "[gray]Enter go to window  s signal  S signal group  +/- nice  q close[-]"
+
+type tasks struct{}
+
+func (tasks) Info() nativeapp.Info {
+	return nativeapp.Info{
+		Name:   "tasks",
+		Title:  "📋 Tasks",
+		Width:  84,
+		Height: 22,
+	}
+}
+
+// row is a line of the table: a window, or a process of its tree.
+type row struct {
+	term nativeapp.Terminal
+	// proc is the process, with a zero PID for the window's line
+	proc procs.Process
+	// fg is the foreground process group of the window
+	fg int
+	// depth is how deep proc is in the tree of the window
+	depth int
+}
+
+// view is a Tasks window. The ticker refreshes it while keys act on the
+// selected row, hence the lock.
+type view struct {
+	d      nativeapp.Desktop
+	table  *cview.Table
+	status *cview.TextView
+
+	mu   sync.Mutex
+	rows []row
+	// prev are the processes read last, at
+	prev map[int]procs.Process
+	at   time.Time
+	// signaling is whether a signal is being chosen, for a process group
+	// if signalGroup
+	signaling   bool
+	signalGroup bool
+}
+
+// Start lists the process trees of the terminal windows, refreshed every
+// two seconds. Escape or q closes the window.
+func (tasks) Start(w nativeapp.Window, d nativeapp.Desktop) (cview.Primitive, error) {
+	v := &view{
+		d:      d,
+		table:  cview.NewTable(),
+		status: cview.NewTextView(),
+		prev:   map[int]procs.Process{},
+	}
+	v.table.SetSelectable(true, false)
+	v.table.SetFixed(1, 0)
+	v.table.SetSelectedFunc(func(int, int) {
+		if r, ok := v.selected(); ok {
+			r.term.Raise()
+		}
+	})
+	v.status.SetDynamicColors(true)
+	v.status.SetText(help)
+	v.refresh()
+
+	ticker := time.NewTicker(refreshInterval)
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-ticker.C:
+				v.refresh()
+				d.Redraw()
+			case <-done:
+				return
+			}
+		}
+	}()
+	w.SetCloseHandler(func() {
+		ticker.Stop()
+		close(done)
+	})
+
+	root := cview.NewFlex()
+	root.SetDirection(cview.FlexRow)
+	root.AddItem(v.table, 0, 1, true)
+	root.AddItem(v.status, 1, 0, false)
+	root.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if v.choosingSignal(event) {
+			return nil
+		}
+		if event.Key() == tcell.KeyEscape || (event.Key() == tcell.KeyRune && event.Rune() == 'q') {
+			w.Close()
+			return nil
+		}
+		if event.Key() != tcell.KeyRune {
+			return event
+		}
+		switch event.Rune() {
+		case 's', 'S':
+			v.askSignal(event.Rune() == 'S')
+		case '+':
+			v.renice(1)
+		case '-':
+			v.renice(-1)
+		default:
+			return event
+		}
+		return nil
+	})
+	return root, nil
+}
+
+// selected returns the selected row.
+func (v *view) selected() (row, bool) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	i, _ := v.table.GetSelection()
+	// The first row is the header
+	if i < 1 || i > len(v.rows) {
+		return row{}, false
+	}
+	return v.rows[i-1], true
+}
+
+// target returns the process the selected row acts on, a window's line
+// acting on the program it runs.
+func (v *view) target() (procs.Process, bool) {
+	r, ok := v.selected()
+	if !ok {
+		return procs.Process{}, false
+	}
+	if r.proc.PID != 0 {
+		return r.proc, true
+	}
+	p, err := procs.Read(r.term.Pid())
+	return p, err == nil
+}
+
+func (v *view) askSignal(group bool) {
+	p, ok := v.target()
+	if !ok {
+		return
+	}
+	r, _ := v.selected()
+	to := fmt.Sprintf("%d %s", p.PID, p.Name)
+	if group {
+		to = fmt.Sprintf("group %d", v.groupOf(r, p))
+	}
+	choices := []string{}
+	for i, name := range procs.Signals {
+		choices = append(choices, fmt.Sprintf("[::b]%d[::-] %s", (i+1)%10, name))
+	}
+	v.mu.Lock()
+	v.signaling, v.signalGroup = true, group
+	v.mu.Unlock()
+	v.status.SetText(fmt.Sprintf("Send to %s: %s", cview.Escape(to), strings.Join(choices, " ")))
+}
+
+// groupOf returns the process group signaled for r: the foreground group
+// of a window's line, like the keyboard's signals do, or the group of p.
+func (v *view) groupOf(r row, p procs.Process) int {
+	if r.proc.PID == 0 && r.fg != 0 {
+		return r.fg
+	}
+	return p.PGID
+}
+
+// choosingSignal takes the keys choosing a signal to send, returning
+// whether it used the key.
+func (v *view) choosingSignal(event *tcell.EventKey) bool {
+	v.mu.Lock()
+	signaling, group := v.signaling, v.signalGroup
+	v.signaling = false
+	v.mu.Unlock()
+	if !signaling {
+		return false
+	}
+	v.status.SetText(help)
+	if event.Key() != tcell.KeyRune || event.Rune() < '0' || event.Rune() > '9' {
+		return true
+	}
+	name := procs.Signals[(int(event.Rune()-'0')+9)%10]
+	p, ok := v.target()
+	if !ok {
+		return true
+	}
+	var err error
+	if group {
+		r, _ := v.selected()
+		pgid := v.groupOf(r, p)
+		err = procs.SignalGroup(pgid, name)
+		v.report(err, fmt.Sprintf("Sent SIG%s to group %d", name, pgid))
+	} else {
+		err = procs.Signal(p.PID, name)
+		v.report(err, fmt.Sprintf("Sent SIG%s to %d %s", name, p.PID, p.Name))
+	}
+	if err == nil {
+		time.AfterFunc(refreshInterval/4, func() {
+			v.refresh()
+			v.d.Redraw()
+		})
+	}
+	return true
+}
+
+func (v *view) renice(by int) {
+	p, ok := v.target()
+	if !ok {
+		return
+	}
+	nice := min(max(p.Nice+by, -20), 19)
+	err := procs.Renice(p.PID, nice)
+	v.report(err, fmt.Sprintf("Niced %d %s to %d", p.PID, p.Name, nice))
+	if err == nil {
+		v.refresh()
+	}
+}
+
+// report shows the outcome of an action in the status line.
+func (v *view) report(err error, done string) {
+	if err != nil {
+		v.status.SetText("[red]" + cview.Escape(err.Error()) + "[-]")
+		return
+	}
+	v.status.SetText("[green]" + cview.Escape(done) + "[-]")
+}
+
+// refresh reads the processes again and shows the tree of each window,
+// keeping the selection.
+func (v *view) refresh() {
+	all, err := procs.List()
+	if err != nil {
+		v.report(err, "")
+		return
+	}
+	now := time.Now()
+	tree := procs.NewTree(all)
+	rows := []row{}
+	for _, term := range v.d.Terminals() {
+		r := row{term: term, fg: term.ForegroundPgrp()}
+		rows = append(rows, r)
+		tree.Walk(term.Pid(), func(p procs.Process, depth int) {
+			r.proc, r.depth = p, depth
+			rows = append(rows, r)
+		})
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	selected := row{}
+	if i, _ := v.table.GetSelection(); i >= 1 && i <= len(v.rows) {
+		selected = v.rows[i-1]
+	}
+	elapsed := now.Sub(v.at)
+	v.table.Clear()
+	for c, title := range []string{"PID", "PGID", "CPU%", "RSS", "S", "NI", "COMMAND"} {
+		cell := cview.NewTableCell(title)
+		cell.SetTextColor(tcell.ColorYellow)
+		cell.SetSelectable(false)
+		if c < 4 {
+			cell.SetAlign(cview.AlignRight)
+		}
+		v.table.SetCell(0, c, cell)
+	}
+	selectRow := 1
+	for i, r := range rows {
+		if r.term == selected.term && r.proc.PID == selected.proc.PID {
+			selectRow = i + 1
+		}
+		if r.proc.PID == 0 {
+			title := "▣ " + r.term.Title()
+			if r.fg != 0 {
+				title += fmt.Sprintf("  (foreground group %d)", r.fg)
+			}
+			cell := cview.NewTableCell(cview.Escape(title))
+			cell.SetTextColor(tcell.ColorAqua)
+			v.table.SetCell(i+1, 6, cell)
+			for c := 0; c < 6; c++ {
+				v.table.SetCell(i+1, c, cview.NewTableCell(""))
+			}
+			continue
+		}
+		p := r.proc
+		cpu := ""
+		if prev, ok := v.prev[p.PID]; ok {
+			cpu = fmt.Sprintf("%.1f", procs.CPUPercent(prev, p, elapsed))
+		}
+		state := p.State
+		color := tcell.ColorWhite
+		// Marked like ps does
+		if p.PGID == r.fg {
+			state += "+"
+			color = tcell.ColorYellow
+		}
+		command := strings.Repeat("  ", r.depth) + p.Command
+		if len(command) > 80 {
+			command = command[:80]
+		}
+		for c, text := range []string{
+			fmt.Sprint(p.PID), fmt.Sprint(p.PGID), cpu, sysstat.FormatBytes(p.RSS),
+			state, fmt.Sprint(p.Nice), cview.Escape(command),
+		} {
+			cell := cview.NewTableCell(text)
+			cell.SetTextColor(color)
+			if c < 4 {
+				cell.SetAlign(cview.AlignRight)
+			}
+			if c == 6 {
+				cell.SetExpansion(1)
+			}
+			v.table.SetCell(i+1, c, cell)
+		}
+	}
+	if len(rows) == 0 {
+		cell := cview.NewTableCell("No terminal windows")
+		cell.SetSelectable(false)
+		v.table.SetCell(1, 6, cell)
+	}
+	v.table.Select(selectRow, 0)
+
+	v.rows = rows
+	v.prev = map[int]procs.Process{}
+	for _, p := range all {
+		v.prev[p.PID] = p
+	}
+	v.at = now
+}
@@ -0,0 +1,122 @@
+// Package notify keeps the desktop's notifications.
+package notify
+
+import (
+	"sync"
+	"time"
+)
+
+// MaxHistory is the number of notifications kept in the history.
+const MaxHistory = 100
+
+type Notification struct {
+	Title string
+	Body  string
+	When  time.Time
+}
+
+// Center receives notifications, keeps their history and passes them on to
+// be shown, unless do-not-disturb is on.
+type Center struct {
+	mu      sync.Mutex
+	history []Notification
+	unread  int
+	dnd     bool
+	show    func(n Notification)
+	changed func()
+}
+
+func NewCenter() *Center {
+	return &Center{}
+}
+
+// SetShowHandler sets the function showing a new notification to the user.
+func (c *Center) SetShowHandler(f func(n Notification)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.show = f
+}
+
+// SetChangedHandler sets a function called when the history, unread count or
+// do-not-disturb changes.
+func (c *Center) SetChangedHandler(f func()) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.changed = f
+}
+
+// Notify adds a notification to the history and shows it.
+func (c *Center) Notify(title, body string) {
+	n := Notification{Title: title, Body: body, When: time.Now()}
+	c.mu.Lock()
+	c.history = append(c.history, n)
+	if len(c.history) > MaxHistory {
+		c.history = append([]Notification(nil), c.history[len(c.history)-MaxHistory:]...)
+	}
+	c.unread += 1
+	show, changed := c.show, c.changed
+	if c.dnd {
+		show = nil
+	}
+	c.mu.Unlock()
+	if show != nil {
+		show(n)
+	}
+	if changed != nil {
+		changed()
+	}
+}
+
+// History returns the notifications, newest first, and marks them read.
+func (c *Center) History() []Notification {
+	c.mu.Lock()
+	history := make([]Notification, len(c.history))
+	for i, n := range c.history {
+		history[len(history)-1-i] = n
+	}
+	c.unread = 0
+	changed := c.changed
+	c.mu.Unlock()
+	if changed != nil {
+		changed()
+	}
+	return history
+}
+
+// Unread returns the number of notifications since History was last called.
+func (c *Center) Unread() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.unread
+}
+
+// Clear removes all notifications.
+func (c *Center) Clear() {
+	c.mu.Lock()
+	c.history = nil
+	c.unread = 0
+	changed := c.changed
+	c.mu.Unlock()
+	if changed != nil {
+		changed()
+	}
+}
+
+// SetDND turns do-not-disturb on or off. Notifications are still kept in the
+// history while it is on, but not shown.
+func (c *Center) SetDND(dnd bool) {
+	c.mu.Lock()
+	c.dnd = dnd
+	changed := c.changed
+	c.mu.Unlock()
+	if changed != nil {
+		changed()
+	}
+}
+
+// DND reports whether do-not-disturb is on.
+func (c *Center) DND() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.dnd
+}
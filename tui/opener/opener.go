@@ -0,0 +1,82 @@
+// Package opener opens links clicked in windows, the way the user configured.
+package opener
+
+import (
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"github.com/snadrus/tuitop/apps"
+	"github.com/snadrus/tuitop/deps/cview"
+	"github.com/snadrus/tuitop/tui/clipboard"
+	"github.com/snadrus/tuitop/tui/config"
+	"github.com/snadrus/tuitop/tui/installer"
+	"github.com/snadrus/tuitop/tui/tuiwindow"
+	"golang.org/x/xerrors"
+)
+
+type Opener struct {
+	app          *cview.Application
+	cfg          config.Links
+	inst         *installer.Installer
+	createWindow tuiwindow.CreateWindow
+}
+
+func New(app *cview.Application, cfg config.Links, inst *installer.Installer, createWindow tuiwindow.CreateWindow) *Opener {
+	return &Opener{app: app, cfg: cfg, inst: inst, createWindow: createWindow}
+}
+
+// Open opens url with the configured handler. Apps from the catalogue only
+// open web links; other links go to the system's handler. It may install an
+// app or run a command, so isn't called on the UI goroutine.
+func (o *Opener) Open(url string) error {
+	switch {
+	case o.cfg.Open == "clipboard":
+		return clipboard.Copy(url)
+	case strings.HasPrefix(o.cfg.Open, "app:") && isWeb(url):
+		return o.openApp(strings.TrimPrefix(o.cfg.Open, "app:"), url)
+	default:
+		return system(url)
+	}
+}
+
+func isWeb(url string) bool {
+	return strings.HasPrefix(url, "http://") || strings.HasPrefix(url, "https://")
+}
+
+// openApp opens url in a window running an app from the catalogue, installing
+// the app if needed. The URL is added to the app's command line.
+func (o *Opener) openApp(name, url string) error {
+	def, err := apps.Open(name)
+	if err != nil {
+		return xerrors.Errorf("no app %s: %w", name, err)
+	}
+	defer def.Close()
+	y, err := installer.ReadYaml(def)
+	if err != nil {
+		return err
+	}
+	p, err := o.inst.EnsureYaml(y)
+	if err != nil {
+		return err
+	}
+	args := append(strings.Fields(y.CLI)[1:], url)
+	o.app.QueueUpdateDraw(func() {
+		o.createWindow(p, tuiwindow.WithArgs(args...))
+	})
+	return nil
+}
+
+// system opens url with the desktop's handler.
+func system(url string) error {
+	tool := "xdg-open"
+	if runtime.GOOS == "darwin" {
+		tool = "open"
+	}
+	cmd := exec.Command(tool, url)
+	if err := cmd.Start(); err != nil {
+		return xerrors.Errorf("cannot run %s: %w", tool, err)
+	}
+	go cmd.Wait()
+	return nil
+}
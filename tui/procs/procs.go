@@ -0,0 +1,217 @@
+// Package procs reads processes from /proc, to show the process trees of
+// windows, and sends them signals and renices them.
+package procs
+
+import (
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/sys/unix"
+	"golang.org/x/xerrors"
+)
+
+// clockTick is the unit of CPU times in /proc, USER_HZ, which is 100 on all
+// Linux architectures.
+const clockTick = time.Second / 100
+
+// Process is a process of the system.
+type Process struct {
+	PID, PPID int
+	// PGID is the process group and SID the session
+	PGID, SID int
+	// TTY is the device number of the controlling terminal, 0 without one
+	TTY int
+	// Name is the name of the executable, at most 15 bytes
+	Name string
+	// Command is the command line, or Name in brackets for kernel threads
+	Command string
+	// State is R running, S sleeping, D waiting on disk, Z zombie, T
+	// stopped, t traced or I idle
+	State string
+	Nice  int
+	// RSS is the memory resident in RAM, in bytes
+	RSS uint64
+	// CPUTime is the time spent on CPUs, by the process only
+	CPUTime time.Duration
+}
+
+// List reads all processes, sorted by PID.
+func List() ([]Process, error) {
+	return list("/proc")
+}
+
+func list(proc string) ([]Process, error) {
+	entries, err := os.ReadDir(proc)
+	if err != nil {
+		return nil, xerrors.Errorf("cannot list processes: %w", err)
+	}
+	found := []Process{}
+	for _, e := range entries {
+		pid, err := strconv.Atoi(e.Name())
+		if err != nil {
+			continue
+		}
+		// Processes exit while being listed
+		if p, err := read(proc, pid); err == nil {
+			found = append(found, p)
+		}
+	}
+	sort.Slice(found, func(a, b int) bool { return found[a].PID < found[b].PID })
+	return found, nil
+}
+
+// Read reads the process pid.
+func Read(pid int) (Process, error) {
+	return read("/proc", pid)
+}
+
+func read(proc string, pid int) (Process, error) {
+	dir := path.Join(proc, strconv.Itoa(pid))
+	stat, err := os.ReadFile(path.Join(dir, "stat"))
+	if err != nil {
+		return Process{}, xerrors.Errorf("cannot read process %d: %w", pid, err)
+	}
+	p, err := parseStat(string(stat))
+	if err != nil {
+		return Process{}, xerrors.Errorf("process %d: %w", pid, err)
+	}
+	cmdline, _ := os.ReadFile(path.Join(dir, "cmdline"))
+	p.Command = strings.TrimSpace(strings.ReplaceAll(string(cmdline), "\x00", " "))
+	if p.Command == "" {
+		p.Command = "[" + p.Name + "]"
+	}
+	return p, nil
+}
+
+// parseStat reads the contents of /proc/PID/stat.
+func parseStat(stat string) (Process, error) {
+	// The name is in parentheses and may hold any character
+	open, end := strings.IndexByte(stat, '('), strings.LastIndexByte(stat, ')')
+	if open < 0 || end < open {
+		return Process{}, xerrors.New("cannot parse stat")
+	}
+	fields := strings.Fields(stat[end+1:])
+	if len(fields) < 22 {
+		return Process{}, xerrors.New("stat too short")
+	}
+	num := func(i int) int {
+		n, _ := strconv.Atoi(fields[i])
+		return n
+	}
+	p := Process{Name: stat[open+1 : end], State: fields[0]}
+	p.PID, _ = strconv.Atoi(strings.TrimSpace(stat[:open]))
+	p.PPID, p.PGID, p.SID, p.TTY = num(1), num(2), num(3), num(4)
+	p.CPUTime = time.Duration(num(11)+num(12)) * clockTick
+	p.Nice = num(16)
+	p.RSS = uint64(num(21)) * uint64(os.Getpagesize())
+	return p, nil
+}
+
+// CPUPercent returns the share of a CPU the process used between two reads,
+// elapsed apart.
+func CPUPercent(prev, now Process, elapsed time.Duration) float64 {
+	if elapsed <= 0 || now.CPUTime < prev.CPUTime {
+		return 0
+	}
+	return 100 * float64(now.CPUTime-prev.CPUTime) / float64(elapsed)
+}
+
+// Tree is the processes of the system by parent.
+type Tree struct {
+	procs    map[int]Process
+	children map[int][]int
+}
+
+// NewTree arranges procs, sorted by PID, in a tree.
+func NewTree(procs []Process) *Tree {
+	t := &Tree{procs: map[int]Process{}, children: map[int][]int{}}
+	for _, p := range procs {
+		t.procs[p.PID] = p
+		t.children[p.PPID] = append(t.children[p.PPID], p.PID)
+	}
+	return t
+}
+
+// Get returns the process pid.
+func (t *Tree) Get(pid int) (Process, bool) {
+	p, ok := t.procs[pid]
+	return p, ok
+}
+
+// Children returns the processes started by pid.
+func (t *Tree) Children(pid int) []Process {
+	found := []Process{}
+	for _, child := range t.children[pid] {
+		found = append(found, t.procs[child])
+	}
+	return found
+}
+
+// Walk calls f with pid and its descendants, each before its children, with
+// its depth under pid.
+func (t *Tree) Walk(pid int, f func(p Process, depth int)) {
+	p, ok := t.procs[pid]
+	if !ok {
+		return
+	}
+	var walk func(p Process, depth int)
+	walk = func(p Process, depth int) {
+		f(p, depth)
+		for _, child := range t.children[p.PID] {
+			walk(t.procs[child], depth+1)
+		}
+	}
+	walk(p, 0)
+}
+
+// Signals are the names of the signals offered to send, the most used first.
+var Signals = []string{"TERM", "INT", "HUP", "KILL", "STOP", "CONT", "TSTP", "QUIT", "USR1", "USR2"}
+
+func signalNum(name string) (unix.Signal, error) {
+	sig := unix.SignalNum("SIG" + name)
+	if sig == 0 {
+		return 0, xerrors.Errorf("unknown signal %s", name)
+	}
+	return sig, nil
+}
+
+// Signal sends the signal named name, such as "TERM", to pid.
+func Signal(pid int, name string) error {
+	sig, err := signalNum(name)
+	if err != nil {
+		return err
+	}
+	if err := unix.Kill(pid, sig); err != nil {
+		return xerrors.Errorf("cannot send SIG%s to %d: %w", name, pid, err)
+	}
+	return nil
+}
+
+// SignalGroup sends the signal named name to all the processes of group
+// pgid.
+func SignalGroup(pgid int, name string) error {
+	sig, err := signalNum(name)
+	if err != nil {
+		return err
+	}
+	if pgid <= 1 {
+		return xerrors.Errorf("refusing to signal process group %d", pgid)
+	}
+	if err := unix.Kill(-pgid, sig); err != nil {
+		return xerrors.Errorf("cannot send SIG%s to group %d: %w", name, pgid, err)
+	}
+	return nil
+}
+
+// Renice sets the nice value of pid, from -20 for the most CPU to 19 for the
+// least. Only root can lower it.
+func Renice(pid, nice int) error {
+	if err := unix.Setpriority(unix.PRIO_PROCESS, pid, nice); err != nil {
+		return xerrors.Errorf("cannot renice %d to %d: %w", pid, nice, err)
+	}
+	return nil
+}
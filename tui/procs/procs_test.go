@@ -0,0 +1,134 @@
+package procs
+
+import (
+	"os"
+	"os/exec"
+	"path"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestParseStat(t *testing.T) {
+	stat := "4242 (tmux: server) (x) S 1 4242 4242 34817 4300 4194560 100 0 0 0 150 50 0 0 20 5 1 0 12345 10000000 300 18446744073709551615\n"
+	p, err := parseStat(stat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Process{
+		PID: 4242, PPID: 1, PGID: 4242, SID: 4242, TTY: 34817,
+		Name: "tmux: server) (x", State: "S", Nice: 5,
+		RSS:     300 * uint64(os.Getpagesize()),
+		CPUTime: 2 * time.Second,
+	}
+	if p != want {
+		t.Fatalf("got %+v, want %+v", p, want)
+	}
+	if _, err := parseStat("4242 (sh) S 1"); err == nil {
+		t.Fatal("parsed a short stat")
+	}
+}
+
+func TestList(t *testing.T) {
+	proc := t.TempDir()
+	for pid, files := range map[string]map[string]string{
+		"1":  {"stat": "1 (init) S 0 1 1 0 -1 0 0 0 0 0 1 1 0 0 20 0 1 0 1 1 1 1", "cmdline": "/sbin/init\x00splash\x00"},
+		"2":  {"stat": "2 (kthreadd) S 0 0 0 0 -1 0 0 0 0 0 0 0 0 0 20 0 1 0 1 0 0 1", "cmdline": ""},
+		"10": {"stat": "10 (bash) S 1 10 10 34816 10 0 0 0 0 0 0 0 0 0 20 0 1 0 1 1 1 1", "cmdline": "bash\x00"},
+		"11": {"stat": "11 (vim) R 10 11 10 34816 11 0 0 0 0 0 0 0 0 0 20 0 1 0 1 1 1 1", "cmdline": "vim\x00a b\x00"},
+		"12": {"stat": "12 (less) S 10 11 10 34816 11 0 0 0 0 0 0 0 0 0 20 0 1 0 1 1 1 1", "cmdline": "less\x00"},
+	} {
+		for name, content := range files {
+			if err := os.MkdirAll(path.Join(proc, pid), 0o755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(path.Join(proc, pid, name), []byte(content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	// Not processes
+	os.WriteFile(path.Join(proc, "meminfo"), nil, 0o644)
+	os.Mkdir(path.Join(proc, "self"), 0o755)
+
+	all, err := list(proc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 5 || all[0].PID != 1 || all[4].PID != 12 {
+		t.Fatalf("got %+v", all)
+	}
+	if all[0].Command != "/sbin/init splash" || all[1].Command != "[kthreadd]" || all[3].Command != "vim a b" {
+		t.Errorf("got commands %q, %q and %q", all[0].Command, all[1].Command, all[3].Command)
+	}
+
+	tree := NewTree(all)
+	if children := tree.Children(10); len(children) != 2 || children[0].Name != "vim" || children[1].Name != "less" {
+		t.Errorf("got children %+v", children)
+	}
+	walked := []string{}
+	tree.Walk(1, func(p Process, depth int) {
+		walked = append(walked, string(rune('0'+depth))+p.Name)
+	})
+	if got := len(walked); got != 4 || walked[0] != "0init" || walked[1] != "1bash" || walked[2] != "2vim" || walked[3] != "2less" {
+		t.Errorf("walked %v", walked)
+	}
+	if _, ok := tree.Get(99); ok {
+		t.Error("got a process which doesn't exist")
+	}
+}
+
+func TestCPUPercent(t *testing.T) {
+	prev := Process{CPUTime: time.Second}
+	now := Process{CPUTime: 1500 * time.Millisecond}
+	if got := CPUPercent(prev, now, 2*time.Second); got != 25 {
+		t.Errorf("got %v%%, want 25", got)
+	}
+	if got := CPUPercent(now, prev, time.Second); got != 0 {
+		t.Errorf("got %v%% going back in time", got)
+	}
+}
+
+// start runs sleep in a process group of its own.
+func start(t *testing.T) *exec.Cmd {
+	t.Helper()
+	cmd := exec.Command("sleep", "10")
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		cmd.Process.Kill()
+		cmd.Wait()
+	})
+	return cmd
+}
+
+func TestSignal(t *testing.T) {
+	cmd := start(t)
+	p, err := Read(cmd.Process.Pid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.PGID != cmd.Process.Pid || p.PPID != os.Getpid() || p.Name != "sleep" {
+		t.Fatalf("got %+v", p)
+	}
+
+	if err := Renice(p.PID, p.Nice+1); err != nil {
+		t.Fatal(err)
+	}
+	if reniced, _ := Read(p.PID); reniced.Nice != p.Nice+1 {
+		t.Errorf("nice is %d, want %d", reniced.Nice, p.Nice+1)
+	}
+
+	if err := Signal(p.PID, "BOGUS"); err == nil {
+		t.Error("sent an unknown signal")
+	}
+	if err := SignalGroup(p.PGID, "TERM"); err != nil {
+		t.Fatal(err)
+	}
+	err = cmd.Wait()
+	if status, ok := cmd.ProcessState.Sys().(syscall.WaitStatus); !ok || status.Signal() != syscall.SIGTERM {
+		t.Fatalf("sleep ended with %v", err)
+	}
+}
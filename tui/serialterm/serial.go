@@ -0,0 +1,362 @@
+// Package serialterm connects terminal windows to serial devices, such as
+// USB serial adapters and console ports.
+package serialterm
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+
+	"golang.org/x/xerrors"
+)
+
+// Bauds are the usual baud rates, offered in the picker.
+var Bauds = []int{1200, 2400, 4800, 9600, 19200, 38400, 57600, 115200, 230400, 460800, 921600}
+
+type Parity string
+
+const (
+	ParityNone Parity = "none"
+	ParityEven Parity = "even"
+	ParityOdd  Parity = "odd"
+)
+
+type Flow string
+
+const (
+	FlowNone Flow = "none"
+	// FlowHardware is RTS/CTS flow control
+	FlowHardware Flow = "rts/cts"
+	// FlowSoftware is XON/XOFF flow control
+	FlowSoftware Flow = "xon/xoff"
+)
+
+// Newline is a line ending.
+type Newline string
+
+const (
+	NewlineCR   Newline = "cr"
+	NewlineLF   Newline = "lf"
+	NewlineCRLF Newline = "crlf"
+)
+
+// Options is how to set up a serial device. Fields left empty have their
+// defaults: 115200 baud, 8 data bits, no parity, 1 stop bit and no flow
+// control.
+type Options struct {
+	Device   string
+	Baud     int
+	DataBits int
+	Parity   Parity
+	StopBits int
+	Flow     Flow
+	// LocalEcho shows what is sent, for devices which don't echo.
+	LocalEcho bool
+	// Send is what the Enter key sends, CR by default.
+	Send Newline
+	// Receive is how the device ends lines. Lines ending with a lone CR or
+	// LF are shown as if they ended with CRLF. The default, CRLF, leaves
+	// the output as is.
+	Receive Newline
+}
+
+func (o *Options) setDefaults() {
+	if o.Baud == 0 {
+		o.Baud = 115200
+	}
+	if o.DataBits == 0 {
+		o.DataBits = 8
+	}
+	if o.Parity == "" {
+		o.Parity = ParityNone
+	}
+	if o.StopBits == 0 {
+		o.StopBits = 1
+	}
+	if o.Flow == "" {
+		o.Flow = FlowNone
+	}
+	if o.Send == "" {
+		o.Send = NewlineCR
+	}
+	if o.Receive == "" {
+		o.Receive = NewlineCRLF
+	}
+}
+
+// writeQueue is how many writes may wait for a device holding off with flow
+// control before more fail.
+const writeQueue = 64
+
+// ErrBusy is returned when writing to a device which is holding off.
+var ErrBusy = errors.New("the device is not accepting data")
+
+// Port is an open serial device. Reading returns what the device sent, and
+// what was written when local echo is on, with line endings translated.
+type Port struct {
+	f   *os.File
+	out *stream
+	// writes are sent to the device by writer, so that writing never waits
+	// on flow control
+	writes chan []byte
+	closed chan struct{}
+	once   sync.Once
+
+	mu        sync.Mutex
+	localEcho bool
+	send      Newline
+	receive   Newline
+	// err is the error of the last write to the device
+	err error
+}
+
+// Open opens and sets up the serial device.
+func Open(opts Options) (*Port, error) {
+	opts.setDefaults()
+	f, err := openDevice(opts)
+	if err != nil {
+		return nil, err
+	}
+	p := &Port{
+		f:         f,
+		out:       newStream(),
+		writes:    make(chan []byte, writeQueue),
+		closed:    make(chan struct{}),
+		localEcho: opts.LocalEcho,
+		send:      opts.Send,
+		receive:   opts.Receive,
+	}
+	go p.run()
+	go p.writer()
+	return p, nil
+}
+
+// writer sends the queued writes to the device until it is closed.
+func (p *Port) writer() {
+	for {
+		select {
+		case data := <-p.writes:
+			if _, err := p.f.Write(data); err != nil {
+				p.mu.Lock()
+				p.err = err
+				p.mu.Unlock()
+			}
+		case <-p.closed:
+			return
+		}
+	}
+}
+
+// run passes on what the device sends until it is closed.
+func (p *Port) run() {
+	buf := make([]byte, 4096)
+	// lastCR is set when the last byte received was a CR, so that the LF of
+	// a CRLF split across reads is not doubled
+	lastCR := false
+	for {
+		n, err := p.f.Read(buf)
+		if n > 0 {
+			p.mu.Lock()
+			receive := p.receive
+			p.mu.Unlock()
+			var data []byte
+			data, lastCR = translateReceived(buf[:n], receive, lastCR)
+			p.out.write(data)
+		}
+		if err != nil {
+			p.out.close()
+			return
+		}
+	}
+}
+
+// translateReceived turns the line endings of the device into CRLF.
+func translateReceived(data []byte, receive Newline, lastCR bool) ([]byte, bool) {
+	if receive == NewlineCRLF || len(data) == 0 {
+		return data, false
+	}
+	out := make([]byte, 0, len(data)+8)
+	for _, b := range data {
+		switch {
+		case receive == NewlineLF && b == '\n':
+			out = append(out, '\r', '\n')
+		case receive == NewlineCR && b == '\r':
+			out = append(out, '\r', '\n')
+		case receive == NewlineCR && b == '\n' && lastCR:
+			// The LF of a CRLF was already shown
+		default:
+			out = append(out, b)
+		}
+		lastCR = b == '\r'
+	}
+	return out, lastCR
+}
+
+// translateSent turns the CR sent by the Enter key into the device's line
+// ending.
+func translateSent(data []byte, send Newline) []byte {
+	if send == NewlineCR {
+		return data
+	}
+	nl := "\n"
+	if send == NewlineCRLF {
+		nl = "\r\n"
+	}
+	out := make([]byte, 0, len(data)+8)
+	for _, b := range data {
+		if b == '\r' {
+			out = append(out, nl...)
+			continue
+		}
+		out = append(out, b)
+	}
+	return out
+}
+
+// Read returns what the device sent. It returns io.EOF once the port is
+// closed or the device is gone.
+func (p *Port) Read(b []byte) (int, error) {
+	return p.out.read(b)
+}
+
+// Write queues b to be sent to the device. It doesn't wait for the device,
+// failing with ErrBusy if too much is queued, or with the error of an
+// earlier write.
+func (p *Port) Write(b []byte) (int, error) {
+	p.mu.Lock()
+	echo, send, err := p.localEcho, p.send, p.err
+	p.err = nil
+	p.mu.Unlock()
+	if err != nil {
+		return 0, xerrors.Errorf("cannot write to the device: %w", err)
+	}
+	select {
+	case <-p.closed:
+		return 0, os.ErrClosed
+	default:
+	}
+	select {
+	case p.writes <- translateSent(b, send):
+	default:
+		return 0, ErrBusy
+	}
+	if echo {
+		// Echo Enter as a new line
+		p.out.write([]byte(strings.ReplaceAll(string(b), "\r", "\r\n")))
+	}
+	return len(b), nil
+}
+
+// Close closes the device, dropping the writes not yet sent.
+func (p *Port) Close() error {
+	p.once.Do(func() { close(p.closed) })
+	p.out.close()
+	return p.f.Close()
+}
+
+// SetLocalEcho turns local echo on or off.
+func (p *Port) SetLocalEcho(echo bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.localEcho = echo
+}
+
+// LocalEcho reports whether local echo is on.
+func (p *Port) LocalEcho() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.localEcho
+}
+
+// SetNewlines sets what Enter sends and how the device ends lines.
+func (p *Port) SetNewlines(send, receive Newline) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.send, p.receive = send, receive
+}
+
+// Break sends a break, holding the line low for a quarter of a second or so.
+func (p *Port) Break() error {
+	if err := sendBreak(p.f); err != nil {
+		return xerrors.Errorf("cannot send break: %w", err)
+	}
+	return nil
+}
+
+// stream is a buffer whose writes never block, so that echoing input can't
+// wait on the terminal reading its output.
+type stream struct {
+	mu     sync.Mutex
+	cond   *sync.Cond
+	buf    []byte
+	closed bool
+}
+
+func newStream() *stream {
+	s := &stream{}
+	s.cond = sync.NewCond(&s.mu)
+	return s
+}
+
+func (s *stream) write(b []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return
+	}
+	s.buf = append(s.buf, b...)
+	s.cond.Signal()
+}
+
+// read returns buffered data, waiting for some if there is none.
+func (s *stream) read(b []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for len(s.buf) == 0 && !s.closed {
+		s.cond.Wait()
+	}
+	if len(s.buf) == 0 {
+		return 0, io.EOF
+	}
+	n := copy(b, s.buf)
+	s.buf = s.buf[n:]
+	return n, nil
+}
+
+func (s *stream) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	s.cond.Broadcast()
+}
+
+// Devices returns the serial devices found in /sys/class/tty.
+func Devices() []string {
+	return devices("/sys/class/tty")
+}
+
+// devices lists the ttys in sysDir backed by a device with a driver. Legacy
+// ports of unknown type, which most ttyS are, are left out.
+func devices(sysDir string) []string {
+	entries, err := os.ReadDir(sysDir)
+	if err != nil {
+		return nil
+	}
+	found := []string{}
+	for _, e := range entries {
+		dir := path.Join(sysDir, e.Name())
+		if _, err := os.Stat(path.Join(dir, "device", "driver")); err != nil {
+			continue
+		}
+		if typ, err := os.ReadFile(path.Join(dir, "type")); err == nil && strings.TrimSpace(string(typ)) == "0" {
+			continue
+		}
+		found = append(found, path.Join("/dev", e.Name()))
+	}
+	sort.Strings(found)
+	return found
+}
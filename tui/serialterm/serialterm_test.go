@@ -0,0 +1,165 @@
+//go:build linux
+
+package serialterm
+
+import (
+	"io"
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/creack/pty"
+	"github.com/snadrus/tuitop/tui/internal/termtest"
+)
+
+// openPair opens a pty pair, with the port on the tty side standing in for a
+// serial device and the returned master being the device.
+func openPair(t *testing.T, opts Options) (*Port, *os.File) {
+	master, tty, err := pty.Open()
+	if err != nil {
+		t.Skipf("no pty: %v", err)
+	}
+	t.Cleanup(func() {
+		master.Close()
+		tty.Close()
+	})
+	opts.Device = tty.Name()
+	p, err := Open(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { p.Close() })
+	return p, master
+}
+
+func TestPort(t *testing.T) {
+	p, device := openPair(t, Options{Baud: 9600, Receive: NewlineLF, Send: NewlineCRLF})
+
+	device.WriteString("boot\nok\r\n")
+	// Raw mode passes CRLF through untouched, and lone LFs are translated
+	termtest.Expect(t, p, "boot\r\nok\r\r\n")
+
+	p.Write([]byte("ls\r"))
+	termtest.Expect(t, device, "ls\r\n")
+
+	// Local echo shows what was typed
+	p.SetLocalEcho(true)
+	p.SetNewlines(NewlineCR, NewlineCRLF)
+	p.Write([]byte("x\r"))
+	termtest.Expect(t, p, "x\r\n")
+	termtest.Expect(t, device, "x\r")
+
+	if err := p.Break(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPortHoldingOff(t *testing.T) {
+	p, device := openPair(t, Options{Flow: FlowSoftware})
+	device.Write([]byte{0x13})
+	time.Sleep(100 * time.Millisecond)
+
+	// Writes don't wait for the device, failing once too many are queued
+	var err error
+	for i := 0; i < 2*writeQueue && err == nil; i++ {
+		_, err = p.Write([]byte("x"))
+	}
+	if err != ErrBusy {
+		t.Fatalf("got %v, want ErrBusy", err)
+	}
+	device.Write([]byte{0x11})
+	termtest.Expect(t, device, strings.Repeat("x", writeQueue))
+}
+
+func TestPortClose(t *testing.T) {
+	p, _ := openPair(t, Options{})
+	done := make(chan error)
+	go func() {
+		_, err := p.Read(make([]byte, 10))
+		done <- err
+	}()
+	p.Close()
+	select {
+	case err := <-done:
+		if err != io.EOF {
+			t.Fatalf("got %v, want EOF", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Read did not return after Close")
+	}
+}
+
+func TestOpenErrors(t *testing.T) {
+	for _, opts := range []Options{
+		{Baud: 12345},
+		{DataBits: 9},
+		{Parity: "mark"},
+		{StopBits: 3},
+		{Flow: "dtr"},
+	} {
+		master, tty, err := pty.Open()
+		if err != nil {
+			t.Skipf("no pty: %v", err)
+		}
+		opts.Device = tty.Name()
+		if p, err := Open(opts); err == nil {
+			p.Close()
+			t.Errorf("%+v: opened", opts)
+		}
+		master.Close()
+		tty.Close()
+	}
+	if _, err := Open(Options{Device: "/dev/null"}); err == nil {
+		t.Error("opened /dev/null as a serial device")
+	}
+}
+
+func TestTranslateReceived(t *testing.T) {
+	for _, test := range []struct {
+		receive Newline
+		in      []string
+		want    string
+	}{
+		{NewlineCRLF, []string{"a\nb\r\n"}, "a\nb\r\n"},
+		{NewlineLF, []string{"a\nb\n"}, "a\r\nb\r\n"},
+		{NewlineCR, []string{"a\rb\r"}, "a\r\nb\r\n"},
+		// A CRLF split across reads is not doubled
+		{NewlineCR, []string{"a\r", "\nb"}, "a\r\nb"},
+	} {
+		got, lastCR := "", false
+		for _, in := range test.in {
+			var out []byte
+			out, lastCR = translateReceived([]byte(in), test.receive, lastCR)
+			got += string(out)
+		}
+		if got != test.want {
+			t.Errorf("%s %q: got %q, want %q", test.receive, test.in, got, test.want)
+		}
+	}
+}
+
+func TestDevices(t *testing.T) {
+	dir := t.TempDir()
+	mkdev := func(name string, driver bool, typ string) {
+		d := path.Join(dir, name, "device")
+		os.MkdirAll(d, 0o755)
+		if driver {
+			os.Mkdir(path.Join(d, "driver"), 0o755)
+		}
+		if typ != "" {
+			os.WriteFile(path.Join(dir, name, "type"), []byte(typ+"\n"), 0o644)
+		}
+	}
+	mkdev("ttyUSB0", true, "")
+	mkdev("ttyACM1", true, "")
+	mkdev("ttyS0", true, "4")
+	mkdev("ttyS1", true, "0")
+	mkdev("tty1", false, "")
+	got := strings.Join(devices(dir), " ")
+	want := "/dev/ttyACM1 /dev/ttyS0 /dev/ttyUSB0"
+	if got != want {
+		t.Fatalf("got %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,116 @@
+package serialterm
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+	"golang.org/x/xerrors"
+)
+
+var bauds = map[int]uint32{
+	50: unix.B50, 75: unix.B75, 110: unix.B110, 134: unix.B134, 150: unix.B150,
+	200: unix.B200, 300: unix.B300, 600: unix.B600, 1200: unix.B1200,
+	1800: unix.B1800, 2400: unix.B2400, 4800: unix.B4800, 9600: unix.B9600,
+	19200: unix.B19200, 38400: unix.B38400, 57600: unix.B57600,
+	115200: unix.B115200, 230400: unix.B230400, 460800: unix.B460800,
+	500000: unix.B500000, 576000: unix.B576000, 921600: unix.B921600,
+	1000000: unix.B1000000, 1152000: unix.B1152000, 1500000: unix.B1500000,
+	2000000: unix.B2000000, 2500000: unix.B2500000, 3000000: unix.B3000000,
+	3500000: unix.B3500000, 4000000: unix.B4000000,
+}
+
+var dataBits = map[int]uint32{5: unix.CS5, 6: unix.CS6, 7: unix.CS7, 8: unix.CS8}
+
+// openDevice opens the device in raw mode with the settings of opts.
+func openDevice(opts Options) (*os.File, error) {
+	speed, ok := bauds[opts.Baud]
+	if !ok {
+		return nil, xerrors.Errorf("unsupported baud rate %d", opts.Baud)
+	}
+	size, ok := dataBits[opts.DataBits]
+	if !ok {
+		return nil, xerrors.Errorf("unsupported data bits %d", opts.DataBits)
+	}
+	// Without O_NOCTTY the device could become our controlling terminal.
+	// O_NONBLOCK lets Close interrupt a pending Read
+	f, err := os.OpenFile(opts.Device, os.O_RDWR|unix.O_NOCTTY|unix.O_NONBLOCK, 0)
+	if err != nil {
+		return nil, xerrors.Errorf("cannot open %s: %w", opts.Device, err)
+	}
+	var t *unix.Termios
+	err = control(f, func(fd int) (err error) {
+		t, err = unix.IoctlGetTermios(fd, unix.TCGETS)
+		return err
+	})
+	if err != nil {
+		f.Close()
+		return nil, xerrors.Errorf("%s is not a tty: %w", opts.Device, err)
+	}
+	// Raw mode, as cfmakeraw
+	t.Iflag &^= unix.IGNBRK | unix.BRKINT | unix.PARMRK | unix.ISTRIP | unix.INLCR | unix.IGNCR | unix.ICRNL | unix.IXON | unix.IXOFF | unix.IXANY
+	t.Oflag &^= unix.OPOST
+	t.Lflag &^= unix.ECHO | unix.ECHONL | unix.ICANON | unix.ISIG | unix.IEXTEN
+	t.Cflag &^= unix.CSIZE | unix.PARENB | unix.PARODD | unix.CSTOPB | unix.CRTSCTS | unix.CBAUD
+	t.Cflag |= size | unix.CREAD | unix.CLOCAL | speed
+	t.Ispeed, t.Ospeed = speed, speed
+	switch opts.Parity {
+	case ParityNone:
+	case ParityEven:
+		t.Cflag |= unix.PARENB
+		t.Iflag |= unix.INPCK
+	case ParityOdd:
+		t.Cflag |= unix.PARENB | unix.PARODD
+		t.Iflag |= unix.INPCK
+	default:
+		f.Close()
+		return nil, xerrors.Errorf("unsupported parity %q", opts.Parity)
+	}
+	switch opts.StopBits {
+	case 1:
+	case 2:
+		t.Cflag |= unix.CSTOPB
+	default:
+		f.Close()
+		return nil, xerrors.Errorf("unsupported stop bits %d", opts.StopBits)
+	}
+	switch opts.Flow {
+	case FlowNone:
+	case FlowHardware:
+		t.Cflag |= unix.CRTSCTS
+	case FlowSoftware:
+		t.Iflag |= unix.IXON | unix.IXOFF
+	default:
+		f.Close()
+		return nil, xerrors.Errorf("unsupported flow control %q", opts.Flow)
+	}
+	t.Cc[unix.VMIN] = 1
+	t.Cc[unix.VTIME] = 0
+	err = control(f, func(fd int) error {
+		return unix.IoctlSetTermios(fd, unix.TCSETS, t)
+	})
+	if err != nil {
+		f.Close()
+		return nil, xerrors.Errorf("cannot set up %s: %w", opts.Device, err)
+	}
+	return f, nil
+}
+
+func sendBreak(f *os.File) error {
+	return control(f, func(fd int) error {
+		return unix.IoctlSetInt(fd, unix.TCSBRK, 0)
+	})
+}
+
+// control runs fn with the descriptor of f. Unlike f.Fd, it leaves f
+// non-blocking.
+func control(f *os.File, fn func(fd int) error) error {
+	raw, err := f.SyscallConn()
+	if err != nil {
+		return err
+	}
+	var fnErr error
+	if err := raw.Control(func(fd uintptr) { fnErr = fn(int(fd)) }); err != nil {
+		return err
+	}
+	return fnErr
+}
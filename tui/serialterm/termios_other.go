@@ -0,0 +1,17 @@
+//go:build !linux
+
+package serialterm
+
+import (
+	"os"
+
+	"golang.org/x/xerrors"
+)
+
+func openDevice(opts Options) (*os.File, error) {
+	return nil, xerrors.New("serial devices are only supported on Linux")
+}
+
+func sendBreak(f *os.File) error {
+	return xerrors.New("serial devices are only supported on Linux")
+}
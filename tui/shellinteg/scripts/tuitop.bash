@@ -0,0 +1,22 @@
+# TuiTop shell integration for bash. Marks prompts, commands and their output
+# with OSC 133 and reports the working directory with OSC 7.
+if [ -f ~/.bashrc ]; then
+	. ~/.bashrc
+fi
+
+__tuitop_precmd() {
+	local ret=$?
+	printf '\e]133;D;%s\a\e]7;file://%s%s\a' "$ret" "$HOSTNAME" "$PWD"
+	return $ret
+}
+
+__tuitop_ps1() {
+	# Prompt themes may set PS1 in PROMPT_COMMAND, so mark it every time
+	case "$PS1" in
+	*'133;A'*) ;;
+	*) PS1='\[\e]133;A\a\]'"$PS1"'\[\e]133;B\a\]' ;;
+	esac
+}
+
+PROMPT_COMMAND="__tuitop_precmd${PROMPT_COMMAND:+;$PROMPT_COMMAND};__tuitop_ps1"
+PS0="${PS0}\e]133;C\a"
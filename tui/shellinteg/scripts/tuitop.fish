@@ -0,0 +1,23 @@
+# TuiTop shell integration for fish. Marks prompts, commands and their output
+# with OSC 133 and reports the working directory with OSC 7.
+status is-interactive; or exit
+
+function __tuitop_prompt --on-event fish_prompt
+	printf '\e]7;file://%s%s\a\e]133;A\a' $hostname $PWD
+	# Mark the end of the prompt, once the user's prompt is loaded
+	if not functions -q __tuitop_fish_prompt; and functions -q fish_prompt
+		functions -c fish_prompt __tuitop_fish_prompt
+		function fish_prompt
+			__tuitop_fish_prompt
+			printf '\e]133;B\a'
+		end
+	end
+end
+
+function __tuitop_preexec --on-event fish_preexec
+	printf '\e]133;C\a'
+end
+
+function __tuitop_postexec --on-event fish_postexec
+	printf '\e]133;D;%s\a' $status
+end
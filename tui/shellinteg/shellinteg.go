@@ -0,0 +1,92 @@
+// Package shellinteg sets up shells started in TuiTop windows to mark their
+// prompts and commands with OSC 133 and report their working directory with
+// OSC 7.
+package shellinteg
+
+import (
+	"embed"
+	"os"
+	"os/exec"
+	"path"
+
+	"golang.org/x/xerrors"
+)
+
+//go:embed scripts
+var scripts embed.FS
+
+// files maps the scripts to where they are written, relative to Dir().
+var files = map[string]string{
+	"scripts/tuitop.bash": "tuitop.bash",
+	"scripts/zshenv":      "zsh/.zshenv",
+	"scripts/tuitop.zsh":  "zsh/tuitop.zsh",
+	"scripts/tuitop.fish": "fish/vendor_conf.d/tuitop.fish",
+}
+
+// Dir returns the directory the scripts are written to.
+func Dir() (string, error) {
+	config, err := os.UserConfigDir()
+	if err != nil {
+		return "", xerrors.Errorf("cannot get user config directory: %w", err)
+	}
+	return path.Join(config, "tuitop", "shell"), nil
+}
+
+// install writes the scripts, replacing older versions.
+func install() (string, error) {
+	dir, err := Dir()
+	if err != nil {
+		return "", err
+	}
+	for src, dst := range files {
+		data, err := scripts.ReadFile(src)
+		if err != nil {
+			return "", xerrors.Errorf("cannot read %s: %w", src, err)
+		}
+		dst = path.Join(dir, dst)
+		if err := os.MkdirAll(path.Dir(dst), 0o755); err != nil {
+			return "", xerrors.Errorf("cannot create %s: %w", path.Dir(dst), err)
+		}
+		if err := os.WriteFile(dst, data, 0o644); err != nil {
+			return "", xerrors.Errorf("cannot write %s: %w", dst, err)
+		}
+	}
+	return dir, nil
+}
+
+// Setup changes cmd to load the integration script of its shell. Commands
+// other than bash, zsh and fish are left alone.
+func Setup(cmd *exec.Cmd) error {
+	shell := path.Base(cmd.Path)
+	switch shell {
+	case "bash", "zsh", "fish":
+	default:
+		return nil
+	}
+	dir, err := install()
+	if err != nil {
+		return err
+	}
+	env := cmd.Env
+	if env == nil {
+		env = os.Environ()
+	}
+	env = append(env, "TUITOP_SHELL_INTEGRATION=1")
+	switch shell {
+	case "bash":
+		cmd.Args = append([]string{cmd.Args[0], "--rcfile", path.Join(dir, "tuitop.bash")}, cmd.Args[1:]...)
+	case "zsh":
+		if zdotdir, ok := os.LookupEnv("ZDOTDIR"); ok {
+			env = append(env, "TUITOP_ZDOTDIR="+zdotdir)
+		}
+		env = append(env, "ZDOTDIR="+path.Join(dir, "zsh"))
+	case "fish":
+		dataDirs := os.Getenv("XDG_DATA_DIRS")
+		if dataDirs == "" {
+			dataDirs = "/usr/local/share:/usr/share"
+		}
+		env = append(env, "XDG_DATA_DIRS="+dir+":"+dataDirs)
+	}
+	cmd.Env = env
+	return nil
+}
@@ -0,0 +1,200 @@
+package sshterm
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"os/user"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"golang.org/x/xerrors"
+)
+
+// Target is a host to connect to, with the settings found for it in the SSH
+// config.
+type Target struct {
+	// Alias is the name the host was given as, which titles the window.
+	Alias         string
+	Host          string
+	User          string
+	Port          int
+	IdentityFiles []string
+}
+
+// Addr returns the host and port to dial.
+func (t Target) Addr() string {
+	return fmtAddr(t.Host, t.Port)
+}
+
+func fmtAddr(host string, port int) string {
+	if strings.Contains(host, ":") {
+		return "[" + host + "]:" + strconv.Itoa(port)
+	}
+	return host + ":" + strconv.Itoa(port)
+}
+
+// ParseDestination splits a destination written as [user@]host[:port].
+// Fields which are not given are left empty or zero.
+func ParseDestination(dest string) Target {
+	t := Target{}
+	if i := strings.LastIndexByte(dest, '@'); i >= 0 {
+		t.User, dest = dest[:i], dest[i+1:]
+	}
+	switch {
+	case strings.HasPrefix(dest, "["):
+		// [ipv6]:port
+		if end := strings.IndexByte(dest, ']'); end > 0 {
+			host, rest := dest[1:end], dest[end+1:]
+			if port, err := strconv.Atoi(strings.TrimPrefix(rest, ":")); err == nil {
+				t.Port = port
+			}
+			dest = host
+		}
+	case strings.Count(dest, ":") == 1:
+		i := strings.IndexByte(dest, ':')
+		if port, err := strconv.Atoi(dest[i+1:]); err == nil {
+			t.Port = port
+			dest = dest[:i]
+		}
+	}
+	t.Alias = dest
+	t.Host = dest
+	return t
+}
+
+// Resolve fills in the target for dest from the user's ~/.ssh/config.
+// Settings given in dest take precedence over the config, and defaults are
+// used for what neither sets.
+func Resolve(dest string) (Target, error) {
+	t := ParseDestination(dest)
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return defaults(t), nil
+	}
+	f, err := os.Open(path.Join(home, ".ssh", "config"))
+	if os.IsNotExist(err) {
+		return defaults(t), nil
+	}
+	if err != nil {
+		return defaults(t), xerrors.Errorf("cannot read ssh config: %w", err)
+	}
+	defer f.Close()
+	return defaults(applyConfig(t, f, home)), nil
+}
+
+// applyConfig sets the fields of t which are still unset from the Host
+// sections of an SSH config matching its alias. As with ssh, the first value
+// found for a setting wins.
+func applyConfig(t Target, r io.Reader, home string) Target {
+	var (
+		host, user string
+		port       int
+		identities []string
+		matching   = true
+	)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		key, value, ok := configLine(scanner.Text())
+		if !ok {
+			continue
+		}
+		if key == "host" {
+			matching = matchHost(t.Alias, strings.Fields(value))
+			continue
+		}
+		if key == "match" {
+			// Match blocks are not supported and never apply
+			matching = false
+			continue
+		}
+		if !matching {
+			continue
+		}
+		switch key {
+		case "hostname":
+			if host == "" {
+				host = value
+			}
+		case "user":
+			if user == "" {
+				user = value
+			}
+		case "port":
+			if port == 0 {
+				port, _ = strconv.Atoi(value)
+			}
+		case "identityfile":
+			identities = append(identities, expandHome(value, home))
+		}
+	}
+	if host != "" {
+		t.Host = strings.ReplaceAll(host, "%h", t.Alias)
+	}
+	if t.User == "" {
+		t.User = user
+	}
+	if t.Port == 0 {
+		t.Port = port
+	}
+	t.IdentityFiles = append(t.IdentityFiles, identities...)
+	return t
+}
+
+// configLine splits a config line into its lowercased keyword and value.
+// Keywords are separated from values by blanks or an equals sign.
+func configLine(line string) (string, string, bool) {
+	line = strings.TrimSpace(line)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return "", "", false
+	}
+	i := strings.IndexAny(line, " \t=")
+	if i < 0 {
+		return "", "", false
+	}
+	key := strings.ToLower(line[:i])
+	value := strings.TrimLeft(line[i:], " \t")
+	value = strings.TrimPrefix(value, "=")
+	value = strings.Trim(strings.TrimSpace(value), `"`)
+	return key, value, true
+}
+
+// matchHost reports whether alias matches the patterns of a Host line. A
+// pattern starting with ! excludes the hosts it matches.
+func matchHost(alias string, patterns []string) bool {
+	matched := false
+	for _, pattern := range patterns {
+		negate := strings.HasPrefix(pattern, "!")
+		pattern = strings.TrimPrefix(pattern, "!")
+		ok, err := filepath.Match(pattern, alias)
+		if err != nil || !ok {
+			continue
+		}
+		if negate {
+			return false
+		}
+		matched = true
+	}
+	return matched
+}
+
+func expandHome(p string, home string) string {
+	if strings.HasPrefix(p, "~/") {
+		return path.Join(home, p[2:])
+	}
+	return p
+}
+
+func defaults(t Target) Target {
+	if t.Port == 0 {
+		t.Port = 22
+	}
+	if t.User == "" {
+		if u, err := user.Current(); err == nil {
+			t.User = u.Username
+		}
+	}
+	return t
+}
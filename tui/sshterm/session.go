@@ -0,0 +1,309 @@
+// Package sshterm connects terminal windows to remote shells over SSH, with
+// hosts set up as in the user's ~/.ssh/config and keys checked against
+// ~/.ssh/known_hosts.
+package sshterm
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
+	"golang.org/x/xerrors"
+)
+
+// ReconnectDelay is the time between two attempts to reconnect a dropped
+// session.
+var ReconnectDelay = 3 * time.Second
+
+// DialTimeout is the longest time to wait for the host to answer.
+const DialTimeout = 15 * time.Second
+
+// defaultIdentities are tried, in order, when the config names no identity
+// file for the host.
+var defaultIdentities = []string{"id_ed25519", "id_ecdsa", "id_rsa"}
+
+type Options struct {
+	Target Target
+	// KnownHosts is the known_hosts file host keys are checked against and
+	// added to. The default is ~/.ssh/known_hosts.
+	KnownHosts string
+	// Confirm asks the user whether to trust the key of a host which is not
+	// in KnownHosts yet. It is called from the dialing goroutine and may
+	// block. Unknown hosts are refused if it is nil.
+	Confirm func(host string, key ssh.PublicKey) bool
+	// Reconnect dials again when the connection drops, rather than ending
+	// the session. Sessions whose shell exits are not reconnected.
+	Reconnect bool
+	// Term is the TERM of the remote shell, xterm-256color by default.
+	Term string
+}
+
+// Session is a remote shell. Reading returns its output and writing sends it
+// input.
+type Session struct {
+	opts Options
+	out  *io.PipeReader
+	outW *io.PipeWriter
+
+	mu         sync.Mutex
+	client     *ssh.Client
+	session    *ssh.Session
+	stdin      io.WriteCloser
+	cols, rows int
+	closed     bool
+}
+
+// Dial connects to the target and starts a shell on a terminal of cols by
+// rows cells.
+func Dial(opts Options, cols, rows int) (*Session, error) {
+	if opts.Term == "" {
+		opts.Term = "xterm-256color"
+	}
+	if opts.KnownHosts == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, xerrors.Errorf("cannot find known_hosts: %w", err)
+		}
+		opts.KnownHosts = path.Join(home, ".ssh", "known_hosts")
+	}
+	s := &Session{opts: opts, cols: cols, rows: rows}
+	s.out, s.outW = io.Pipe()
+	if err := s.connect(); err != nil {
+		return nil, err
+	}
+	go s.run()
+	return s, nil
+}
+
+// connect dials the host and starts the shell, replacing any previous
+// connection.
+func (s *Session) connect() error {
+	t := s.opts.Target
+	auth, done := s.authMethods()
+	client, err := ssh.Dial("tcp", t.Addr(), &ssh.ClientConfig{
+		User:            t.User,
+		Auth:            auth,
+		HostKeyCallback: s.checkHostKey,
+		Timeout:         DialTimeout,
+	})
+	done()
+	if err != nil {
+		return xerrors.Errorf("cannot connect to %s: %w", t.Alias, err)
+	}
+	session, err := client.NewSession()
+	if err != nil {
+		client.Close()
+		return xerrors.Errorf("cannot open session on %s: %w", t.Alias, err)
+	}
+	stdin, err := session.StdinPipe()
+	if err != nil {
+		client.Close()
+		return xerrors.Errorf("cannot open session on %s: %w", t.Alias, err)
+	}
+	session.Stdout = s.outW
+	session.Stderr = s.outW
+
+	s.mu.Lock()
+	cols, rows := s.cols, s.rows
+	s.mu.Unlock()
+	modes := ssh.TerminalModes{ssh.ECHO: 1}
+	if err := session.RequestPty(s.opts.Term, rows, cols, modes); err != nil {
+		client.Close()
+		return xerrors.Errorf("cannot get a terminal on %s: %w", t.Alias, err)
+	}
+	if err := session.Shell(); err != nil {
+		client.Close()
+		return xerrors.Errorf("cannot start shell on %s: %w", t.Alias, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		client.Close()
+		return net.ErrClosed
+	}
+	s.client, s.session, s.stdin = client, session, stdin
+	// The terminal may have been resized while connecting
+	if s.cols != cols || s.rows != rows {
+		session.WindowChange(s.rows, s.cols)
+	}
+	return nil
+}
+
+// run waits for the shell to end, reconnecting if the connection dropped and
+// Reconnect is set.
+func (s *Session) run() {
+	for {
+		s.mu.Lock()
+		session, client := s.session, s.client
+		s.mu.Unlock()
+		err := session.Wait()
+		client.Close()
+
+		var exit *ssh.ExitError
+		if err == nil || errors.As(err, &exit) || !s.opts.Reconnect || s.isClosed() {
+			s.outW.Close()
+			return
+		}
+		fmt.Fprintf(s.outW, "\r\n\x1b[33m[connection to %s lost, reconnecting]\x1b[m\r\n", s.opts.Target.Alias)
+		for {
+			time.Sleep(ReconnectDelay)
+			if s.isClosed() {
+				s.outW.Close()
+				return
+			}
+			err := s.connect()
+			if err == nil {
+				break
+			}
+			if errors.Is(err, net.ErrClosed) {
+				s.outW.Close()
+				return
+			}
+			fmt.Fprintf(s.outW, "\x1b[33m[%s]\x1b[m\r\n", err)
+		}
+	}
+}
+
+func (s *Session) isClosed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.closed
+}
+
+// Read returns the output of the shell. It returns io.EOF once the shell
+// exited or the connection dropped for good.
+func (s *Session) Read(p []byte) (int, error) {
+	return s.out.Read(p)
+}
+
+// Write sends input to the shell. Input is dropped while reconnecting.
+func (s *Session) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	stdin := s.stdin
+	s.mu.Unlock()
+	if stdin == nil {
+		return 0, net.ErrClosed
+	}
+	if _, err := stdin.Write(p); err != nil && !s.opts.Reconnect {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Resize tells the remote shell the terminal is now cols by rows cells.
+func (s *Session) Resize(cols, rows int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if cols == s.cols && rows == s.rows {
+		return
+	}
+	s.cols, s.rows = cols, rows
+	if s.session != nil {
+		s.session.WindowChange(rows, cols)
+	}
+}
+
+// Close disconnects from the host.
+func (s *Session) Close() error {
+	s.mu.Lock()
+	s.closed = true
+	client := s.client
+	s.mu.Unlock()
+	if client != nil {
+		client.Close()
+	}
+	return s.out.Close()
+}
+
+// checkHostKey accepts the keys found in known_hosts. The user is asked about
+// unknown hosts, whose key is then saved, while a key which changed is
+// always refused.
+func (s *Session) checkHostKey(host string, remote net.Addr, key ssh.PublicKey) error {
+	known := s.opts.KnownHosts
+	if _, err := os.Stat(known); err == nil {
+		check, err := knownhosts.New(known)
+		if err != nil {
+			return xerrors.Errorf("cannot read %s: %w", known, err)
+		}
+		err = check(host, remote, key)
+		var keyErr *knownhosts.KeyError
+		if !errors.As(err, &keyErr) || len(keyErr.Want) > 0 {
+			// Known, changed or revoked
+			return err
+		}
+	}
+	if s.opts.Confirm == nil || !s.opts.Confirm(host, key) {
+		return xerrors.Errorf("host key of %s was not accepted", host)
+	}
+	if err := os.MkdirAll(path.Dir(known), 0o700); err != nil {
+		return xerrors.Errorf("cannot create %s: %w", path.Dir(known), err)
+	}
+	f, err := os.OpenFile(known, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return xerrors.Errorf("cannot save host key: %w", err)
+	}
+	defer f.Close()
+	line := knownhosts.Line([]string{knownhosts.Normalize(host)}, key)
+	if _, err := fmt.Fprintln(f, line); err != nil {
+		return xerrors.Errorf("cannot save host key: %w", err)
+	}
+	return nil
+}
+
+// authMethods returns the keys of the SSH agent, then those of the identity
+// files, as one method since each is tried only once. done releases the
+// agent once the client authenticated.
+func (s *Session) authMethods() (auth []ssh.AuthMethod, done func()) {
+	done = func() {}
+	var agentSigners func() ([]ssh.Signer, error)
+	if sock := os.Getenv("SSH_AUTH_SOCK"); sock != "" {
+		if conn, err := net.Dial("unix", sock); err == nil {
+			agentSigners = agent.NewClient(conn).Signers
+			done = func() { conn.Close() }
+		}
+	}
+	files := s.opts.Target.IdentityFiles
+	if len(files) == 0 {
+		if home, err := os.UserHomeDir(); err == nil {
+			for _, name := range defaultIdentities {
+				files = append(files, path.Join(home, ".ssh", name))
+			}
+		}
+	}
+	fileSigners := []ssh.Signer{}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			continue
+		}
+		// Keys protected by a passphrase are left to the agent
+		signer, err := ssh.ParsePrivateKey(data)
+		if err != nil {
+			continue
+		}
+		fileSigners = append(fileSigners, signer)
+	}
+	if agentSigners == nil && len(fileSigners) == 0 {
+		return nil, done
+	}
+	auth = append(auth, ssh.PublicKeysCallback(func() ([]ssh.Signer, error) {
+		signers := []ssh.Signer{}
+		if agentSigners != nil {
+			// An agent failing leaves the identity files
+			if found, err := agentSigners(); err == nil {
+				signers = append(signers, found...)
+			}
+		}
+		return append(signers, fileSigners...), nil
+	}))
+	return auth, done
+}
@@ -0,0 +1,372 @@
+package sshterm
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/pem"
+	"io"
+	"net"
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/snadrus/tuitop/tui/internal/termtest"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+// testServer is an SSH server whose shell echoes its input.
+type testServer struct {
+	addr    string
+	hostKey ssh.Signer
+	// sizes receives the terminal size, in columns and rows, on pty-req and
+	// window-change
+	sizes chan [2]int
+	// conns receives each accepted connection
+	conns chan net.Conn
+}
+
+func newKey(t *testing.T) (ed25519.PrivateKey, ssh.Signer) {
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := ssh.NewSignerFromKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return priv, signer
+}
+
+func startServer(t *testing.T, clientKey ssh.PublicKey) *testServer {
+	_, hostKey := newKey(t)
+	config := &ssh.ServerConfig{
+		PublicKeyCallback: func(conn ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			if conn.User() == "tester" && string(key.Marshal()) == string(clientKey.Marshal()) {
+				return nil, nil
+			}
+			return nil, io.EOF
+		},
+	}
+	config.AddHostKey(hostKey)
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	srv := &testServer{
+		addr:    l.Addr().String(),
+		hostKey: hostKey,
+		sizes:   make(chan [2]int, 10),
+		conns:   make(chan net.Conn, 10),
+	}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			srv.conns <- conn
+			go srv.serve(conn, config)
+		}
+	}()
+	return srv
+}
+
+func (srv *testServer) serve(conn net.Conn, config *ssh.ServerConfig) {
+	_, chans, reqs, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		return
+	}
+	go ssh.DiscardRequests(reqs)
+	for newCh := range chans {
+		if newCh.ChannelType() != "session" {
+			newCh.Reject(ssh.UnknownChannelType, "")
+			continue
+		}
+		ch, reqs, err := newCh.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			for req := range reqs {
+				switch req.Type {
+				case "pty-req":
+					// string term, uint32 cols, uint32 rows, ...
+					termLen := binary.BigEndian.Uint32(req.Payload)
+					size := req.Payload[4+termLen:]
+					srv.sizes <- [2]int{int(binary.BigEndian.Uint32(size)), int(binary.BigEndian.Uint32(size[4:]))}
+					req.Reply(true, nil)
+				case "window-change":
+					srv.sizes <- [2]int{int(binary.BigEndian.Uint32(req.Payload)), int(binary.BigEndian.Uint32(req.Payload[4:]))}
+				case "shell":
+					req.Reply(true, nil)
+					go func() {
+						io.Copy(ch, ch)
+						ch.SendRequest("exit-status", false, []byte{0, 0, 0, 0})
+						ch.Close()
+					}()
+				default:
+					req.Reply(false, nil)
+				}
+			}
+		}()
+	}
+}
+
+// setup returns options to connect to srv as tester, with a key file and an
+// empty known_hosts in a temporary folder
+func setup(t *testing.T) (*testServer, Options) {
+	t.Setenv("SSH_AUTH_SOCK", "")
+	dir := t.TempDir()
+	priv, signer := newKey(t)
+	block, err := ssh.MarshalPrivateKey(priv, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyFile := path.Join(dir, "id_ed25519")
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(block), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	srv := startServer(t, signer.PublicKey())
+	target := ParseDestination("tester@" + srv.addr)
+	target.IdentityFiles = []string{keyFile}
+	return srv, Options{
+		Target:     target,
+		KnownHosts: path.Join(dir, "known_hosts"),
+	}
+}
+
+func expectSize(t *testing.T, srv *testServer, want [2]int) {
+	t.Helper()
+	select {
+	case got := <-srv.sizes:
+		if got != want {
+			t.Fatalf("got size %v, want %v", got, want)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timed out waiting for size %v", want)
+	}
+}
+
+func TestKnownHosts(t *testing.T) {
+	srv, opts := setup(t)
+
+	// Refused without confirmation, and not saved
+	opts.Confirm = func(host string, key ssh.PublicKey) bool { return false }
+	if _, err := Dial(opts, 80, 24); err == nil {
+		t.Fatal("connected to unconfirmed host")
+	}
+	if _, err := os.Stat(opts.KnownHosts); err == nil {
+		t.Fatal("saved refused host key")
+	}
+
+	// Confirmed and saved
+	asked := 0
+	opts.Confirm = func(host string, key ssh.PublicKey) bool {
+		asked += 1
+		if string(key.Marshal()) != string(srv.hostKey.PublicKey().Marshal()) {
+			t.Errorf("asked about the wrong key")
+		}
+		return true
+	}
+	s, err := Dial(opts, 80, 24)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Close()
+	if asked != 1 {
+		t.Fatalf("asked %d times, want 1", asked)
+	}
+
+	// Known now
+	s, err = Dial(opts, 80, 24)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Close()
+	if asked != 1 {
+		t.Fatalf("asked %d times about a known host, want 1", asked)
+	}
+}
+
+func TestHostKeyMismatch(t *testing.T) {
+	srv, opts := setup(t)
+	_, other := newKey(t)
+	line := "[127.0.0.1]:" + srv.addr[strings.LastIndexByte(srv.addr, ':')+1:] + " " +
+		strings.TrimSpace(string(ssh.MarshalAuthorizedKey(other.PublicKey())))
+	if err := os.WriteFile(opts.KnownHosts, []byte(line+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	opts.Confirm = func(host string, key ssh.PublicKey) bool {
+		t.Error("asked about a changed key")
+		return true
+	}
+	if _, err := Dial(opts, 80, 24); err == nil {
+		t.Fatal("connected to host with a changed key")
+	}
+}
+
+func TestSession(t *testing.T) {
+	srv, opts := setup(t)
+	opts.Confirm = func(string, ssh.PublicKey) bool { return true }
+	s, err := Dial(opts, 80, 24)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	expectSize(t, srv, [2]int{80, 24})
+
+	if _, err := s.Write([]byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+	termtest.Expect(t, s, "hello")
+
+	s.Resize(100, 30)
+	expectSize(t, srv, [2]int{100, 30})
+	// Unchanged sizes are not sent
+	s.Resize(100, 30)
+	s.Resize(90, 20)
+	expectSize(t, srv, [2]int{90, 20})
+}
+
+func TestAgentWithoutTheKey(t *testing.T) {
+	srv, opts := setup(t)
+	opts.Confirm = func(string, ssh.PublicKey) bool { return true }
+	// The agent's key is tried first, then the identity file's
+	keyring := agent.NewKeyring()
+	other, _ := newKey(t)
+	if err := keyring.Add(agent.AddedKey{PrivateKey: other}); err != nil {
+		t.Fatal(err)
+	}
+	sock := path.Join(t.TempDir(), "agent.sock")
+	l, err := net.Listen("unix", sock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go agent.ServeAgent(keyring, conn)
+		}
+	}()
+	t.Setenv("SSH_AUTH_SOCK", sock)
+
+	s, err := Dial(opts, 80, 24)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Close()
+	expectSize(t, srv, [2]int{80, 24})
+}
+
+func TestReconnect(t *testing.T) {
+	ReconnectDelay = 10 * time.Millisecond
+	srv, opts := setup(t)
+	opts.Confirm = func(string, ssh.PublicKey) bool { return true }
+	opts.Reconnect = true
+	s, err := Dial(opts, 80, 24)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	expectSize(t, srv, [2]int{80, 24})
+	s.Resize(70, 20)
+	expectSize(t, srv, [2]int{70, 20})
+
+	// Drop the connection
+	s.mu.Lock()
+	dropped := s.session
+	s.mu.Unlock()
+	(<-srv.conns).Close()
+	termtest.Expect(t, s, "reconnecting")
+	// The new terminal has the last size
+	expectSize(t, srv, [2]int{70, 20})
+	// Input is dropped until the shell started
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); {
+		s.mu.Lock()
+		reconnected := s.session != dropped
+		s.mu.Unlock()
+		if reconnected {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	s.Write([]byte("again"))
+	termtest.Expect(t, s, "again")
+}
+
+func TestNoReconnect(t *testing.T) {
+	srv, opts := setup(t)
+	opts.Confirm = func(string, ssh.PublicKey) bool { return true }
+	s, err := Dial(opts, 80, 24)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	(<-srv.conns).Close()
+	if _, err := io.ReadAll(s); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestApplyConfig(t *testing.T) {
+	config := `
+# comment
+Host web !db
+    HostName %h.example.com
+    User alice
+    IdentityFile ~/.ssh/web
+
+Host *
+    User nobody
+    Port=2222
+    IdentityFile "~/.ssh/id_any"
+`
+	got := applyConfig(ParseDestination("web"), strings.NewReader(config), "/home/a")
+	want := Target{
+		Alias:         "web",
+		Host:          "web.example.com",
+		User:          "alice",
+		Port:          2222,
+		IdentityFiles: []string{"/home/a/.ssh/web", "/home/a/.ssh/id_any"},
+	}
+	if got.Alias != want.Alias || got.Host != want.Host || got.User != want.User || got.Port != want.Port ||
+		strings.Join(got.IdentityFiles, ",") != strings.Join(want.IdentityFiles, ",") {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+
+	// The destination wins over the config
+	got = applyConfig(ParseDestination("bob@web:2200"), strings.NewReader(config), "/home/a")
+	if got.User != "bob" || got.Port != 2200 {
+		t.Fatalf("got %+v, want user bob on port 2200", got)
+	}
+
+	// Negated patterns exclude the host
+	got = applyConfig(ParseDestination("db"), strings.NewReader("Host * !db\n  User x\n"), "/home/a")
+	if got.User != "" || got.Host != "db" {
+		t.Fatalf("got %+v, want no settings", got)
+	}
+}
+
+func TestParseDestination(t *testing.T) {
+	for dest, want := range map[string]Target{
+		"host":         {Alias: "host", Host: "host"},
+		"me@host:2022": {Alias: "host", Host: "host", User: "me", Port: 2022},
+		"[::1]:2022":   {Alias: "::1", Host: "::1", Port: 2022},
+		"me@x@host":    {Alias: "host", Host: "host", User: "me@x"},
+		"fe80::1":      {Alias: "fe80::1", Host: "fe80::1"},
+	} {
+		got := ParseDestination(dest)
+		if got.Alias != want.Alias || got.Host != want.Host || got.User != want.User || got.Port != want.Port {
+			t.Errorf("%s: got %+v, want %+v", dest, got, want)
+		}
+	}
+}
@@ -0,0 +1,16 @@
+package sysstat
+
+import (
+	"golang.org/x/sys/unix"
+	"golang.org/x/xerrors"
+)
+
+// DiskUsage returns the usage of the filesystem holding path.
+func DiskUsage(path string) (Disk, error) {
+	var st unix.Statfs_t
+	if err := unix.Statfs(path, &st); err != nil {
+		return Disk{}, xerrors.Errorf("cannot get usage of %s: %w", path, err)
+	}
+	size := uint64(st.Bsize)
+	return Disk{Path: path, Total: st.Blocks * size, Free: st.Bfree * size, Avail: st.Bavail * size}, nil
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+
+package sysstat
+
+import "golang.org/x/xerrors"
+
+// DiskUsage returns the usage of the filesystem holding path.
+func DiskUsage(path string) (Disk, error) {
+	return Disk{}, xerrors.New("disk usage is only supported on Linux")
+}
@@ -0,0 +1,332 @@
+// Package sysstat reads the state of the system shown in the tray: CPU,
+// memory, load, batteries, network, disks and the keyboard layout, from /proc
+// and /sys.
+package sysstat
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/xerrors"
+)
+
+// CPUTimes are the time the CPUs spent since boot, in clock ticks.
+type CPUTimes struct {
+	Busy, Total uint64
+	Cores       int
+}
+
+// ReadCPU reads the CPU times from /proc/stat.
+func ReadCPU() (CPUTimes, error) {
+	return readCPU("/proc")
+}
+
+func readCPU(proc string) (CPUTimes, error) {
+	data, err := os.ReadFile(path.Join(proc, "stat"))
+	if err != nil {
+		return CPUTimes{}, xerrors.Errorf("cannot read CPU times: %w", err)
+	}
+	var t CPUTimes
+	found := false
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 || !strings.HasPrefix(fields[0], "cpu") {
+			continue
+		}
+		if fields[0] != "cpu" {
+			t.Cores += 1
+			continue
+		}
+		found = true
+		// user nice system idle iowait irq softirq steal, guests being
+		// counted in user already
+		for i, f := range fields[1:min(len(fields), 9)] {
+			n, _ := strconv.ParseUint(f, 10, 64)
+			t.Total += n
+			if i != 3 && i != 4 {
+				t.Busy += n
+			}
+		}
+	}
+	if !found {
+		return CPUTimes{}, xerrors.New("no cpu line in /proc/stat")
+	}
+	return t, nil
+}
+
+// Usage returns the percentage of time the CPUs were busy since prev.
+func (t CPUTimes) Usage(prev CPUTimes) float64 {
+	if t.Total <= prev.Total || t.Busy < prev.Busy {
+		return 0
+	}
+	return 100 * float64(t.Busy-prev.Busy) / float64(t.Total-prev.Total)
+}
+
+// Memory is the memory and swap of the system, in bytes.
+type Memory struct {
+	Total, Available, Buffers, Cached uint64
+	SwapTotal, SwapFree               uint64
+}
+
+// Used returns the memory which is not available.
+func (m Memory) Used() uint64 {
+	return m.Total - min(m.Available, m.Total)
+}
+
+// UsedPercent returns the percentage of memory which is not available.
+func (m Memory) UsedPercent() float64 {
+	if m.Total == 0 {
+		return 0
+	}
+	return 100 * float64(m.Used()) / float64(m.Total)
+}
+
+// ReadMemory reads /proc/meminfo.
+func ReadMemory() (Memory, error) {
+	return readMemory("/proc")
+}
+
+func readMemory(proc string) (Memory, error) {
+	data, err := os.ReadFile(path.Join(proc, "meminfo"))
+	if err != nil {
+		return Memory{}, xerrors.Errorf("cannot read memory usage: %w", err)
+	}
+	var m Memory
+	fields := map[string]*uint64{
+		"MemTotal":     &m.Total,
+		"MemAvailable": &m.Available,
+		"Buffers":      &m.Buffers,
+		"Cached":       &m.Cached,
+		"SwapTotal":    &m.SwapTotal,
+		"SwapFree":     &m.SwapFree,
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		name, value, ok := strings.Cut(line, ":")
+		if p := fields[name]; ok && p != nil {
+			kb, _ := strconv.ParseUint(strings.TrimSuffix(strings.TrimSpace(value), " kB"), 10, 64)
+			*p = kb * 1024
+		}
+	}
+	if m.Total == 0 {
+		return Memory{}, xerrors.New("no MemTotal in /proc/meminfo")
+	}
+	return m, nil
+}
+
+// Load is the load average over 1, 5 and 15 minutes, and the number of
+// runnable and existing tasks.
+type Load struct {
+	One, Five, Fifteen float64
+	Running, Tasks     int
+}
+
+// ReadLoad reads /proc/loadavg.
+func ReadLoad() (Load, error) {
+	return readLoad("/proc")
+}
+
+func readLoad(proc string) (Load, error) {
+	data, err := os.ReadFile(path.Join(proc, "loadavg"))
+	if err != nil {
+		return Load{}, xerrors.Errorf("cannot read load average: %w", err)
+	}
+	var l Load
+	_, err = fmt.Sscanf(string(data), "%f %f %f %d/%d", &l.One, &l.Five, &l.Fifteen, &l.Running, &l.Tasks)
+	if err != nil {
+		return Load{}, xerrors.Errorf("cannot parse /proc/loadavg: %w", err)
+	}
+	return l, nil
+}
+
+// Battery is a battery of the system.
+type Battery struct {
+	Name string
+	// Capacity is the charge in percent
+	Capacity int
+	// Status is Charging, Discharging, Full or Not charging
+	Status string
+	// TimeLeft is how long until the battery is empty, or full when
+	// charging, 0 if unknown
+	TimeLeft time.Duration
+}
+
+// Batteries lists the batteries in /sys/class/power_supply.
+func Batteries() []Battery {
+	return batteries("/sys/class/power_supply")
+}
+
+func batteries(dir string) []Battery {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	found := []Battery{}
+	for _, e := range entries {
+		supply := path.Join(dir, e.Name())
+		if readString(path.Join(supply, "type")) != "Battery" {
+			continue
+		}
+		if present := readString(path.Join(supply, "present")); present == "0" {
+			continue
+		}
+		b := Battery{Name: e.Name(), Status: readString(path.Join(supply, "status"))}
+		b.Capacity, _ = strconv.Atoi(readString(path.Join(supply, "capacity")))
+		// Batteries report energy and power, or charge and current
+		now, full, rate := readUint(supply, "energy_now"), readUint(supply, "energy_full"), readUint(supply, "power_now")
+		if now == 0 {
+			now, full, rate = readUint(supply, "charge_now"), readUint(supply, "charge_full"), readUint(supply, "current_now")
+		}
+		if rate > 0 {
+			switch b.Status {
+			case "Discharging":
+				b.TimeLeft = time.Duration(float64(now) / float64(rate) * float64(time.Hour))
+			case "Charging":
+				if full > now {
+					b.TimeLeft = time.Duration(float64(full-now) / float64(rate) * float64(time.Hour))
+				}
+			}
+		}
+		found = append(found, b)
+	}
+	return found
+}
+
+// Interface is a network interface and what it transferred since boot.
+type Interface struct {
+	Name             string
+	Up               bool
+	RxBytes, TxBytes uint64
+}
+
+// Interfaces lists the network interfaces in /proc/net/dev, except loopback.
+func Interfaces() ([]Interface, error) {
+	return interfaces("/proc", "/sys/class/net")
+}
+
+func interfaces(proc, sysNet string) ([]Interface, error) {
+	data, err := os.ReadFile(path.Join(proc, "net", "dev"))
+	if err != nil {
+		return nil, xerrors.Errorf("cannot read network statistics: %w", err)
+	}
+	found := []Interface{}
+	for _, line := range strings.Split(string(data), "\n") {
+		name, counters, ok := strings.Cut(line, ":")
+		name = strings.TrimSpace(name)
+		fields := strings.Fields(counters)
+		if !ok || name == "lo" || len(fields) < 9 {
+			continue
+		}
+		i := Interface{Name: name}
+		i.RxBytes, _ = strconv.ParseUint(fields[0], 10, 64)
+		i.TxBytes, _ = strconv.ParseUint(fields[8], 10, 64)
+		// Tunnels often don't know their state
+		state := readString(path.Join(sysNet, name, "operstate"))
+		i.Up = state == "up" || state == "unknown"
+		found = append(found, i)
+	}
+	sort.Slice(found, func(a, b int) bool { return found[a].Name < found[b].Name })
+	return found, nil
+}
+
+// Disk is the usage of the filesystem holding Path, in bytes. Avail is what
+// unprivileged users can still use.
+type Disk struct {
+	Path               string
+	Total, Free, Avail uint64
+}
+
+// UsedPercent returns the percentage of the filesystem in use, as df does.
+func (d Disk) UsedPercent() float64 {
+	used := d.Total - d.Free
+	if used+d.Avail == 0 {
+		return 0
+	}
+	return 100 * float64(used) / float64(used+d.Avail)
+}
+
+// KeyboardLayout returns the XKB layout in use, such as "us" or "de", or ""
+// if it is unknown. It asks the X server when there is one, else reads the
+// system keyboard settings.
+func KeyboardLayout() string {
+	if layout := os.Getenv("XKB_DEFAULT_LAYOUT"); layout != "" {
+		return layout
+	}
+	if layout := xLayout(); layout != "" {
+		return layout
+	}
+	for _, f := range []struct{ file, key string }{
+		{"/etc/default/keyboard", "XKBLAYOUT"},
+		{"/etc/vconsole.conf", "XKBLAYOUT"},
+		{"/etc/vconsole.conf", "KEYMAP"},
+	} {
+		data, err := os.ReadFile(f.file)
+		if err != nil {
+			continue
+		}
+		if layout := shellVar(data, f.key); layout != "" {
+			return layout
+		}
+	}
+	return ""
+}
+
+// xLayout asks the X server for its keyboard layout.
+func xLayout() string {
+	if os.Getenv("DISPLAY") == "" {
+		return ""
+	}
+	out, err := exec.Command("setxkbmap", "-query").Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(shellVar(bytes.ReplaceAll(out, []byte(":"), []byte("=")), "layout"))
+}
+
+// shellVar returns the value of a KEY=value line of a shell style config
+// file, without quotes.
+func shellVar(data []byte, key string) string {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		name, value, ok := strings.Cut(strings.TrimSpace(scanner.Text()), "=")
+		if ok && name == key {
+			return strings.Trim(strings.TrimSpace(value), `"'`)
+		}
+	}
+	return ""
+}
+
+// FormatBytes returns n in a few characters, like 512B, 1.5K or 12G.
+func FormatBytes(n uint64) string {
+	const units = "KMGTPE"
+	if n < 1024 {
+		return fmt.Sprintf("%dB", n)
+	}
+	v := float64(n)
+	i := -1
+	for v >= 1024 && i < len(units)-1 {
+		v /= 1024
+		i += 1
+	}
+	if v < 10 {
+		return fmt.Sprintf("%.1f%c", v, units[i])
+	}
+	return fmt.Sprintf("%.0f%c", v, units[i])
+}
+
+func readString(file string) string {
+	data, _ := os.ReadFile(file)
+	return strings.TrimSpace(string(data))
+}
+
+func readUint(dir, name string) uint64 {
+	n, _ := strconv.ParseUint(readString(path.Join(dir, name)), 10, 64)
+	return n
+}
@@ -0,0 +1,195 @@
+package sysstat
+
+import (
+	"os"
+	"path"
+	"testing"
+	"time"
+)
+
+// writeFiles writes files of a fake /proc or /sys under a new directory.
+func writeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	root := t.TempDir()
+	for name, content := range files {
+		file := path.Join(root, name)
+		if err := os.MkdirAll(path.Dir(file), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+func TestCPU(t *testing.T) {
+	before := writeFiles(t, map[string]string{"stat": `cpu  100 0 50 800 50 0 0 0 0 0
+cpu0 50 0 25 400 25 0 0 0 0 0
+cpu1 50 0 25 400 25 0 0 0 0 0
+intr 1234
+`})
+	after := writeFiles(t, map[string]string{"stat": `cpu  200 0 100 900 50 0 0 0 0 0
+cpu0 125 0 50 450 25 0 0 0 0 0
+cpu1 125 0 50 450 25 0 0 0 0 0
+`})
+	prev, err := readCPU(before)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now, err := readCPU(after)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if now.Cores != 2 {
+		t.Errorf("got %d cores, want 2", now.Cores)
+	}
+	if usage := now.Usage(prev); usage != 60 {
+		t.Errorf("got usage %v, want 60", usage)
+	}
+	if usage := prev.Usage(prev); usage != 0 {
+		t.Errorf("got usage %v without time passing", usage)
+	}
+}
+
+func TestMemory(t *testing.T) {
+	proc := writeFiles(t, map[string]string{"meminfo": `MemTotal:        8000000 kB
+MemFree:         1000000 kB
+MemAvailable:    6000000 kB
+Buffers:          100000 kB
+Cached:          2000000 kB
+SwapTotal:       2000000 kB
+SwapFree:        1500000 kB
+`})
+	m, err := readMemory(proc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Memory{Total: 8000000 * 1024, Available: 6000000 * 1024, Buffers: 100000 * 1024, Cached: 2000000 * 1024, SwapTotal: 2000000 * 1024, SwapFree: 1500000 * 1024}
+	if m != want {
+		t.Fatalf("got %+v, want %+v", m, want)
+	}
+	if m.UsedPercent() != 25 {
+		t.Errorf("got %v%% used, want 25", m.UsedPercent())
+	}
+}
+
+func TestLoad(t *testing.T) {
+	proc := writeFiles(t, map[string]string{"loadavg": "0.52 1.50 2.25 3/1234 5678\n"})
+	l, err := readLoad(proc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (Load{0.52, 1.5, 2.25, 3, 1234}); l != want {
+		t.Fatalf("got %+v, want %+v", l, want)
+	}
+}
+
+func TestBatteries(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"AC/type":            "Mains\n",
+		"BAT0/type":          "Battery\n",
+		"BAT0/status":        "Discharging\n",
+		"BAT0/capacity":      "50\n",
+		"BAT0/energy_now":    "20000000\n",
+		"BAT0/energy_full":   "40000000\n",
+		"BAT0/power_now":     "10000000\n",
+		"BAT1/type":          "Battery\n",
+		"BAT1/status":        "Charging\n",
+		"BAT1/capacity":      "75\n",
+		"BAT1/charge_now":    "3000000\n",
+		"BAT1/charge_full":   "4000000\n",
+		"BAT1/current_now":   "2000000\n",
+		"BAT2/type":          "Battery\n",
+		"BAT2/present":       "0\n",
+		"hidpp_battery/type": "Battery\n",
+	})
+	got := batteries(dir)
+	want := []Battery{
+		{Name: "BAT0", Capacity: 50, Status: "Discharging", TimeLeft: 2 * time.Hour},
+		{Name: "BAT1", Capacity: 75, Status: "Charging", TimeLeft: 30 * time.Minute},
+		{Name: "hidpp_battery"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("got %+v, want %+v", got[i], want[i])
+		}
+	}
+}
+
+func TestInterfaces(t *testing.T) {
+	root := writeFiles(t, map[string]string{
+		"proc/net/dev": `Inter-|   Receive                                                |  Transmit
+ face |bytes    packets errs drop fifo frame compressed multicast|bytes    packets errs drop fifo colls carrier compressed
+    lo:  123456     100    0    0    0     0          0         0   123456     100    0    0    0     0       0          0
+wlan0: 5000000    4000    0    0    0     0          0         0  1000000    3000    0    0    0     0       0          0
+ eth0:       0       0    0    0    0     0          0         0        0       0    0    0    0     0       0          0
+  tun0:    2048      10    0    0    0     0          0         0     4096      10    0    0    0     0       0          0
+`,
+		"sys/wlan0/operstate": "up\n",
+		"sys/eth0/operstate":  "down\n",
+		"sys/tun0/operstate":  "unknown\n",
+	})
+	got, err := interfaces(path.Join(root, "proc"), path.Join(root, "sys"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Interface{
+		{Name: "eth0"},
+		{Name: "tun0", Up: true, RxBytes: 2048, TxBytes: 4096},
+		{Name: "wlan0", Up: true, RxBytes: 5000000, TxBytes: 1000000},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("got %+v, want %+v", got[i], want[i])
+		}
+	}
+}
+
+func TestDiskUsage(t *testing.T) {
+	d, err := DiskUsage(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Total == 0 || d.Free > d.Total || d.UsedPercent() < 0 || d.UsedPercent() > 100 {
+		t.Fatalf("implausible usage %+v", d)
+	}
+}
+
+func TestShellVar(t *testing.T) {
+	data := []byte(`# KEYBOARD CONFIGURATION FILE
+XKBMODEL="pc105"
+XKBLAYOUT="de"
+XKBVARIANT=""
+KEYMAP=fr
+`)
+	for key, want := range map[string]string{"XKBLAYOUT": "de", "KEYMAP": "fr", "XKBVARIANT": "", "MISSING": ""} {
+		if got := shellVar(data, key); got != want {
+			t.Errorf("%s: got %q, want %q", key, got, want)
+		}
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	for n, want := range map[uint64]string{
+		0:           "0B",
+		1023:        "1023B",
+		1536:        "1.5K",
+		20 << 20:    "20M",
+		3 << 30:     "3.0G",
+		1<<40 + 1:   "1.0T",
+		999 << 20:   "999M",
+		1023 << 20:  "1023M",
+		12345 << 30: "12T",
+	} {
+		if got := FormatBytes(n); got != want {
+			t.Errorf("FormatBytes(%d) = %q, want %q", n, got, want)
+		}
+	}
+}
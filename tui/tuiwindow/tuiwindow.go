@@ -1,19 +1,34 @@
 package tuiwindow
 
 import (
+	"fmt"
+	"io"
 	"log"
+	"os"
 	"os/exec"
 	"path"
+	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/gdamore/tcell/v2"
 	"github.com/snadrus/tuitop/deps/cterm"
 	"github.com/snadrus/tuitop/deps/cview"
 	"github.com/snadrus/tuitop/deps/tcellterm"
+	"github.com/snadrus/tuitop/tui/procs"
+	"github.com/snadrus/tuitop/tui/shellinteg"
+	"golang.org/x/sys/unix"
 )
 
 type TuiWindowCfg struct {
 	closeHandler func(exitStatus int)
+	windowOps    func(program string) bool
+	cellW, cellH int
+	dir          string
+	args         []string
+	conn         io.ReadWriteCloser
+	resize       func(w, h int)
+	inputCapture func(event *tcell.EventKey) *tcell.EventKey
 }
 
 func WithCloseHandler(f func(exitStatus int)) func(*TuiWindowCfg) {
@@ -22,38 +37,402 @@ func WithCloseHandler(f func(exitStatus int)) func(*TuiWindowCfg) {
 	}
 }
 
+// WithWindowOps lets the program resize, raise, minimize and maximize its
+// window with XTWINOPS if allow accepts its name. The program is the one in
+// the foreground of the window, else the one it was started with. It is off
+// by default.
+func WithWindowOps(allow func(program string) bool) func(*TuiWindowCfg) {
+	return func(w *TuiWindowCfg) {
+		w.windowOps = allow
+	}
+}
+
+// WithDir starts the program in dir. It is ignored if dir doesn't exist.
+func WithDir(dir string) func(*TuiWindowCfg) {
+	return func(w *TuiWindowCfg) {
+		w.dir = dir
+	}
+}
+
+// WithArgs passes arguments to the program.
+func WithArgs(args ...string) func(*TuiWindowCfg) {
+	return func(w *TuiWindowCfg) {
+		w.args = args
+	}
+}
+
+// WithConn makes the window a terminal for a program connected through conn
+// rather than a local command, which then only names the window. resize is
+// called with the size in columns and rows when it changes, and may be nil.
+func WithConn(conn io.ReadWriteCloser, resize func(w, h int)) func(*TuiWindowCfg) {
+	return func(w *TuiWindowCfg) {
+		w.conn = conn
+		w.resize = resize
+	}
+}
+
+// WithInputCapture passes keys to capture before the terminal gets them, for
+// window types with keyboard actions of their own. It returns nil for keys
+// it handled.
+func WithInputCapture(capture func(event *tcell.EventKey) *tcell.EventKey) func(*TuiWindowCfg) {
+	return func(w *TuiWindowCfg) {
+		w.inputCapture = capture
+	}
+}
+
+// WithCellSize sets the cell size in pixels reported to the program.
+func WithCellSize(width, height int) func(*TuiWindowCfg) {
+	return func(w *TuiWindowCfg) {
+		w.cellW, w.cellH = width, height
+	}
+}
+
 type CreateWindow func(cmd string, opts ...func(*TuiWindowCfg))
 
-func MkCreateWindow(wm *cview.WindowManager) CreateWindow {
+// FrameInterval is the shortest time between two desktop redraws.
+const FrameInterval = time.Second / 60
+
+// FrameLimiter coalesces redraw requests from all windows, so the desktop is
+// drawn at most once per interval however busy the windows are.
+type FrameLimiter struct {
+	draw     func()
+	interval time.Duration
+	pending  int32
+}
+
+func NewFrameLimiter(draw func(), interval time.Duration) *FrameLimiter {
+	return &FrameLimiter{draw: draw, interval: interval}
+}
+
+// Request schedules a redraw. Requests arriving before it happens are merged.
+func (f *FrameLimiter) Request() {
+	if !atomic.CompareAndSwapInt32(&f.pending, 0, 1) {
+		return
+	}
+	time.AfterFunc(f.interval, func() {
+		atomic.StoreInt32(&f.pending, 0)
+		f.draw()
+	})
+}
+
+// MkCreateWindow returns a CreateWindow adding windows to wm. redraw is called
+// when a window's content changed, and changes to windows asked for by their
+// programs are queued to app.
+func MkCreateWindow(app *cview.Application, wm *cview.WindowManager, redraw func()) CreateWindow {
 	return func(cmd string, opts ...func(*TuiWindowCfg)) {
 		cfg := &TuiWindowCfg{}
 		for _, opt := range opts {
 			opt(cfg)
 		}
-		cmdExec := exec.Command(cmd)
-		t := cterm.NewTerminal(cmdExec)
-
+		cmdExec := exec.Command(cmd, cfg.args...)
+		if st, err := os.Stat(cfg.dir); err == nil && st.IsDir() {
+			cmdExec.Dir = cfg.dir
+		}
+		var t *cterm.Terminal
+		if cfg.conn != nil {
+			t = cterm.NewTerminalIO(cfg.conn, cfg.resize)
+		} else {
+			if err := shellinteg.Setup(cmdExec); err != nil {
+				log.Printf("shell integration: %v", err)
+			}
+			t = cterm.NewTerminal(cmdExec)
+		}
+		t.SetCellSize(cfg.cellW, cfg.cellH)
+		t.SetLinkHandler(openLink)
+		if cfg.inputCapture != nil {
+			t.SetInputCapture(cfg.inputCapture)
+		}
 		w := cview.NewWindow(t)
+		t.SetFocusHandler(func() {
+			focused.Store(t)
+			if n := notifier.Load(); n != nil {
+				(*n).SetUrgent(w, false)
+			}
+		})
 		_, file := path.Split(cmd)
 		w.SetTitle(file)
 
+		term := &Terminal{Window: w, t: t}
+		if cfg.conn == nil {
+			addTerminal(term)
+		}
+		addWindow(managed{w: w, close: term.Close, term: term})
+		t.SetMenuHandler(func(x, y int) {
+			if m := menus.Load(); m != nil {
+				(*m).TerminalMenu(term, x, y)
+			}
+		})
+
 		windowWidth, windowHt := 54, 12
 		bestX, bestY := bestXY(wm, windowWidth, windowHt)
 		w.SetRect(bestX, bestY, windowWidth, windowHt)
 		wm.Add(w)
 		t.Attach(func(ev tcell.Event) {
-			switch ev.(type) {
+			switch ev := ev.(type) {
+			case *tcellterm.EventRedraw:
+				redraw()
+			case *tcellterm.EventWindow:
+				if cfg.windowOps != nil && cfg.windowOps(foregroundProgram(t, cmd)) {
+					app.QueueUpdateDraw(func() {
+						windowOp(wm, w, ev)
+					})
+				}
+			case *tcellterm.EventCommandDone:
+				if ev.Duration() >= LongCommand && !t.HasFocus() {
+					notify(w, t, "", commandDone(ev))
+					redraw()
+				}
+			case *tcellterm.EventNotify:
+				notify(w, t, ev.Title(), ev.Body())
+				redraw()
+			case *tcellterm.EventBell:
+				// A bell in the background asks for attention
+				if !t.HasFocus() {
+					notify(w, t, "", "bell")
+					redraw()
+				}
 			case *tcellterm.EventClosed:
 				log.Printf("closed")
 				if cfg.closeHandler != nil {
 					cfg.closeHandler(cmdExec.ProcessState.ExitCode())
 				}
+				focused.CompareAndSwap(t, nil)
+				removeTerminal(w)
+				RemoveWindow(w)
+				if n := notifier.Load(); n != nil {
+					(*n).SetUrgent(w, false)
+				}
 				wm.Remove(w)
 			}
 		})
 	}
 }
 
+// LongCommand is how long a command runs before its window notifies when it
+// finishes in the background.
+const LongCommand = 10 * time.Second
+
+// Notifier shows notifications from windows to the user.
+type Notifier interface {
+	Notify(title, body string)
+	// SetUrgent marks a window as wanting attention, until it gets focus.
+	SetUrgent(w *cview.Window, urgent bool)
+}
+
+// notifier is nil until set.
+var notifier atomic.Pointer[Notifier]
+
+// SetNotifier sets where notifications from windows go.
+func SetNotifier(n Notifier) {
+	notifier.Store(&n)
+}
+
+// notify shows a notification from w. Windows without focus are marked
+// urgent.
+func notify(w *cview.Window, t *cterm.Terminal, title, body string) {
+	n := notifier.Load()
+	if n == nil {
+		return
+	}
+	if title == "" {
+		title = w.GetTitle()
+	}
+	(*n).Notify(title, body)
+	if !t.HasFocus() {
+		(*n).SetUrgent(w, true)
+	}
+}
+
+func commandDone(ev *tcellterm.EventCommandDone) string {
+	if ev.Exit() != 0 {
+		return fmt.Sprintf("command failed with status %d after %s", ev.Exit(), ev.Duration().Round(time.Second))
+	}
+	return fmt.Sprintf("command finished after %s", ev.Duration().Round(time.Second))
+}
+
+// linkOpener is nil until set.
+var linkOpener atomic.Pointer[func(url string) error]
+
+// SetLinkOpener sets how links clicked in windows are opened.
+func SetLinkOpener(f func(url string) error) {
+	linkOpener.Store(&f)
+}
+
+func openLink(url string) {
+	f := linkOpener.Load()
+	if f == nil {
+		return
+	}
+	// Opening may install an app first
+	go func() {
+		if err := (*f)(url); err != nil {
+			log.Printf("cannot open %s: %v", url, err)
+			if n := notifier.Load(); n != nil {
+				(*n).Notify("Cannot open link", err.Error())
+			}
+		}
+	}()
+}
+
+// Terminal is a window running a program in a terminal. Terminals lists
+// those running local programs.
+type Terminal struct {
+	Window *cview.Window
+	t      *cterm.Terminal
+}
+
+// SelectedText returns the text selected with the mouse, or "".
+func (t *Terminal) SelectedText() string {
+	return t.t.SelectedText()
+}
+
+// Paste sends text to the program as if it was typed.
+func (t *Terminal) Paste(text string) {
+	t.t.Paste(text)
+}
+
+// Find opens the find bar of the window.
+func (t *Terminal) Find() {
+	t.t.Find()
+}
+
+// ClearScrollback removes the lines which scrolled off the window.
+func (t *Terminal) ClearScrollback() {
+	t.t.ClearScrollback()
+}
+
+// LinkAt returns the URL of the link drawn at x, y on the screen.
+func (t *Terminal) LinkAt(x, y int) (string, bool) {
+	return t.t.LinkAt(x, y)
+}
+
+// OpenLink opens url the way links clicked in windows are opened.
+func (t *Terminal) OpenLink(url string) {
+	openLink(url)
+}
+
+// WorkingDir returns the working directory of the program, or "".
+func (t *Terminal) WorkingDir() string {
+	return t.t.WorkingDir()
+}
+
+// Close hangs up the program, as closing a terminal does, or closes the
+// connection to a remote one.
+func (t *Terminal) Close() {
+	if pid := t.Pid(); pid > 0 && unix.Kill(pid, unix.SIGHUP) == nil {
+		return
+	}
+	t.t.Close()
+}
+
+// Kill kills the program, for those which ignore Close.
+func (t *Terminal) Kill() {
+	t.t.Close()
+}
+
+// Pid returns the process the window runs, usually a shell, or 0 before it
+// started.
+func (t *Terminal) Pid() int {
+	return t.t.Pid()
+}
+
+// ForegroundPgrp returns the process group in the foreground of the
+// window's pty, or 0 if it is unknown.
+func (t *Terminal) ForegroundPgrp() int {
+	return t.t.ForegroundPgrp()
+}
+
+var (
+	terminalsMu sync.Mutex
+	terminals   []*Terminal
+)
+
+func addTerminal(t *Terminal) {
+	terminalsMu.Lock()
+	defer terminalsMu.Unlock()
+	terminals = append(terminals, t)
+}
+
+func removeTerminal(w *cview.Window) {
+	terminalsMu.Lock()
+	defer terminalsMu.Unlock()
+	for i, t := range terminals {
+		if t.Window == w {
+			terminals = append(terminals[:i], terminals[i+1:]...)
+			return
+		}
+	}
+}
+
+// Terminals returns the open windows running local programs, oldest first.
+func Terminals() []*Terminal {
+	terminalsMu.Lock()
+	defer terminalsMu.Unlock()
+	return append([]*Terminal(nil), terminals...)
+}
+
+// focused is the terminal window which last had focus.
+var focused atomic.Pointer[cterm.Terminal]
+
+// FocusedDir returns the working directory of the terminal window which last
+// had focus, or "" if there is none.
+func FocusedDir() string {
+	t := focused.Load()
+	if t == nil {
+		return ""
+	}
+	return t.WorkingDir()
+}
+
+// foregroundProgram returns the name of the program in the foreground of t,
+// else of cmd.
+func foregroundProgram(t *cterm.Terminal, cmd string) string {
+	if pgrp := t.ForegroundPgrp(); pgrp > 0 {
+		if p, err := procs.Read(pgrp); err == nil {
+			return p.Name
+		}
+	}
+	return path.Base(cmd)
+}
+
+// windowOp carries out a window manipulation asked for by the program. It is
+// called on the UI goroutine.
+func windowOp(wm *cview.WindowManager, w *cview.Window, ev *tcellterm.EventWindow) {
+	switch ev.Op() {
+	case tcellterm.WindowDeiconify:
+		w.SetVisible(true)
+	case tcellterm.WindowIconify:
+		w.SetVisible(false)
+	case tcellterm.WindowRaise:
+		wm.Remove(w)
+		wm.Add(w)
+	case tcellterm.WindowResize:
+		cols, rows := ev.Size()
+		x, y, _, _ := w.GetRect()
+		_, _, screenW, screenH := wm.GetRect()
+		// Make room for the border
+		width, height := cols+2, rows+2
+		if cols == 0 || width > screenW {
+			width = screenW
+		}
+		if rows == 0 || height > screenH {
+			height = screenH
+		}
+		// Keep the window on the desktop
+		if x+width > screenW {
+			x = screenW - width
+		}
+		if y+height > screenH {
+			y = screenH - height
+		}
+		w.SetRect(x, y, width, height)
+	case tcellterm.WindowMaximize:
+		w.SetFullscreen(true)
+	case tcellterm.WindowUnmaximize:
+		w.SetFullscreen(false)
+	}
+}
+
 var location int64 = 0
 
 func bestXY(wm *cview.WindowManager, windowWidth, windowHt int) (x, y int) {
@@ -0,0 +1,112 @@
+package tuiwindow
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/snadrus/tuitop/deps/cview"
+)
+
+// Menus shows the context menus of windows.
+type Menus interface {
+	// WindowMenu shows the menu of w's title bar at x, y on the screen.
+	WindowMenu(w *cview.Window, x, y int)
+	// TerminalMenu shows the menu of the terminal window t at x, y, where
+	// it was right-clicked.
+	TerminalMenu(t *Terminal, x, y int)
+}
+
+// menus is nil until set.
+var menus atomic.Pointer[Menus]
+
+// SetMenus sets what shows the context menus of windows.
+func SetMenus(m Menus) {
+	menus.Store(&m)
+}
+
+// managed is a window of a program or app.
+type managed struct {
+	w     *cview.Window
+	close func()
+	// term is set for terminal windows
+	term *Terminal
+}
+
+var (
+	windowsMu sync.Mutex
+	windows   []managed
+)
+
+// AddWindow adds a window of a program or app to Windows. close is called
+// when the user closes it from its menu, which right-clicking its title bar
+// shows.
+func AddWindow(w *cview.Window, close func()) {
+	addWindow(managed{w: w, close: close})
+}
+
+func addWindow(m managed) {
+	windowsMu.Lock()
+	windows = append(windows, m)
+	windowsMu.Unlock()
+	w := m.w
+	w.SetMouseCapture(func(action cview.MouseAction, event *tcell.EventMouse) (cview.MouseAction, *tcell.EventMouse) {
+		_, y, _, _ := w.GetRect()
+		px, py := event.Position()
+		if action != cview.MouseRightClick || py != y {
+			return action, event
+		}
+		if m := menus.Load(); m != nil {
+			(*m).WindowMenu(w, px, py)
+		}
+		return action, nil
+	})
+}
+
+// RemoveWindow removes a window which closed from Windows.
+func RemoveWindow(w *cview.Window) {
+	windowsMu.Lock()
+	defer windowsMu.Unlock()
+	for i, m := range windows {
+		if m.w == w {
+			windows = append(windows[:i], windows[i+1:]...)
+			return
+		}
+	}
+}
+
+// Windows returns the windows of programs and apps, oldest first. Dialogs
+// and menus are left out.
+func Windows() []*cview.Window {
+	windowsMu.Lock()
+	defer windowsMu.Unlock()
+	found := []*cview.Window{}
+	for _, m := range windows {
+		found = append(found, m.w)
+	}
+	return found
+}
+
+func lookupWindow(w *cview.Window) (managed, bool) {
+	windowsMu.Lock()
+	defer windowsMu.Unlock()
+	for _, m := range windows {
+		if m.w == w {
+			return m, true
+		}
+	}
+	return managed{}, false
+}
+
+// CloseWindow closes a window of Windows the way its program or app wants.
+func CloseWindow(w *cview.Window) {
+	if m, ok := lookupWindow(w); ok && m.close != nil {
+		m.close()
+	}
+}
+
+// TerminalOf returns the terminal shown in w, if it is a terminal window.
+func TerminalOf(w *cview.Window) (*Terminal, bool) {
+	m, ok := lookupWindow(w)
+	return m.term, ok && m.term != nil
+}
@@ -0,0 +1,393 @@
+package tuiwm
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strconv"
+	"sync"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/snadrus/tuitop/deps/cview"
+	"github.com/snadrus/tuitop/tui/appproto"
+	"github.com/snadrus/tuitop/tui/tuiwindow"
+)
+
+// socketPath returns where the app protocol server listens.
+func socketPath() string {
+	dir := os.Getenv("XDG_RUNTIME_DIR")
+	if dir == "" {
+		dir = os.TempDir()
+	}
+	return path.Join(dir, fmt.Sprintf("tuitop-%d.sock", os.Getpid()))
+}
+
+// protoBackend shows the windows, tray items, dialogs and menus of programs
+// speaking the app protocol. Calls come from the connections' goroutines, so
+// changes are queued to the UI goroutine.
+type protoBackend struct {
+	l    *launcher
+	tray *Tray
+
+	mu      sync.Mutex
+	windows map[int]*protoWindow
+	// popups are the open dialogs and menus
+	popups map[int]*cview.Window
+}
+
+func newProtoBackend(l *launcher, tray *Tray) *protoBackend {
+	return &protoBackend{
+		l:       l,
+		tray:    tray,
+		windows: map[int]*protoWindow{},
+		popups:  map[int]*cview.Window{},
+	}
+}
+
+// protoWindow is a window of a program, built from widget descriptions.
+type protoWindow struct {
+	w       *cview.Window
+	content *cview.Flex
+	root    cview.Primitive
+	events  appproto.Sink
+	// widgets are the widgets with an ID
+	widgets map[string]*builtWidget
+}
+
+// builtWidget is a widget with an ID and where it is laid out.
+type builtWidget struct {
+	p      cview.Primitive
+	parent *cview.Flex
+	index  int
+	size   int
+	weight int
+}
+
+// queue runs f on the UI goroutine.
+func (b *protoBackend) queue(f func()) {
+	b.l.app.QueueUpdateDraw(f)
+}
+
+func (b *protoBackend) OpenWindow(id int, title string, width, height int, root appproto.Widget, events appproto.Sink) error {
+	content := cview.NewFlex()
+	w := cview.NewWindow(content)
+	w.SetTitle(title)
+	w.SetBorder(true)
+	pw := &protoWindow{w: w, content: content, events: events}
+	close := func() {
+		b.mu.Lock()
+		_, open := b.windows[id]
+		delete(b.windows, id)
+		b.mu.Unlock()
+		if open {
+			b.l.close(w)
+			events(appproto.CloseEvent())
+		}
+	}
+	// Escape closes the window
+	w.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() != tcell.KeyEscape {
+			return event
+		}
+		close()
+		return nil
+	})
+	b.mu.Lock()
+	b.windows[id] = pw
+	b.mu.Unlock()
+	b.queue(func() {
+		pw.setContent(root)
+		tuiwindow.AddWindow(w, close)
+		b.l.show(w, width, height)
+	})
+	return nil
+}
+
+func (b *protoBackend) window(id int, f func(pw *protoWindow)) {
+	b.mu.Lock()
+	pw, ok := b.windows[id]
+	b.mu.Unlock()
+	if ok {
+		b.queue(func() { f(pw) })
+	}
+}
+
+func (b *protoBackend) SetTitle(id int, title string) {
+	b.window(id, func(pw *protoWindow) { pw.w.SetTitle(title) })
+}
+
+func (b *protoBackend) SetContent(id int, root appproto.Widget) {
+	b.window(id, func(pw *protoWindow) { pw.setContent(root) })
+}
+
+func (b *protoBackend) UpdateWidget(id int, w appproto.Widget) {
+	b.window(id, func(pw *protoWindow) { pw.update(w) })
+}
+
+func (b *protoBackend) CloseWindow(id int) {
+	b.mu.Lock()
+	pw, ok := b.windows[id]
+	delete(b.windows, id)
+	b.mu.Unlock()
+	if ok {
+		b.queue(func() { b.l.close(pw.w) })
+	}
+}
+
+func (b *protoBackend) Notify(title, body string) {
+	b.l.center.Notify(title, body)
+}
+
+func (b *protoBackend) AddTray(id int, text string, events appproto.Sink) {
+	b.tray.add(id, text, func() { events(appproto.TrayClickEvent()) })
+	b.l.redraw()
+}
+
+func (b *protoBackend) SetTray(id int, text string) {
+	b.tray.set(id, text)
+	b.l.redraw()
+}
+
+func (b *protoBackend) RemoveTray(id int) {
+	b.tray.remove(id)
+	b.l.redraw()
+}
+
+func (b *protoBackend) ShowDialog(id int, title, text string, buttons []string, events appproto.Sink) {
+	modal := cview.NewModal()
+	modal.SetText(text)
+	modal.AddButtons(buttons)
+	dialog := cview.NewWindow(modal)
+	dialog.SetTitle(title)
+	dialog.SetBorder(true)
+	// Escape is reported as no button
+	modal.SetDoneFunc(func(buttonIndex int, buttonLabel string) {
+		if b.closePopup(id) {
+			events(appproto.DialogEvent(buttonLabel))
+		}
+	})
+	b.showPopup(id, dialog, 50, 10)
+}
+
+func (b *protoBackend) ShowMenu(id int, items []string, events appproto.Sink) {
+	list := cview.NewList()
+	width := 10
+	for _, text := range items {
+		list.AddItem(cview.NewListItem(text))
+		if len(text)+4 > width {
+			width = len(text) + 4
+		}
+	}
+	list.SetSelectedFunc(func(index int, item *cview.ListItem) {
+		if b.closePopup(id) {
+			events(appproto.MenuEvent(index))
+		}
+	})
+	list.SetDoneFunc(func() {
+		if b.closePopup(id) {
+			events(appproto.MenuEvent(-1))
+		}
+	})
+	menu := cview.NewWindow(list)
+	menu.SetBorder(true)
+	b.showPopup(id, menu, width, len(items)+2)
+}
+
+func (b *protoBackend) showPopup(id int, popup *cview.Window, width, height int) {
+	b.mu.Lock()
+	b.popups[id] = popup
+	b.mu.Unlock()
+	b.queue(func() { b.l.show(popup, width, height) })
+}
+
+// closePopup closes a dialog or menu, returning false if it was closed
+// already.
+func (b *protoBackend) closePopup(id int) bool {
+	b.mu.Lock()
+	popup, ok := b.popups[id]
+	delete(b.popups, id)
+	b.mu.Unlock()
+	if ok {
+		b.l.close(popup)
+	}
+	return ok
+}
+
+func (b *protoBackend) Dismiss(id int) {
+	b.queue(func() { b.closePopup(id) })
+}
+
+// setContent replaces the widgets of the window.
+func (pw *protoWindow) setContent(root appproto.Widget) {
+	if pw.root != nil {
+		pw.content.RemoveItem(pw.root)
+	}
+	pw.widgets = map[string]*builtWidget{}
+	pw.root = pw.build(root, pw.content, 0, 0, 1)
+	pw.content.AddItem(pw.root, 0, 1, true)
+}
+
+// update changes a widget in place, or builds it again.
+func (pw *protoWindow) update(spec appproto.Widget) {
+	bw, ok := pw.widgets[spec.ID]
+	if !ok {
+		return
+	}
+	switch p := bw.p.(type) {
+	case *cview.TextView:
+		p.SetText(spec.Text)
+		setTitle(p, spec.Title)
+		return
+	case *cview.List:
+		current := p.GetCurrentItemIndex()
+		p.Clear()
+		for _, text := range spec.Items {
+			p.AddItem(cview.NewListItem(text))
+		}
+		if current < len(spec.Items) {
+			p.SetCurrentItem(current)
+		}
+		setTitle(p, spec.Title)
+		return
+	case *cview.Table:
+		fillTable(p, spec.Rows)
+		setTitle(p, spec.Title)
+		return
+	}
+	parent := bw.parent
+	parent.RemoveItem(bw.p)
+	p := pw.build(spec, parent, bw.index, bw.size, bw.weight)
+	parent.AddItemAtIndex(bw.index, p, bw.size, bw.weight, true)
+}
+
+type titled interface {
+	SetBorder(show bool)
+	SetTitle(title string)
+}
+
+func setTitle(p titled, title string) {
+	p.SetBorder(title != "")
+	p.SetTitle(title)
+}
+
+// build makes the primitive of a widget laid out at index in parent.
+func (pw *protoWindow) build(spec appproto.Widget, parent *cview.Flex, index, size, weight int) cview.Primitive {
+	id := spec.ID
+	var p interface {
+		cview.Primitive
+		titled
+	}
+	switch spec.Type {
+	case appproto.WidgetText:
+		text := cview.NewTextView()
+		text.SetDynamicColors(true)
+		text.SetWrap(true)
+		text.SetText(spec.Text)
+		p = text
+	case appproto.WidgetList:
+		list := cview.NewList()
+		for _, text := range spec.Items {
+			list.AddItem(cview.NewListItem(text))
+		}
+		list.SetSelectedFunc(func(index int, item *cview.ListItem) {
+			pw.events(appproto.SelectEvent(id, index))
+		})
+		p = list
+	case appproto.WidgetTable:
+		table := cview.NewTable()
+		table.SetSelectable(true, false)
+		table.SetFixed(1, 0)
+		fillTable(table, spec.Rows)
+		table.SetSelectedFunc(func(row, column int) {
+			// Rows are numbered after the header
+			pw.events(appproto.SelectEvent(id, row-1))
+		})
+		p = table
+	case appproto.WidgetForm:
+		p = pw.buildForm(spec)
+	case appproto.WidgetFlex:
+		flex := cview.NewFlex()
+		if spec.Direction == "row" {
+			flex.SetDirection(cview.FlexRow)
+		}
+		for i, c := range spec.Children {
+			w := c.Weight
+			if c.Size == 0 && w == 0 {
+				w = 1
+			}
+			flex.AddItem(pw.build(c.Widget, flex, i, c.Size, w), c.Size, w, i == 0)
+		}
+		p = flex
+	default:
+		// Refused by the server
+		p = cview.NewTextView()
+	}
+	if spec.Title != "" {
+		setTitle(p, spec.Title)
+	}
+	if id != "" {
+		pw.widgets[id] = &builtWidget{p: p, parent: parent, index: index, size: size, weight: weight}
+	}
+	return p
+}
+
+func fillTable(table *cview.Table, rows [][]string) {
+	table.Clear()
+	for r, row := range rows {
+		for c, text := range row {
+			cell := cview.NewTableCell(text)
+			if r == 0 {
+				cell.SetTextColor(tcell.ColorYellow)
+				cell.SetSelectable(false)
+			}
+			cell.SetExpansion(1)
+			table.SetCell(r, c, cell)
+		}
+	}
+}
+
+func (pw *protoWindow) buildForm(spec appproto.Widget) *cview.Form {
+	form := cview.NewForm()
+	values := map[string]string{}
+	for _, f := range spec.Fields {
+		f := f
+		values[f.ID] = f.Value
+		set := func(text string) { values[f.ID] = text }
+		switch f.Type {
+		case appproto.FieldInput:
+			form.AddInputField(f.Label, f.Value, 0, nil, set)
+		case appproto.FieldPassword:
+			form.AddPasswordField(f.Label, f.Value, 0, '*', set)
+		case appproto.FieldCheckbox:
+			checked := f.Value == "true"
+			values[f.ID] = strconv.FormatBool(checked)
+			form.AddCheckBox(f.Label, "", checked, func(checked bool) {
+				values[f.ID] = strconv.FormatBool(checked)
+			})
+		case appproto.FieldDropdown:
+			initial := 0
+			for i, option := range f.Options {
+				if option == f.Value {
+					initial = i
+				}
+			}
+			if len(f.Options) > 0 {
+				values[f.ID] = f.Options[initial]
+			}
+			form.AddDropDownSimple(f.Label, initial, func(index int, option *cview.DropDownOption) {
+				values[f.ID] = f.Options[index]
+			}, f.Options...)
+		}
+	}
+	for _, label := range spec.Buttons {
+		label := label
+		form.AddButton(label, func() {
+			submitted := make(map[string]string, len(values))
+			for k, v := range values {
+				submitted[k] = v
+			}
+			pw.events(appproto.SubmitEvent(spec.ID, label, submitted))
+		})
+	}
+	return form
+}
@@ -0,0 +1,151 @@
+package tuiwm
+
+import (
+	"os"
+	"os/exec"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/snadrus/tuitop/apps"
+	"github.com/snadrus/tuitop/deps/cview"
+	"github.com/snadrus/tuitop/tui/clipboard"
+	"github.com/snadrus/tuitop/tui/config"
+	"github.com/snadrus/tuitop/tui/tuiwindow"
+	"golang.org/x/xerrors"
+)
+
+// desktopMenu shows the menu of the desktop at x, y.
+func (d *desktop) desktopMenu(x, y int) {
+	launch := []menuItem{}
+	for _, name := range apps.Names() {
+		launch = append(launch, menuItem{label: appName(name), action: func() {
+			if err := d.l.open(name); err != nil {
+				d.l.center.Notify("Cannot open "+appName(name), err.Error())
+			}
+		}})
+	}
+	items := []menuItem{
+		{label: "New shell", action: func() { AddShell(d.l.createWindow) }},
+		{label: "Launch", items: launch},
+		{label: "Pin to desktop", items: d.pinItems(x, y)},
+		{label: "Workspace", items: d.l.workspaceItems(d.l.spaces.current, d.l.spaces.switchTo)},
+		{label: "Arrange windows", action: d.l.spaces.arrange},
+		{label: "Settings", action: d.openSettings},
+	}
+	values := map[string]string{"dir": tuiwindow.FocusedDir()}
+	d.l.showMenu(x, y, append(items, d.l.appItems("desktop", values)...))
+}
+
+// workspaceItems offers the workspaces, marking current, calling choose with
+// the one chosen.
+func (l *launcher) workspaceItems(current int, choose func(n int)) []menuItem {
+	items := []menuItem{}
+	for n := 0; n < workspaceCount; n++ {
+		label := "  " + strconv.Itoa(n+1)
+		if n == current {
+			label = "• " + strconv.Itoa(n+1)
+		}
+		items = append(items, menuItem{label: label, action: func() { choose(n) }})
+	}
+	return items
+}
+
+// openSettings edits config.yaml with $VISUAL or $EDITOR, else nano or vi.
+func (d *desktop) openSettings() {
+	if err := d.editSettings(); err != nil {
+		d.l.center.Notify("Cannot open settings", err.Error())
+	}
+}
+
+func (d *desktop) editSettings() error {
+	dir, err := config.Dir()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return xerrors.Errorf("cannot create config folder: %w", err)
+	}
+	editor := strings.Fields(os.Getenv("VISUAL"))
+	if len(editor) == 0 {
+		editor = strings.Fields(os.Getenv("EDITOR"))
+	}
+	if len(editor) == 0 {
+		editor = []string{"nano"}
+		if _, err := exec.LookPath("nano"); err != nil {
+			editor = []string{"vi"}
+		}
+	}
+	cmd, err := exec.LookPath(editor[0])
+	if err != nil {
+		return xerrors.Errorf("no editor: %w", err)
+	}
+	d.l.createWindow(cmd, tuiwindow.WithArgs(append(editor[1:], path.Join(dir, "config.yaml"))...))
+	d.l.redraw()
+	return nil
+}
+
+// WindowMenu shows the menu of a title bar.
+func (d *desktop) WindowMenu(w *cview.Window, x, y int) {
+	spaces := d.l.spaces
+	spaces.settle()
+	onTop := "  Always on top"
+	if spaces.onTop[w] {
+		onTop = "✓ Always on top"
+	}
+	items := []menuItem{
+		{label: "Move to workspace", items: d.l.workspaceItems(spaces.on[w], func(n int) { spaces.move(w, n) })},
+		{label: onTop, action: func() { spaces.toggleOnTop(w) }},
+		{label: "Close", action: func() { tuiwindow.CloseWindow(w) }},
+	}
+	values := map[string]string{}
+	if t, ok := tuiwindow.TerminalOf(w); ok {
+		items = append(items, menuItem{label: "Kill", action: t.Kill})
+		values["dir"] = t.WorkingDir()
+	}
+	d.l.showMenu(x, y, append(items, d.l.appItems("window", values)...))
+}
+
+// TerminalMenu shows the menu of the inside of a terminal window, where the
+// program doesn't use the mouse.
+func (d *desktop) TerminalMenu(t *tuiwindow.Terminal, x, y int) {
+	selection := t.SelectedText()
+	link, onLink := t.LinkAt(x, y)
+	items := []menuItem{}
+	if selection != "" {
+		items = append(items, menuItem{label: "Copy", action: func() { d.copy(selection) }})
+	}
+	items = append(items,
+		menuItem{label: "Paste", action: func() { go d.paste(t) }},
+		menuItem{label: "Search", action: func() {
+			d.l.raise(t.Window)
+			t.Find()
+		}},
+		menuItem{label: "Clear scrollback", action: t.ClearScrollback},
+	)
+	if onLink {
+		items = append(items,
+			menuItem{label: "Open link", action: func() { t.OpenLink(link) }},
+			menuItem{label: "Copy link", action: func() { d.copy(link) }},
+		)
+	}
+	values := map[string]string{"link": link, "selection": selection, "dir": t.WorkingDir()}
+	d.l.showMenu(x, y, append(items, d.l.appItems("terminal", values)...))
+}
+
+func (d *desktop) copy(text string) {
+	if err := clipboard.Copy(text); err != nil {
+		d.l.center.Notify("Cannot copy", err.Error())
+	}
+}
+
+// paste types the clipboard into t. It runs the clipboard's command, so
+// isn't called on the UI goroutine.
+func (d *desktop) paste(t *tuiwindow.Terminal) {
+	text, err := clipboard.Paste()
+	if err != nil {
+		d.l.center.Notify("Cannot paste", err.Error())
+		return
+	}
+	t.Paste(text)
+}
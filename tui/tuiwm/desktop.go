@@ -0,0 +1,409 @@
+package tuiwm
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/mattn/go-runewidth"
+	"github.com/snadrus/tuitop/apps"
+	"github.com/snadrus/tuitop/deps/cview"
+	"github.com/snadrus/tuitop/tui/config"
+	"github.com/snadrus/tuitop/tui/wallpaper"
+)
+
+// iconWidth is the width of an icon's label and iconHeight its rows, the
+// emoji over the label.
+const iconWidth, iconHeight = 12, 2
+
+// hint is shown in the bottom right corner of the desktop, after the
+// workspace.
+const hint = "Ctrl-C exits."
+
+// icon is an app pinned to the desktop.
+type icon struct {
+	config.Icon
+	// emoji and label are from the app's name in the catalogue
+	emoji, label string
+}
+
+func newIcon(pinned config.Icon) *icon {
+	i := &icon{Icon: pinned}
+	i.emoji, i.label = splitName(appName(pinned.App))
+	return i
+}
+
+// appName returns the name of an app of the catalogue, such as
+// "🕒 Clock", or name itself if it isn't in the catalogue.
+func appName(name string) string {
+	y, err := readApp(name)
+	if err != nil || y.Name == "" {
+		return name
+	}
+	return y.Name
+}
+
+// splitName splits the name of an app into the emoji it starts with and the
+// rest. Names without an emoji get a question mark.
+func splitName(name string) (emoji, label string) {
+	first, rest, ok := strings.Cut(name, " ")
+	if ok && first != "" && first[0] >= utf8.RuneSelf {
+		return first, rest
+	}
+	return "❔", name
+}
+
+// desktop is the window manager drawn over the wallpaper and the icons of
+// pinned apps. Icons are dragged to arrange them, double-clicked to open
+// them and right-clicked for a menu. Right-clicking elsewhere shows the
+// desktop's menu.
+type desktop struct {
+	*cview.WindowManager
+	l *launcher
+	// wallpaper is only used while drawing
+	wallpaper *wallpaper.Wallpaper
+
+	mu       sync.Mutex
+	icons    []*icon
+	selected *icon
+	// dragging is the icon held by the mouse, at dragX, dragY from its
+	// corner, and dragged whether it moved
+	dragging     *icon
+	dragX, dragY int
+	dragged      bool
+	// moving is the icon moved with the arrow keys
+	moving *icon
+}
+
+func newDesktop(l *launcher, cfg config.Desktop) *desktop {
+	d := &desktop{WindowManager: l.wm, l: l}
+	// The wallpaper is drawn instead
+	l.wm.SetBackgroundTransparent(true)
+	var err error
+	d.wallpaper, err = wallpaper.Load(cfg.Wallpaper)
+	if err != nil {
+		log.Print(err)
+	}
+	pinned, err := config.LoadIcons()
+	if err != nil {
+		log.Print(err)
+	}
+	for _, p := range pinned {
+		d.icons = append(d.icons, newIcon(p))
+	}
+	return d
+}
+
+// place returns where i is drawn on a desktop width by height, moved to fit.
+func place(i *icon, width, height int) (x, y int) {
+	return max(min(i.X, width-iconWidth), 0), max(min(i.Y, height-iconHeight), 0)
+}
+
+// iconAt returns the icon at x, y from the desktop's corner, the one drawn
+// last if they overlap. d.mu must be held.
+func (d *desktop) iconAt(x, y int) *icon {
+	_, _, width, height := d.GetRect()
+	for n := len(d.icons) - 1; n >= 0; n-- {
+		ix, iy := place(d.icons[n], width, height)
+		if x >= ix && x < ix+iconWidth && y >= iy && y < iy+iconHeight {
+			return d.icons[n]
+		}
+	}
+	return nil
+}
+
+// save writes the icons to the config folder. d.mu must be held.
+func (d *desktop) save() {
+	pinned := []config.Icon{}
+	for _, i := range d.icons {
+		pinned = append(pinned, i.Icon)
+	}
+	go func() {
+		if err := config.SaveIcons(pinned); err != nil {
+			log.Print(err)
+		}
+	}()
+}
+
+func (d *desktop) Draw(screen tcell.Screen) {
+	x, y, width, height := d.GetRect()
+	cells := d.wallpaper.Render(width, height)
+	for row, line := range cells {
+		for col, c := range line {
+			screen.SetContent(x+col, y+row, c.Rune, nil, c.Style)
+		}
+	}
+	// under is the style of text written over the wallpaper at col, row
+	under := func(col, row int) tcell.Style {
+		_, bg, _ := cells[row][col].Style.Decompose()
+		return tcell.StyleDefault.Foreground(tcell.ColorWhite).Background(bg)
+	}
+	status := fmt.Sprintf("Workspace %d   %s", d.l.spaces.current+1, hint)
+	if width > len(status) && height > 0 {
+		col := width - len(status) - 1
+		for n, r := range status {
+			screen.SetContent(x+col+n, y+height-1, r, nil, under(col+n, height-1).Foreground(tcell.ColorGray))
+		}
+	}
+
+	d.mu.Lock()
+	for _, i := range d.icons {
+		if width < iconWidth || height < iconHeight {
+			break
+		}
+		ix, iy := place(i, width, height)
+		emoji := []rune(i.emoji)
+		col := ix + (iconWidth-runewidth.StringWidth(i.emoji))/2
+		screen.SetContent(x+col, y+iy, emoji[0], emoji[1:], under(col, iy))
+		label := runewidth.Truncate(i.label, iconWidth, "…")
+		col = ix + (iconWidth-runewidth.StringWidth(label))/2
+		for _, r := range label {
+			style := under(col, iy+1)
+			switch i {
+			case d.moving:
+				style = style.Background(tcell.ColorOlive)
+			case d.selected:
+				style = style.Background(ColorWindowsBlue)
+			}
+			screen.SetContent(x+col, y+iy+1, r, nil, style)
+			col += runewidth.RuneWidth(r)
+		}
+	}
+	d.mu.Unlock()
+
+	d.WindowManager.Draw(screen)
+}
+
+// MouseHandler gives the mouse to the windows, and to the icons where there
+// is no window. Pressing a button outside the open menus closes them.
+func (d *desktop) MouseHandler() func(action cview.MouseAction, event *tcell.EventMouse, setFocus func(p cview.Primitive)) (consumed bool, capture cview.Primitive) {
+	windows := d.WindowManager.MouseHandler()
+	return func(action cview.MouseAction, event *tcell.EventMouse, setFocus func(p cview.Primitive)) (consumed bool, capture cview.Primitive) {
+		d.mu.Lock()
+		dragging := d.dragging != nil
+		// Clicking elsewhere drops the icon moved with the keys
+		if d.moving != nil && action == cview.MouseLeftDown {
+			d.moving = nil
+			d.save()
+		}
+		d.mu.Unlock()
+		if dragging {
+			return d.drag(action, event)
+		}
+		px, py := event.Position()
+		down := action == cview.MouseLeftDown || action == cview.MouseMiddleDown || action == cview.MouseRightDown
+		if down && !d.l.inMenu(px, py) {
+			d.l.closeMenus()
+		}
+		if consumed, capture := windows(action, event, setFocus); consumed {
+			if down {
+				d.l.spaces.keepOnTop()
+			}
+			return consumed, capture
+		}
+		// Borders and title bars of windows are not the desktop
+		if !d.InRect(px, py) || d.l.covers(px, py) {
+			return false, nil
+		}
+		return d.click(action, event)
+	}
+}
+
+// click selects, drags, opens or shows the menu of the icon under the
+// mouse.
+func (d *desktop) click(action cview.MouseAction, event *tcell.EventMouse) (consumed bool, capture cview.Primitive) {
+	x, y, width, height := d.GetRect()
+	px, py := event.Position()
+	d.mu.Lock()
+	i := d.iconAt(px-x, py-y)
+	switch action {
+	case cview.MouseLeftDown:
+		d.selected = i
+		if i != nil {
+			ix, iy := place(i, width, height)
+			d.dragging, d.dragX, d.dragY, d.dragged = i, px-x-ix, py-y-iy, false
+		}
+		d.mu.Unlock()
+		d.l.redraw()
+		if i != nil {
+			return true, d
+		}
+		return true, nil
+	case cview.MouseLeftDoubleClick:
+		d.mu.Unlock()
+		if i != nil {
+			d.open(i)
+		}
+		return i != nil, nil
+	case cview.MouseRightClick:
+		d.selected = i
+		d.mu.Unlock()
+		if i != nil {
+			d.iconMenu(i, px, py)
+		} else {
+			d.desktopMenu(px, py)
+		}
+		return true, nil
+	}
+	d.mu.Unlock()
+	return false, nil
+}
+
+// drag moves the icon held by the mouse, until the button is released.
+func (d *desktop) drag(action cview.MouseAction, event *tcell.EventMouse) (consumed bool, capture cview.Primitive) {
+	x, y, width, height := d.GetRect()
+	px, py := event.Position()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	// Moves without the button held mean the release was missed
+	if action == cview.MouseLeftUp || (action == cview.MouseMove && event.Buttons()&tcell.Button1 == 0) {
+		if d.dragged {
+			d.save()
+		}
+		d.dragging = nil
+		d.l.redraw()
+		return true, nil
+	}
+	if action == cview.MouseMove {
+		i := d.dragging
+		i.X = max(min(px-x-d.dragX, width-iconWidth), 0)
+		i.Y = max(min(py-y-d.dragY, height-iconHeight), 0)
+		d.dragged = true
+		d.l.redraw()
+	}
+	return true, d
+}
+
+func (d *desktop) open(i *icon) {
+	if err := d.l.open(i.App); err != nil {
+		d.l.center.Notify("Cannot open "+i.label, err.Error())
+	}
+}
+
+// iconMenu shows what can be done with an icon.
+func (d *desktop) iconMenu(i *icon, x, y int) {
+	d.l.showMenu(x, y, []menuItem{
+		{label: "Open", action: func() { d.open(i) }},
+		{label: "Move", action: func() { d.startMoving(i) }},
+		{label: "Unpin", action: func() { d.unpin(i) }},
+	})
+}
+
+// pinItems are the apps of the catalogue which are not pinned, pinning the
+// one chosen at x, y.
+func (d *desktop) pinItems(x, y int) []menuItem {
+	d.mu.Lock()
+	pinned := map[string]bool{}
+	for _, i := range d.icons {
+		pinned[i.App] = true
+	}
+	d.mu.Unlock()
+	items := []menuItem{}
+	for _, name := range apps.Names() {
+		if pinned[name] {
+			continue
+		}
+		items = append(items, menuItem{label: appName(name), action: func() { d.pin(name, x, y) }})
+	}
+	return items
+}
+
+// pin adds an icon for the app of the catalogue named name at x, y on the
+// screen.
+func (d *desktop) pin(name string, x, y int) {
+	dx, dy, _, _ := d.GetRect()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	i := newIcon(config.Icon{App: name, X: x - dx - iconWidth/2, Y: y - dy})
+	d.icons = append(d.icons, i)
+	d.selected = i
+	d.save()
+	d.l.redraw()
+}
+
+func (d *desktop) unpin(i *icon) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for n, pinned := range d.icons {
+		if pinned == i {
+			d.icons = append(d.icons[:n], d.icons[n+1:]...)
+			break
+		}
+	}
+	d.save()
+	d.l.redraw()
+}
+
+// startMoving moves i with the arrow keys until Enter or Escape.
+func (d *desktop) startMoving(i *icon) {
+	d.mu.Lock()
+	d.moving, d.selected = i, i
+	d.mu.Unlock()
+	d.l.app.SetFocus(d)
+	d.l.redraw()
+}
+
+// Focus keeps the keys on the desktop while an icon is moved, and otherwise
+// gives them to the window in front.
+func (d *desktop) Focus(delegate func(p cview.Primitive)) {
+	d.mu.Lock()
+	moving := d.moving != nil
+	d.mu.Unlock()
+	if moving {
+		d.WindowManager.Box.Focus(delegate)
+		return
+	}
+	d.WindowManager.Focus(delegate)
+}
+
+func (d *desktop) InputHandler() func(event *tcell.EventKey, setFocus func(p cview.Primitive)) {
+	windows := d.WindowManager.InputHandler()
+	return func(event *tcell.EventKey, setFocus func(p cview.Primitive)) {
+		if d.moveKey(event) {
+			if !d.isMoving() {
+				setFocus(d)
+			}
+			d.l.redraw()
+			return
+		}
+		if windows != nil {
+			windows(event, setFocus)
+		}
+	}
+}
+
+func (d *desktop) isMoving() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.moving != nil
+}
+
+// moveKey moves the icon being moved, returning whether it used the key.
+func (d *desktop) moveKey(event *tcell.EventKey) bool {
+	_, _, width, height := d.GetRect()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	i := d.moving
+	if i == nil {
+		return false
+	}
+	i.X, i.Y = place(i, width, height)
+	switch event.Key() {
+	case tcell.KeyLeft:
+		i.X--
+	case tcell.KeyRight:
+		i.X++
+	case tcell.KeyUp:
+		i.Y--
+	case tcell.KeyDown:
+		i.Y++
+	case tcell.KeyEnter, tcell.KeyEscape:
+		d.moving = nil
+		d.save()
+	}
+	i.X, i.Y = place(i, width, height)
+	return true
+}
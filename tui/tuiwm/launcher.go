@@ -0,0 +1,124 @@
+package tuiwm
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/snadrus/tuitop/apps"
+	"github.com/snadrus/tuitop/deps/cview"
+	"github.com/snadrus/tuitop/tui/installer"
+	"github.com/snadrus/tuitop/tui/notify"
+	"github.com/snadrus/tuitop/tui/tuiwindow"
+	"golang.org/x/xerrors"
+)
+
+// launcher shows the dialogs opening windows which are not local programs,
+// such as SSH sessions and serial consoles.
+type launcher struct {
+	app          *cview.Application
+	redraw       func()
+	wm           *cview.WindowManager
+	createWindow tuiwindow.CreateWindow
+	center       *notify.Center
+	inst         *installer.Installer
+	spaces       *workspaces
+	// menus are the open context menu and its open submenus, used on the UI
+	// goroutine
+	menus []*cview.Window
+
+	mu sync.Mutex
+	// shown are the dialogs and windows opened with show
+	shown []*cview.Window
+}
+
+// show centers dialog on the desktop and focuses it.
+func (l *launcher) show(dialog *cview.Window, width, height int) {
+	_, _, screenW, screenH := l.wm.GetRect()
+	dialog.SetRect((screenW-width)/2, (screenH-height)/2, width, height)
+	l.mu.Lock()
+	l.shown = append(l.shown, dialog)
+	l.mu.Unlock()
+	l.wm.Add(dialog)
+	l.app.SetFocus(dialog)
+	l.redraw()
+}
+
+func (l *launcher) close(dialog *cview.Window) {
+	l.mu.Lock()
+	for i, w := range l.shown {
+		if w == dialog {
+			l.shown = append(l.shown[:i], l.shown[i+1:]...)
+			break
+		}
+	}
+	l.mu.Unlock()
+	tuiwindow.RemoveWindow(dialog)
+	l.wm.Remove(dialog)
+	l.redraw()
+}
+
+// covers returns whether a window or menu is drawn at x, y on the screen.
+func (l *launcher) covers(x, y int) bool {
+	l.mu.Lock()
+	windows := append(tuiwindow.Windows(), l.shown...)
+	l.mu.Unlock()
+	for _, w := range append(windows, l.menus...) {
+		if w.GetVisible() && w.InRect(x, y) {
+			return true
+		}
+	}
+	return false
+}
+
+// raise brings w to the front, switching to its workspace, and focuses it.
+func (l *launcher) raise(w *cview.Window) {
+	l.spaces.reveal(w)
+	w.SetVisible(true)
+	l.wm.Remove(w)
+	l.wm.Add(w)
+	l.spaces.keepOnTop()
+	l.app.SetFocus(w)
+	l.redraw()
+}
+
+// readApp returns the definition of the app of the catalogue named name.
+func readApp(name string) (installer.InstallerYaml, error) {
+	def, err := apps.Open(name)
+	if err != nil {
+		return installer.InstallerYaml{}, xerrors.Errorf("no app %s: %w", name, err)
+	}
+	defer def.Close()
+	return installer.ReadYaml(def)
+}
+
+// open starts an app from the catalogue, installing it first if needed,
+// adding args to its command line. Installing runs in the background,
+// failures showing as notifications.
+func (l *launcher) open(name string, args ...string) error {
+	y, err := readApp(name)
+	if err != nil {
+		return err
+	}
+	switch y.Window {
+	case "":
+	case "ssh":
+		l.openSSH()
+		return nil
+	case "serial":
+		l.openSerial()
+		return nil
+	default:
+		return l.Launch(y.Window)
+	}
+	go func() {
+		path, err := l.inst.EnsureYaml(y)
+		if err != nil {
+			l.center.Notify("Cannot open "+y.Name, err.Error())
+			return
+		}
+		l.app.QueueUpdateDraw(func() {
+			l.createWindow(path, tuiwindow.WithArgs(append(strings.Fields(y.CLI)[1:], args...)...))
+		})
+	}()
+	return nil
+}
@@ -0,0 +1,173 @@
+package tuiwm
+
+import (
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/mattn/go-runewidth"
+	"github.com/snadrus/tuitop/apps"
+	"github.com/snadrus/tuitop/deps/cview"
+)
+
+// menuItem is an entry of a context menu, running action when chosen or,
+// if it has items, opening them as a submenu.
+type menuItem struct {
+	label  string
+	action func()
+	items  []menuItem
+}
+
+// showMenu opens a context menu at x, y on the screen, moved to fit, closing
+// any other. Up and Down move through the items, Enter chooses one, Right
+// opens a submenu, Left closes it and Escape closes the menu. Choosing an
+// item closes the menu before running its action.
+func (l *launcher) showMenu(x, y int, items []menuItem) {
+	l.closeMenus()
+	if len(items) > 0 {
+		l.openMenu(x, y, items)
+	}
+}
+
+// openMenu opens a menu in front of the open ones.
+func (l *launcher) openMenu(x, y int, items []menuItem) {
+	level := len(l.menus)
+	list := cview.NewList()
+	width := 10
+	for _, item := range items {
+		label := item.label
+		if item.items != nil {
+			label += " ▸"
+		}
+		list.AddItem(cview.NewListItem(label))
+		width = max(width, runewidth.StringWidth(label)+4)
+	}
+	menu := cview.NewWindow(list)
+	menu.SetBorder(true)
+	height := len(items) + 2
+	_, _, screenW, screenH := l.wm.GetRect()
+	menu.SetRect(max(min(x, screenW-width), 0), max(min(y, screenH-height), 0), width, height)
+
+	choose := func(index int) {
+		item := items[index]
+		if item.items == nil {
+			l.closeMenus()
+			item.action()
+			return
+		}
+		l.closeMenusFrom(level + 1)
+		if len(item.items) > 0 {
+			mx, my, mw, _ := menu.GetRect()
+			l.openMenu(mx+mw, my+1+index, item.items)
+		}
+	}
+	list.SetSelectedFunc(func(index int, _ *cview.ListItem) {
+		choose(index)
+	})
+	list.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch event.Key() {
+		case tcell.KeyRight:
+			if index := list.GetCurrentItemIndex(); items[index].items != nil {
+				choose(index)
+			}
+			return nil
+		case tcell.KeyLeft:
+			if level > 0 {
+				l.closeMenusFrom(level)
+			}
+			return nil
+		case tcell.KeyEscape:
+			l.closeMenusFrom(level)
+			return nil
+		}
+		return event
+	})
+	l.menus = append(l.menus, menu)
+	l.wm.Add(menu)
+	l.app.SetFocus(menu)
+	l.redraw()
+}
+
+// closeMenus closes the open menus, focusing the window in front.
+func (l *launcher) closeMenus() {
+	l.closeMenusFrom(0)
+}
+
+// closeMenusFrom closes the menus opened at level and after, focusing the
+// menu left in front or else the window in front.
+func (l *launcher) closeMenusFrom(level int) {
+	if level >= len(l.menus) {
+		return
+	}
+	l.wm.Remove(l.menus[level:]...)
+	l.menus = l.menus[:level]
+	if level > 0 {
+		l.app.SetFocus(l.menus[level-1])
+	} else {
+		l.app.SetFocus(l.wm)
+	}
+	l.redraw()
+}
+
+// inMenu returns whether x, y on the screen is in an open menu.
+func (l *launcher) inMenu(x, y int) bool {
+	for _, menu := range l.menus {
+		if menu.InRect(x, y) {
+			return true
+		}
+	}
+	return false
+}
+
+// placeholders are replaced in the arguments of the catalogue's menu items.
+var placeholders = []string{"link", "selection", "dir"}
+
+// appItems returns the items apps of the catalogue add to the menu named
+// menu, with placeholders in their arguments replaced from values.
+func (l *launcher) appItems(menu string, values map[string]string) []menuItem {
+	items := []menuItem{}
+	for _, name := range apps.Names() {
+		y, err := readApp(name)
+		if err != nil {
+			continue
+		}
+		for _, entry := range y.Menus {
+			if entry.Menu != menu {
+				continue
+			}
+			args, ok := expand(entry.Args, values)
+			if !ok {
+				continue
+			}
+			label := entry.Label
+			if label == "" {
+				label = y.Name
+			}
+			items = append(items, menuItem{label: label, action: func() {
+				if err := l.open(name, args...); err != nil {
+					l.center.Notify("Cannot open "+y.Name, err.Error())
+				}
+			}})
+		}
+	}
+	return items
+}
+
+// expand replaces the placeholders in args, returning false if one used has
+// no value, or one starting with "-" which the app could take for an option.
+func expand(args []string, values map[string]string) ([]string, bool) {
+	expanded := []string{}
+	for _, arg := range args {
+		for _, name := range placeholders {
+			placeholder := "{" + name + "}"
+			if !strings.Contains(arg, placeholder) {
+				continue
+			}
+			if values[name] == "" || strings.HasPrefix(values[name], "-") {
+				return nil, false
+			}
+			arg = strings.ReplaceAll(arg, placeholder, values[name])
+		}
+		expanded = append(expanded, arg)
+	}
+	return expanded, true
+}
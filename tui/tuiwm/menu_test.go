@@ -0,0 +1,32 @@
+package tuiwm
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExpand(t *testing.T) {
+	values := map[string]string{"link": "https://example.com/?a=1", "dir": "/home/me", "selection": ""}
+	for _, test := range []struct {
+		args   []string
+		values map[string]string
+		want   []string
+	}{
+		{nil, values, []string{}},
+		{[]string{"--new-window", "{link}"}, values, []string{"--new-window", "https://example.com/?a=1"}},
+		{[]string{"--dir={dir}", "{dir}/{dir}"}, values, []string{"--dir=/home/me", "/home/me//home/me"}},
+		// Unknown placeholders are left as they are
+		{[]string{"{url}"}, values, []string{"{url}"}},
+		// Items needing a value which is missing or looks like an option
+		// are left out
+		{[]string{"{selection}"}, values, nil},
+		{[]string{"{link}"}, map[string]string{}, nil},
+		{[]string{"{link}"}, map[string]string{"link": "--help"}, nil},
+		{[]string{"--select={selection}"}, map[string]string{"selection": "-rf"}, nil},
+	} {
+		got, ok := expand(test.args, test.values)
+		if ok != (test.want != nil) || ok && !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%q with %v: got %q, %v", test.args, test.values, got, ok)
+		}
+	}
+}
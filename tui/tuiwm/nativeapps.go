@@ -0,0 +1,137 @@
+package tuiwm
+
+import (
+	"os/exec"
+	"sync"
+
+	"github.com/snadrus/tuitop/deps/cview"
+	"github.com/snadrus/tuitop/tui/clipboard"
+	"github.com/snadrus/tuitop/tui/nativeapp"
+	_ "github.com/snadrus/tuitop/tui/nativeapp/tasks"
+	"github.com/snadrus/tuitop/tui/tuiwindow"
+	"golang.org/x/xerrors"
+)
+
+// appWindow is the window of a native app.
+type appWindow struct {
+	l       *launcher
+	w       *cview.Window
+	content *cview.Flex
+	root    cview.Primitive
+
+	mu      sync.Mutex
+	onClose func()
+	closed  bool
+}
+
+func (a *appWindow) SetTitle(title string) {
+	a.w.SetTitle(title)
+	a.l.redraw()
+}
+
+func (a *appWindow) SetRoot(root cview.Primitive) {
+	if a.root != nil {
+		a.content.RemoveItem(a.root)
+	}
+	a.root = root
+	a.content.AddItem(root, 0, 1, true)
+	a.l.redraw()
+}
+
+func (a *appWindow) Resize(width, height int) {
+	x, y, _, _ := a.w.GetRect()
+	a.w.SetRect(x, y, width, height)
+	a.l.redraw()
+}
+
+func (a *appWindow) Size() (int, int) {
+	_, _, width, height := a.w.GetRect()
+	return width, height
+}
+
+func (a *appWindow) Close() {
+	a.mu.Lock()
+	if a.closed {
+		a.mu.Unlock()
+		return
+	}
+	a.closed = true
+	onClose := a.onClose
+	a.mu.Unlock()
+	a.l.close(a.w)
+	if onClose != nil {
+		onClose()
+	}
+}
+
+func (a *appWindow) SetCloseHandler(f func()) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.onClose = f
+}
+
+// startApp opens a new window running the native app.
+func (l *launcher) startApp(app nativeapp.App) error {
+	info := app.Info()
+	content := cview.NewFlex()
+	w := cview.NewWindow(content)
+	w.SetTitle(info.Title)
+	w.SetBorder(true)
+	aw := &appWindow{l: l, w: w, content: content}
+	root, err := app.Start(aw, l)
+	if err != nil {
+		return xerrors.Errorf("cannot start %s: %w", info.Title, err)
+	}
+	aw.SetRoot(root)
+	tuiwindow.AddWindow(w, aw.Close)
+	l.show(w, info.Width, info.Height)
+	return nil
+}
+
+func (l *launcher) Notify(title, body string) {
+	l.center.Notify(title, body)
+}
+
+func (l *launcher) Copy(text string) error {
+	return clipboard.Copy(text)
+}
+
+// Launch starts a native app, or else runs a program in a terminal window.
+func (l *launcher) Launch(name string, args ...string) error {
+	if app, ok := nativeapp.Lookup(name); ok {
+		return l.startApp(app)
+	}
+	path, err := exec.LookPath(name)
+	if err != nil {
+		return xerrors.Errorf("cannot launch %s: %w", name, err)
+	}
+	l.createWindow(path, tuiwindow.WithArgs(args...))
+	l.redraw()
+	return nil
+}
+
+func (l *launcher) Redraw() {
+	l.redraw()
+}
+
+func (l *launcher) Terminals() []nativeapp.Terminal {
+	found := []nativeapp.Terminal{}
+	for _, t := range tuiwindow.Terminals() {
+		found = append(found, terminal{t, l})
+	}
+	return found
+}
+
+// terminal is a terminal window shown to native apps.
+type terminal struct {
+	*tuiwindow.Terminal
+	l *launcher
+}
+
+func (t terminal) Title() string {
+	return t.Window.GetTitle()
+}
+
+func (t terminal) Raise() {
+	t.l.raise(t.Window)
+}
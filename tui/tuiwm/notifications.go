@@ -0,0 +1,224 @@
+package tuiwm
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/snadrus/tuitop/deps/cview"
+	"github.com/snadrus/tuitop/tui/notify"
+)
+
+// ToastTime is how long a notification is shown over the desktop.
+const ToastTime = 6 * time.Second
+
+const (
+	toastWidth, toastHeight     = 36, 4
+	historyWidth, historyHeight = 50, 14
+)
+
+// desktopNotifier shows notifications as toasts in the bottom right corner of
+// the desktop and marks urgent windows in the taskbar.
+type desktopNotifier struct {
+	app       *cview.Application
+	redraw    func()
+	wm        *cview.WindowManager
+	center    *notify.Center
+	drawer    *cview.TextView
+	indicator *cview.TextView
+
+	mu      sync.Mutex
+	toasts  []*cview.Window
+	urgent  []*cview.Window
+	history *cview.Window
+}
+
+// newDesktopNotifier returns a notifier for the desktop. redraw must be safe to
+// call from event handlers.
+func newDesktopNotifier(app *cview.Application, redraw func(), wm *cview.WindowManager, center *notify.Center, drawer *cview.TextView) *desktopNotifier {
+	d := &desktopNotifier{
+		app:       app,
+		redraw:    redraw,
+		wm:        wm,
+		center:    center,
+		drawer:    drawer,
+		indicator: cview.NewTextView(),
+	}
+	d.indicator.SetBackgroundColor(Light(ColorWindowsBlue, 4))
+	d.indicator.SetTextColor(tcell.ColorBlack)
+	d.indicator.SetMouseCapture(func(action cview.MouseAction, event *tcell.EventMouse) (cview.MouseAction, *tcell.EventMouse) {
+		switch action {
+		case cview.MouseLeftClick:
+			d.toggleHistory()
+		case cview.MouseRightClick:
+			center.SetDND(!center.DND())
+		}
+		return action, event
+	})
+	d.drawer.SetDynamicColors(true)
+	d.drawer.SetMouseCapture(func(action cview.MouseAction, event *tcell.EventMouse) (cview.MouseAction, *tcell.EventMouse) {
+		if action == cview.MouseLeftClick {
+			d.raiseUrgent()
+		}
+		return action, event
+	})
+	center.SetShowHandler(d.showToast)
+	center.SetChangedHandler(d.updateIndicator)
+	d.updateIndicator()
+	return d
+}
+
+func (d *desktopNotifier) Notify(title, body string) {
+	d.center.Notify(title, body)
+}
+
+// SetUrgent marks w in the taskbar and colors its border.
+func (d *desktopNotifier) SetUrgent(w *cview.Window, urgent bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for i, u := range d.urgent {
+		if u == w {
+			d.urgent = append(d.urgent[:i], d.urgent[i+1:]...)
+			break
+		}
+	}
+	if urgent {
+		d.urgent = append(d.urgent, w)
+		w.SetBorderColor(tcell.ColorYellow)
+	} else {
+		w.SetBorderColor(cview.Styles.BorderColor)
+	}
+	var text strings.Builder
+	for _, u := range d.urgent {
+		fmt.Fprintf(&text, " [black:yellow] %s [-:-]", cview.Escape(u.GetTitle()))
+	}
+	d.drawer.SetText(text.String())
+}
+
+// raiseUrgent brings the window which first asked for attention to the front.
+func (d *desktopNotifier) raiseUrgent() {
+	d.mu.Lock()
+	if len(d.urgent) == 0 {
+		d.mu.Unlock()
+		return
+	}
+	w := d.urgent[0]
+	d.mu.Unlock()
+	w.SetVisible(true)
+	d.wm.Remove(w)
+	d.wm.Add(w)
+	// Focusing the window clears its urgency
+	d.app.SetFocus(w)
+}
+
+// updateIndicator shows the unread count or do-not-disturb in the tray.
+func (d *desktopNotifier) updateIndicator() {
+	switch unread := d.center.Unread(); {
+	case d.center.DND():
+		d.indicator.SetText(" DND")
+	case unread > 0:
+		d.indicator.SetText(fmt.Sprintf(" ✉%d", unread))
+	default:
+		d.indicator.SetText(" ✉")
+	}
+	d.redraw()
+}
+
+// showToast shows n over the desktop for ToastTime. It is called from event
+// handlers, so the toast is added on the UI goroutine.
+func (d *desktopNotifier) showToast(n notify.Notification) {
+	d.app.QueueUpdateDraw(func() {
+		text := cview.NewTextView()
+		text.SetText(n.Body)
+		text.SetWrap(true)
+		toast := cview.NewWindow(text)
+		toast.SetTitle(n.Title)
+		toast.SetBorder(true)
+		toast.SetMouseCapture(func(action cview.MouseAction, event *tcell.EventMouse) (cview.MouseAction, *tcell.EventMouse) {
+			if action == cview.MouseLeftClick {
+				d.removeToast(toast)
+				return 0, nil
+			}
+			return action, event
+		})
+
+		d.mu.Lock()
+		d.toasts = append(d.toasts, toast)
+		d.placeToasts()
+		d.mu.Unlock()
+		d.wm.Add(toast)
+		time.AfterFunc(ToastTime, func() {
+			d.removeToast(toast)
+		})
+	})
+}
+
+// removeToast closes toast, on the UI goroutine, moving the others down.
+func (d *desktopNotifier) removeToast(toast *cview.Window) {
+	d.app.QueueUpdateDraw(func() {
+		d.mu.Lock()
+		found := false
+		for i, t := range d.toasts {
+			if t == toast {
+				d.toasts = append(d.toasts[:i], d.toasts[i+1:]...)
+				found = true
+				break
+			}
+		}
+		d.placeToasts()
+		d.mu.Unlock()
+		if found {
+			d.wm.Remove(toast)
+		}
+	})
+}
+
+// placeToasts stacks the toasts upwards from the bottom right corner, newest
+// at the bottom.
+func (d *desktopNotifier) placeToasts() {
+	_, _, screenW, screenH := d.wm.GetRect()
+	y := screenH
+	for i := len(d.toasts) - 1; i >= 0; i -= 1 {
+		y -= toastHeight
+		d.toasts[i].SetRect(screenW-toastWidth, y, toastWidth, toastHeight)
+	}
+}
+
+// toggleHistory opens or closes the panel listing past notifications.
+func (d *desktopNotifier) toggleHistory() {
+	d.mu.Lock()
+	panel := d.history
+	d.history = nil
+	d.mu.Unlock()
+	if panel != nil {
+		d.wm.Remove(panel)
+		d.redraw()
+		return
+	}
+
+	var text strings.Builder
+	history := d.center.History()
+	if len(history) == 0 {
+		text.WriteString("No notifications")
+	}
+	for _, n := range history {
+		fmt.Fprintf(&text, "%s [::b]%s[::-] %s\n", n.When.Format("15:04"), cview.Escape(n.Title), cview.Escape(n.Body))
+	}
+	view := cview.NewTextView()
+	view.SetDynamicColors(true)
+	view.SetScrollable(true)
+	view.SetText(text.String())
+	panel = cview.NewWindow(view)
+	panel.SetTitle("Notifications")
+	panel.SetBorder(true)
+	_, _, screenW, screenH := d.wm.GetRect()
+	panel.SetRect(screenW-historyWidth, screenH-historyHeight, historyWidth, historyHeight)
+
+	d.mu.Lock()
+	d.history = panel
+	d.mu.Unlock()
+	d.wm.Add(panel)
+	d.app.SetFocus(panel)
+}
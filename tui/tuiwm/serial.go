@@ -0,0 +1,150 @@
+package tuiwm
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/snadrus/tuitop/deps/cview"
+	"github.com/snadrus/tuitop/tui/serialterm"
+	"github.com/snadrus/tuitop/tui/tuiwindow"
+)
+
+const serialDialogWidth, serialDialogHeight = 50, 16
+
+// openSerial shows the dialog picking a serial device and its settings.
+// Devices found in /sys/class/tty are listed, and any other tty can be typed
+// in.
+func (l *launcher) openSerial() {
+	opts := serialterm.Options{
+		Baud:     115200,
+		DataBits: 8,
+		Parity:   serialterm.ParityNone,
+		StopBits: 1,
+		Flow:     serialterm.FlowNone,
+		Send:     serialterm.NewlineCR,
+		Receive:  serialterm.NewlineCRLF,
+	}
+	devices := serialterm.Devices()
+	if len(devices) > 0 {
+		opts.Device = devices[0]
+	}
+	other := ""
+	bauds := []string{}
+	baudIndex := 0
+	for i, baud := range serialterm.Bauds {
+		bauds = append(bauds, strconv.Itoa(baud))
+		if baud == opts.Baud {
+			baudIndex = i
+		}
+	}
+
+	form := cview.NewForm()
+	if len(devices) > 0 {
+		form.AddDropDownSimple("Device", 0, func(index int, option *cview.DropDownOption) {
+			opts.Device = devices[index]
+		}, devices...)
+	}
+	form.AddInputField("Other tty", "", 30, nil, func(text string) {
+		other = text
+	})
+	form.AddDropDownSimple("Baud", baudIndex, func(index int, option *cview.DropDownOption) {
+		opts.Baud = serialterm.Bauds[index]
+	}, bauds...)
+	form.AddDropDownSimple("Data bits", 0, func(index int, option *cview.DropDownOption) {
+		opts.DataBits = 8 - index
+	}, "8", "7", "6", "5")
+	form.AddDropDownSimple("Parity", 0, func(index int, option *cview.DropDownOption) {
+		opts.Parity = []serialterm.Parity{serialterm.ParityNone, serialterm.ParityEven, serialterm.ParityOdd}[index]
+	}, "none", "even", "odd")
+	form.AddDropDownSimple("Stop bits", 0, func(index int, option *cview.DropDownOption) {
+		opts.StopBits = 1 + index
+	}, "1", "2")
+	form.AddDropDownSimple("Flow control", 0, func(index int, option *cview.DropDownOption) {
+		opts.Flow = []serialterm.Flow{serialterm.FlowNone, serialterm.FlowHardware, serialterm.FlowSoftware}[index]
+	}, "none", "RTS/CTS", "XON/XOFF")
+	form.AddDropDownSimple("Enter sends", 0, func(index int, option *cview.DropDownOption) {
+		opts.Send = []serialterm.Newline{serialterm.NewlineCR, serialterm.NewlineLF, serialterm.NewlineCRLF}[index]
+	}, "CR", "LF", "CRLF")
+	form.AddDropDownSimple("Lines end with", 0, func(index int, option *cview.DropDownOption) {
+		opts.Receive = []serialterm.Newline{serialterm.NewlineCRLF, serialterm.NewlineLF, serialterm.NewlineCR}[index]
+	}, "CRLF", "LF", "CR")
+	form.AddCheckBox("Local echo", "", false, func(checked bool) {
+		opts.LocalEcho = checked
+	})
+	dialog := cview.NewWindow(form)
+	dialog.SetTitle("Serial console")
+	dialog.SetBorder(true)
+	form.AddButton("Open", func() {
+		l.close(dialog)
+		if strings.TrimSpace(other) != "" {
+			opts.Device = strings.TrimSpace(other)
+		}
+		if opts.Device != "" {
+			l.connectSerial(opts)
+		}
+	})
+	form.AddButton("Cancel", func() {
+		l.close(dialog)
+	})
+	form.SetCancelFunc(func() {
+		l.close(dialog)
+	})
+	l.show(dialog, serialDialogWidth, serialDialogHeight)
+}
+
+// connectSerial opens the device in a window. Alt+Shift+B sends a break,
+// Alt+Shift+E turns local echo on or off, Alt+Shift+N changes what Enter
+// sends and Alt+Shift+R how lines received end.
+func (l *launcher) connectSerial(opts serialterm.Options) {
+	port, err := serialterm.Open(opts)
+	if err != nil {
+		l.center.Notify("Serial console", err.Error())
+		return
+	}
+	capture := func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() != tcell.KeyRune || event.Modifiers()&tcell.ModAlt == 0 {
+			return event
+		}
+		switch event.Rune() {
+		case 'B':
+			// The break waits for the output to drain, which flow
+			// control can hold off
+			go func() {
+				if err := port.Break(); err != nil {
+					l.center.Notify("Serial console", err.Error())
+				}
+			}()
+		case 'E':
+			port.SetLocalEcho(!port.LocalEcho())
+			state := "off"
+			if port.LocalEcho() {
+				state = "on"
+			}
+			l.center.Notify("Serial console", "Local echo is "+state+" for "+opts.Device)
+		case 'N':
+			opts.Send = nextNewline(opts.Send, serialterm.NewlineCR, serialterm.NewlineLF, serialterm.NewlineCRLF)
+			port.SetNewlines(opts.Send, opts.Receive)
+			l.center.Notify("Serial console", "Enter sends "+strings.ToUpper(string(opts.Send))+" to "+opts.Device)
+		case 'R':
+			opts.Receive = nextNewline(opts.Receive, serialterm.NewlineCRLF, serialterm.NewlineLF, serialterm.NewlineCR)
+			port.SetNewlines(opts.Send, opts.Receive)
+			l.center.Notify("Serial console", "Lines from "+opts.Device+" end with "+strings.ToUpper(string(opts.Receive)))
+		default:
+			return event
+		}
+		return nil
+	}
+	l.createWindow(opts.Device, tuiwindow.WithConn(port, nil), tuiwindow.WithInputCapture(capture))
+	l.redraw()
+}
+
+// nextNewline returns the line ending after nl in order, wrapping around.
+func nextNewline(nl serialterm.Newline, order ...serialterm.Newline) serialterm.Newline {
+	for i, o := range order {
+		if o == nl {
+			return order[(i+1)%len(order)]
+		}
+	}
+	return order[0]
+}
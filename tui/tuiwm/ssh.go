@@ -0,0 +1,91 @@
+package tuiwm
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/snadrus/tuitop/deps/cview"
+	"github.com/snadrus/tuitop/tui/sshterm"
+	"github.com/snadrus/tuitop/tui/tuiwindow"
+	"golang.org/x/crypto/ssh"
+)
+
+const sshDialogWidth, sshDialogHeight = 50, 9
+
+// openSSH shows the dialog asking which host to connect to. Hosts are given as
+// [user@]host[:port] or as an alias from ~/.ssh/config.
+func (l *launcher) openSSH() {
+	var (
+		dest      string
+		reconnect bool
+	)
+	form := cview.NewForm()
+	form.AddInputField("Host", "", 30, nil, func(text string) {
+		dest = text
+	})
+	form.AddCheckBox("Reconnect", "", false, func(checked bool) {
+		reconnect = checked
+	})
+	dialog := cview.NewWindow(form)
+	dialog.SetTitle("SSH")
+	dialog.SetBorder(true)
+	form.AddButton("Connect", func() {
+		l.close(dialog)
+		if strings.TrimSpace(dest) != "" {
+			go l.connectSSH(strings.TrimSpace(dest), reconnect)
+		}
+	})
+	form.AddButton("Cancel", func() {
+		l.close(dialog)
+	})
+	form.SetCancelFunc(func() {
+		l.close(dialog)
+	})
+	l.show(dialog, sshDialogWidth, sshDialogHeight)
+}
+
+// connectSSH dials the host and opens a window with its shell. Errors are shown
+// as notifications. It blocks while dialing, so isn't called on the UI
+// goroutine.
+func (l *launcher) connectSSH(dest string, reconnect bool) {
+	target, err := sshterm.Resolve(dest)
+	if err != nil {
+		log.Print(err)
+	}
+	sess, err := sshterm.Dial(sshterm.Options{
+		Target:    target,
+		Confirm:   l.confirmHostKey,
+		Reconnect: reconnect,
+	}, 80, 24)
+	if err != nil {
+		l.center.Notify("SSH", err.Error())
+		return
+	}
+	l.app.QueueUpdateDraw(func() {
+		l.createWindow(target.Alias, tuiwindow.WithConn(sess, sess.Resize))
+	})
+}
+
+// confirmHostKey asks whether to trust the key of a host connected to for
+// the first time. It blocks until the user answers, so isn't called on the UI
+// goroutine.
+func (l *launcher) confirmHostKey(host string, key ssh.PublicKey) bool {
+	answer := make(chan bool, 1)
+	modal := cview.NewModal()
+	modal.SetText(fmt.Sprintf("The authenticity of host %s can't be established.\n%s key fingerprint is\n%s\nTrust it and connect?",
+		host, key.Type(), ssh.FingerprintSHA256(key)))
+	modal.AddButtons([]string{"Trust", "Cancel"})
+	dialog := cview.NewWindow(modal)
+	dialog.SetTitle("Unknown host")
+	dialog.SetBorder(true)
+	// Escape is reported as no button
+	modal.SetDoneFunc(func(buttonIndex int, buttonLabel string) {
+		l.close(dialog)
+		answer <- buttonLabel == "Trust"
+	})
+	l.app.QueueUpdateDraw(func() {
+		l.show(dialog, 64, 12)
+	})
+	return <-answer
+}
@@ -0,0 +1,353 @@
+package tuiwm
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/snadrus/tuitop/deps/cview"
+	"github.com/snadrus/tuitop/tui/config"
+	"github.com/snadrus/tuitop/tui/nativeapp"
+	"github.com/snadrus/tuitop/tui/nativeapp/clock"
+	"github.com/snadrus/tuitop/tui/sysstat"
+)
+
+// statusWidget is a tray widget showing the state of the system. Widgets are
+// only used from the goroutine of their tray.
+type statusWidget interface {
+	// update samples the system, returning the text shown in the tray and
+	// in the popup opened by clicking it. An empty text hides the widget.
+	update(now time.Time) (text, detail string)
+}
+
+// statusWidgets make the widgets which can be put in the tray, with how often
+// they update by default.
+var statusWidgets = map[string]struct {
+	interval time.Duration
+	make     func(cfg *config.Config) statusWidget
+}{
+	"cpu":      {2 * time.Second, func(*config.Config) statusWidget { return &cpuWidget{} }},
+	"memory":   {5 * time.Second, func(*config.Config) statusWidget { return memoryWidget{} }},
+	"load":     {5 * time.Second, func(*config.Config) statusWidget { return loadWidget{} }},
+	"battery":  {30 * time.Second, func(*config.Config) statusWidget { return batteryWidget{} }},
+	"network":  {2 * time.Second, func(*config.Config) statusWidget { return &networkWidget{} }},
+	"disk":     {time.Minute, func(cfg *config.Config) statusWidget { return diskWidget{cfg.Tray.Disk} }},
+	"keyboard": {5 * time.Second, func(*config.Config) statusWidget { return keyboardWidget{} }},
+	"clock":    {time.Second, func(cfg *config.Config) statusWidget { return clockWidget{clock.Layout(cfg.Clock, cfg.Clock.Seconds)} }},
+}
+
+const statusPopupWidth = 44
+
+// statusTray is the tray of status widgets at the right of the taskbar. A
+// single ticker updates the widgets when they are due.
+type statusTray struct {
+	*Tray
+	app    *cview.Application
+	redraw func()
+	wm     *cview.WindowManager
+	items  []*statusItem
+
+	mu    sync.Mutex
+	popup *statusPopup
+}
+
+type statusItem struct {
+	id       int
+	name     string
+	widget   statusWidget
+	interval time.Duration
+	next     time.Time
+	// detail is read by the UI goroutine
+	detail string
+}
+
+type statusPopup struct {
+	item *statusItem
+	w    *cview.Window
+	view *cview.TextView
+}
+
+// newStatusTray makes the widgets set in cfg and updates them once. Clicking
+// the clock opens the Clock app through desktop.
+func newStatusTray(app *cview.Application, redraw func(), wm *cview.WindowManager, cfg *config.Config, desktop nativeapp.Desktop) *statusTray {
+	s := &statusTray{Tray: NewTray(), app: app, redraw: redraw, wm: wm}
+	s.setColors(Light(ColorWindowsBlue, 4), tcell.ColorBlack)
+	s.SetPadding(0, 0, 1, 1)
+	for i, name := range cfg.Tray.Widgets {
+		w, ok := statusWidgets[name]
+		if !ok {
+			log.Printf("unknown tray widget %q", name)
+			continue
+		}
+		item := &statusItem{id: i, name: name, widget: w.make(cfg), interval: w.interval}
+		if interval, ok := cfg.Tray.Intervals[name]; ok && interval > 0 {
+			item.interval = interval
+		}
+		onClick := func() { s.togglePopup(item) }
+		if name == "clock" {
+			onClick = func() {
+				if err := desktop.Launch("clock"); err != nil {
+					log.Print(err)
+				}
+			}
+		}
+		s.add(item.id, "", onClick)
+		s.items = append(s.items, item)
+	}
+	s.tick(time.Now())
+	return s
+}
+
+// size returns the width the tray needs in the taskbar.
+func (s *statusTray) size() int {
+	return s.width() + 2
+}
+
+// run updates the widgets until the program exits, resizing the tray in
+// taskbar to fit them.
+func (s *statusTray) run(taskbar *cview.Flex) {
+	ticker := time.NewTicker(time.Second)
+	for now := range ticker.C {
+		width := s.size()
+		if !s.tick(now) {
+			continue
+		}
+		if newWidth := s.size(); newWidth != width {
+			s.app.QueueUpdateDraw(func() { taskbar.ResizeItem(s, newWidth, 0) })
+			continue
+		}
+		s.redraw()
+	}
+}
+
+// tick updates the widgets which are due, returning whether any was.
+func (s *statusTray) tick(now time.Time) bool {
+	updated := false
+	for _, item := range s.items {
+		if now.Before(item.next) {
+			continue
+		}
+		// Round down so widgets sharing an interval update together
+		item.next = now.Add(item.interval).Truncate(time.Second)
+		text, detail := item.widget.update(now)
+		s.set(item.id, text)
+		s.mu.Lock()
+		item.detail = detail
+		popup := s.popup
+		s.mu.Unlock()
+		if popup != nil && popup.item == item {
+			s.app.QueueUpdate(func() { popup.view.SetText(detail) })
+		}
+		updated = true
+	}
+	return updated
+}
+
+// togglePopup shows the detail of item above the tray, or closes it.
+func (s *statusTray) togglePopup(item *statusItem) {
+	s.mu.Lock()
+	popup := s.popup
+	s.popup = nil
+	detail := item.detail
+	s.mu.Unlock()
+	if popup != nil {
+		s.wm.Remove(popup.w)
+		s.redraw()
+		if popup.item == item {
+			return
+		}
+	}
+
+	view := cview.NewTextView()
+	view.SetDynamicColors(true)
+	view.SetText(detail)
+	w := cview.NewWindow(view)
+	w.SetTitle(strings.ToUpper(item.name[:1]) + item.name[1:])
+	w.SetBorder(true)
+	popup = &statusPopup{item: item, w: w, view: view}
+	w.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() == tcell.KeyEscape || event.Rune() == 'q' {
+			s.togglePopup(item)
+			return nil
+		}
+		return event
+	})
+	height := strings.Count(detail, "\n") + 3
+	_, _, screenW, screenH := s.wm.GetRect()
+	w.SetRect(screenW-statusPopupWidth, screenH-height, statusPopupWidth, height)
+
+	s.mu.Lock()
+	s.popup = popup
+	s.mu.Unlock()
+	s.wm.Add(w)
+	s.app.SetFocus(w)
+	s.redraw()
+}
+
+// detailLines formats label and value pairs in two columns.
+func detailLines(pairs ...string) string {
+	var b strings.Builder
+	for i := 0; i+1 < len(pairs); i += 2 {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "[::b]%-12s[::-] %s", pairs[i], pairs[i+1])
+	}
+	return b.String()
+}
+
+type cpuWidget struct {
+	prev sysstat.CPUTimes
+}
+
+func (w *cpuWidget) update(time.Time) (string, string) {
+	t, err := sysstat.ReadCPU()
+	if err != nil {
+		return "", err.Error()
+	}
+	usage := t.Usage(w.prev)
+	w.prev = t
+	return fmt.Sprintf("CPU %2.0f%%", usage), detailLines(
+		"Usage", fmt.Sprintf("%.1f%%", usage),
+		"Cores", fmt.Sprint(t.Cores),
+	)
+}
+
+type memoryWidget struct{}
+
+func (memoryWidget) update(time.Time) (string, string) {
+	m, err := sysstat.ReadMemory()
+	if err != nil {
+		return "", err.Error()
+	}
+	swap := "none"
+	if m.SwapTotal > 0 {
+		swap = sysstat.FormatBytes(m.SwapTotal-m.SwapFree) + " of " + sysstat.FormatBytes(m.SwapTotal)
+	}
+	return fmt.Sprintf("MEM %2.0f%%", m.UsedPercent()), detailLines(
+		"Used", sysstat.FormatBytes(m.Used())+" of "+sysstat.FormatBytes(m.Total),
+		"Available", sysstat.FormatBytes(m.Available),
+		"Cache", sysstat.FormatBytes(m.Buffers+m.Cached),
+		"Swap", swap,
+	)
+}
+
+type loadWidget struct{}
+
+func (loadWidget) update(time.Time) (string, string) {
+	l, err := sysstat.ReadLoad()
+	if err != nil {
+		return "", err.Error()
+	}
+	return fmt.Sprintf("LOAD %.2f", l.One), detailLines(
+		"1 minute", fmt.Sprintf("%.2f", l.One),
+		"5 minutes", fmt.Sprintf("%.2f", l.Five),
+		"15 minutes", fmt.Sprintf("%.2f", l.Fifteen),
+		"Tasks", fmt.Sprintf("%d running of %d", l.Running, l.Tasks),
+	)
+}
+
+type batteryWidget struct{}
+
+func (batteryWidget) update(time.Time) (string, string) {
+	batteries := sysstat.Batteries()
+	if len(batteries) == 0 {
+		return "", "No battery"
+	}
+	pairs := []string{}
+	for _, b := range batteries {
+		state := fmt.Sprintf("%d%% %s", b.Capacity, strings.ToLower(b.Status))
+		if b.TimeLeft > 0 {
+			state += fmt.Sprintf(", %d:%02d left", int(b.TimeLeft.Hours()), int(b.TimeLeft.Minutes())%60)
+		}
+		pairs = append(pairs, b.Name, state)
+	}
+	b := batteries[0]
+	text := fmt.Sprintf("BAT %d%%", b.Capacity)
+	if b.Status == "Charging" {
+		text += "+"
+	}
+	return text, detailLines(pairs...)
+}
+
+// networkWidget shows the traffic of the interfaces which are up, per second.
+type networkWidget struct {
+	prev map[string]sysstat.Interface
+	at   time.Time
+}
+
+func (w *networkWidget) update(now time.Time) (string, string) {
+	interfaces, err := sysstat.Interfaces()
+	if err != nil {
+		return "", err.Error()
+	}
+	seconds := now.Sub(w.at).Seconds()
+	rate := func(now, before uint64) uint64 {
+		if seconds <= 0 || now < before {
+			return 0
+		}
+		return uint64(float64(now-before) / seconds)
+	}
+	var rx, tx uint64
+	up := false
+	pairs := []string{}
+	prev := map[string]sysstat.Interface{}
+	for _, i := range interfaces {
+		prev[i.Name] = i
+		if !i.Up {
+			pairs = append(pairs, i.Name, "down")
+			continue
+		}
+		up = true
+		before, ok := w.prev[i.Name]
+		if !ok {
+			before = i
+		}
+		irx, itx := rate(i.RxBytes, before.RxBytes), rate(i.TxBytes, before.TxBytes)
+		rx += irx
+		tx += itx
+		pairs = append(pairs, i.Name, fmt.Sprintf("↓%s/s ↑%s/s", sysstat.FormatBytes(irx), sysstat.FormatBytes(itx)))
+	}
+	w.prev, w.at = prev, now
+	if !up {
+		return "NET off", detailLines(pairs...)
+	}
+	return fmt.Sprintf("↓%s ↑%s", sysstat.FormatBytes(rx), sysstat.FormatBytes(tx)), detailLines(pairs...)
+}
+
+type diskWidget struct {
+	path string
+}
+
+func (w diskWidget) update(time.Time) (string, string) {
+	d, err := sysstat.DiskUsage(w.path)
+	if err != nil {
+		return "", err.Error()
+	}
+	return fmt.Sprintf("%s %2.0f%%", w.path, d.UsedPercent()), detailLines(
+		"Filesystem", w.path,
+		"Used", sysstat.FormatBytes(d.Total-d.Free)+" of "+sysstat.FormatBytes(d.Total),
+		"Available", sysstat.FormatBytes(d.Avail),
+	)
+}
+
+type keyboardWidget struct{}
+
+func (keyboardWidget) update(time.Time) (string, string) {
+	layout := sysstat.KeyboardLayout()
+	if layout == "" {
+		return "", "Unknown layout"
+	}
+	return "⌨ " + layout, detailLines("Layout", layout)
+}
+
+type clockWidget struct {
+	layout string
+}
+
+func (w clockWidget) update(now time.Time) (string, string) {
+	return now.Format(w.layout), ""
+}
@@ -0,0 +1,142 @@
+package tuiwm
+
+import (
+	"sync"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/mattn/go-runewidth"
+	"github.com/snadrus/tuitop/deps/cview"
+)
+
+// Tray shows items in the taskbar, right-aligned and separated by a blank.
+// Items without text are hidden.
+type Tray struct {
+	*cview.Box
+	style tcell.Style
+
+	mu    sync.Mutex
+	items []*trayItem
+}
+
+type trayItem struct {
+	id      int
+	text    string
+	onClick func()
+}
+
+// traySpan is where an item is drawn, from start to end exclusive.
+type traySpan struct {
+	item       *trayItem
+	start, end int
+}
+
+// NewTray returns a tray for the items of programs.
+func NewTray() *Tray {
+	t := &Tray{Box: cview.NewBox()}
+	t.setColors(ColorWindowsBlue, tcell.ColorWhite)
+	return t
+}
+
+func (t *Tray) setColors(background, text tcell.Color) {
+	t.SetBackgroundColor(background)
+	t.style = tcell.StyleDefault.Background(background).Foreground(text)
+}
+
+// add adds an item to the left of the others. onClick is called on the UI
+// goroutine.
+func (t *Tray) add(id int, text string, onClick func()) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.items = append(t.items, &trayItem{id: id, text: text, onClick: onClick})
+}
+
+func (t *Tray) set(id int, text string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, item := range t.items {
+		if item.id == id {
+			item.text = text
+		}
+	}
+}
+
+func (t *Tray) remove(id int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for i, item := range t.items {
+		if item.id == id {
+			t.items = append(t.items[:i], t.items[i+1:]...)
+			return
+		}
+	}
+}
+
+// layout places the items from the right edge of a tray width cells wide.
+// Items which don't fit are left out.
+func (t *Tray) layout(width int) []traySpan {
+	spans := []traySpan{}
+	end := width
+	for i := len(t.items) - 1; i >= 0; i -= 1 {
+		item := t.items[i]
+		if item.text == "" {
+			continue
+		}
+		start := end - runewidth.StringWidth(item.text)
+		if start < 0 {
+			break
+		}
+		spans = append(spans, traySpan{item: item, start: start, end: end})
+		end = start - 1
+	}
+	return spans
+}
+
+// width returns the width needed to show all the items.
+func (t *Tray) width() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	width := -1
+	for _, item := range t.items {
+		if item.text != "" {
+			width += runewidth.StringWidth(item.text) + 1
+		}
+	}
+	return max(width, 0)
+}
+
+func (t *Tray) Draw(screen tcell.Screen) {
+	t.Box.Draw(screen)
+	x, y, width, _ := t.GetInnerRect()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, span := range t.layout(width) {
+		col := x + span.start
+		for _, r := range span.item.text {
+			screen.SetContent(col, y, r, nil, t.style)
+			col += runewidth.RuneWidth(r)
+		}
+	}
+}
+
+func (t *Tray) MouseHandler() func(action cview.MouseAction, event *tcell.EventMouse, setFocus func(p cview.Primitive)) (consumed bool, capture cview.Primitive) {
+	return t.WrapMouseHandler(func(action cview.MouseAction, event *tcell.EventMouse, setFocus func(p cview.Primitive)) (consumed bool, capture cview.Primitive) {
+		px, py := event.Position()
+		if action != cview.MouseLeftClick || !t.InRect(px, py) {
+			return false, nil
+		}
+		x, _, width, _ := t.GetInnerRect()
+		t.mu.Lock()
+		var onClick func()
+		for _, span := range t.layout(width) {
+			if px-x >= span.start && px-x < span.end {
+				onClick = span.item.onClick
+			}
+		}
+		t.mu.Unlock()
+		if onClick == nil {
+			return false, nil
+		}
+		onClick()
+		return true, nil
+	})
+}
@@ -1,37 +1,45 @@
 package tuiwm
 
 import (
+	"log"
 	_ "net/http/pprof"
 	"os"
 	"os/exec"
-	"time"
 
 	"github.com/gdamore/tcell/v2"
 	"github.com/snadrus/tuitop/deps/cview"
+	"github.com/snadrus/tuitop/tui/appproto"
+	"github.com/snadrus/tuitop/tui/config"
 	"github.com/snadrus/tuitop/tui/installer"
+	"github.com/snadrus/tuitop/tui/nativeapp"
+	"github.com/snadrus/tuitop/tui/notify"
+	"github.com/snadrus/tuitop/tui/opener"
 	"github.com/snadrus/tuitop/tui/tuiwindow"
 )
 
-// CreateWindowManager returns the window page.
-func CreateWindowManager() *cview.WindowManager {
+// CreateWindowManager returns the window page, with two shells, and what
+// opens terminal windows on it with the settings of cfg.
+func CreateWindowManager(app *cview.Application, redraw func(), cfg config.Terminal) (*cview.WindowManager, tuiwindow.CreateWindow) {
 	wm := cview.NewWindowManager()
-
-	t := cview.NewTextView()
-	t.SetText("Ctrl-C exits.")
-	w3 := cview.NewWindow(t)
-	w3.SetRect(12, 12, 17, 7)
-	w3.SetBorder(false)
-	w3.SetMouseCapture(func(action cview.MouseAction, event *tcell.EventMouse) (cview.MouseAction, *tcell.EventMouse) {
-		return 0, nil
-	})
-
-	wm.Add(w3)
-	createWindow := tuiwindow.MkCreateWindow(wm)
+	createWindow := withTerminalSettings(tuiwindow.MkCreateWindow(app, wm, redraw), cfg)
 	AddShell(createWindow)
 	AddShell(createWindow)
 
 	// TODO replace wm to have Remove() and GetWindows() methods
-	return wm
+	return wm, createWindow
+}
+
+// withTerminalSettings gives the windows createWindow opens the cell size of
+// cfg, and lets the programs it names use XTWINOPS. Options passed when
+// opening a window override them.
+func withTerminalSettings(createWindow tuiwindow.CreateWindow, cfg config.Terminal) tuiwindow.CreateWindow {
+	return func(cmd string, opts ...func(*tuiwindow.TuiWindowCfg)) {
+		settings := []func(*tuiwindow.TuiWindowCfg){
+			tuiwindow.WithWindowOps(cfg.AllowsWindowOps),
+			tuiwindow.WithCellSize(cfg.CellWidth, cfg.CellHeight),
+		}
+		createWindow(cmd, append(settings, opts...)...)
+	}
 }
 
 func AddShell(createWindow tuiwindow.CreateWindow) {
@@ -44,12 +52,15 @@ func AddShell(createWindow tuiwindow.CreateWindow) {
 		}
 	}
 
-	createWindow(sh)
+	createWindow(sh, tuiwindow.WithDir(tuiwindow.FocusedDir()))
 }
 
 var ColorWindowsBlue = tcell.NewRGBColor(49, 119, 217)
 
-func CreateBottomLayout(app *cview.Application, wm *cview.WindowManager, createWindow tuiwindow.CreateWindow) cview.Primitive {
+// CreateBottomLayout returns the taskbar, with the items of programs in
+// apps and the status widgets set in cfg. Clicking the clock opens the Clock
+// app through desktop.
+func CreateBottomLayout(app *cview.Application, redraw func(), wm *cview.WindowManager, createWindow tuiwindow.CreateWindow, center *notify.Center, desktop nativeapp.Desktop, apps *Tray, cfg *config.Config) cview.Primitive {
 	btm := cview.NewFlex()
 	btm.SetDirection(cview.FlexColumn)
 	btn1 := cview.NewTextView()
@@ -78,25 +89,17 @@ func CreateBottomLayout(app *cview.Application, wm *cview.WindowManager, createW
 
 	drawer := cview.NewTextView()
 	drawer.SetBackgroundColor(ColorWindowsBlue) //#3177d9
+	drawer.SetTextColor(tcell.ColorWhite)
 	btm.AddItem(drawer, 0, 100, false)
-	tray := cview.NewTextView()
-	tray.SetBackgroundColor(Light(ColorWindowsBlue, 4)) //#3177d9
-	tray.SetTextColor(tcell.ColorBlack)
-	tray.SetText(getTime())
-
-	go func() {
-		for {
-			tray.SetText(getTime())
-			app.Draw()
-			<-time.After(time.Second)
-		}
-	}()
-	btm.AddItem(tray, 7, 0, false)
+	notifier := newDesktopNotifier(app, redraw, wm, center, drawer)
+	tuiwindow.SetNotifier(notifier)
+	btm.AddItem(notifier.indicator, 4, 0, false)
+	btm.AddItem(apps, 0, 30, false)
+	status := newStatusTray(app, redraw, wm, cfg, desktop)
+	btm.AddItem(status, status.size(), 0, false)
+	go status.run(btm)
 	return btm
 }
-func getTime() string {
-	return time.Now().Format(" 15:04 ")
-}
 
 func Light(baseColor tcell.Color, howLight int32) tcell.Color {
 	r, g, b := baseColor.RGB()
@@ -110,18 +113,91 @@ var TuiTopWindowColor = tcell.NewRGBColor(0, 106, 255)
 
 type XP struct {
 	*cview.Flex
-	inst *installer.Installer
+	inst          *installer.Installer
+	createWindow  tuiwindow.CreateWindow
+	Notifications *notify.Center
+	launch        *launcher
+	apps          *appproto.Server
+}
+
+// HandleShortcut handles the desktop's keyboard shortcuts. Other keys are
+// returned to be passed on. Ctrl+Alt+T opens a shell, Ctrl+Alt+S asks for a
+// host to open an SSH window on and Ctrl+Alt+P for a serial device.
+// Ctrl+Alt+Left and Right switch workspaces.
+func (xp *XP) HandleShortcut(event *tcell.EventKey) *tcell.EventKey {
+	if event.Modifiers()&tcell.ModAlt == 0 {
+		return event
+	}
+	switch event.Key() {
+	case tcell.KeyLeft, tcell.KeyRight:
+		if event.Modifiers()&tcell.ModCtrl == 0 {
+			return event
+		}
+		step := 1
+		if event.Key() == tcell.KeyLeft {
+			step = -1
+		}
+		xp.launch.spaces.switchTo(xp.launch.spaces.current + step)
+		return nil
+	case tcell.KeyCtrlT:
+		AddShell(xp.createWindow)
+		return nil
+	case tcell.KeyCtrlS:
+		if err := xp.Open("ssh"); err != nil {
+			xp.Notifications.Notify("Cannot open SSH", err.Error())
+		}
+		return nil
+	case tcell.KeyCtrlP:
+		if err := xp.Open("serial"); err != nil {
+			xp.Notifications.Notify("Cannot open serial console", err.Error())
+		}
+		return nil
+	}
+	return event
 }
 
-func MakeXP(app *cview.Application) cview.Primitive {
-	wm := CreateWindowManager()
-	createWindow := tuiwindow.MkCreateWindow(wm)
-	btm := CreateBottomLayout(app, wm, createWindow)
+// Open starts the app of the catalogue named name. Apps with a window built
+// into TuiTop open it, and others are installed if needed and run in a
+// terminal window.
+func (xp *XP) Open(name string) error {
+	return xp.launch.open(name)
+}
+
+func MakeXP(app *cview.Application) *XP {
+	frames := tuiwindow.NewFrameLimiter(func() { app.Draw() }, tuiwindow.FrameInterval)
+	// Set before the first shells start so they inherit it
+	sock := socketPath()
+	os.Setenv(appproto.EnvVar, sock)
+	cfg, err := config.Load()
+	if err != nil {
+		log.Print(err)
+	}
+	wm, createWindow := CreateWindowManager(app, frames.Request, cfg.Terminal)
+	center := notify.NewCenter()
+	i := installer.New(createWindow)
+	launch := &launcher{app: app, redraw: frames.Request, wm: wm, createWindow: createWindow, center: center, inst: i}
+	launch.spaces = newWorkspaces(launch)
+	tray := NewTray()
+	apps, err := appproto.Listen(sock, newProtoBackend(launch, tray))
+	if err != nil {
+		log.Print(err)
+	}
+	btm := CreateBottomLayout(app, frames.Request, wm, createWindow, center, launch, tray, cfg)
 	flex := cview.NewFlex()
 	flex.SetDirection(cview.FlexRow)
-	flex.AddItem(wm, 0, 1, true)
+	desk := newDesktop(launch, cfg.Desktop)
+	tuiwindow.SetMenus(desk)
+	flex.AddItem(desk, 0, 1, true)
 	flex.AddItem(btm, 1, 0, false)
 
-	i := installer.New(createWindow)
-	return &XP{flex, i}
+	tuiwindow.SetLinkOpener(opener.New(app, cfg.Links, i, createWindow).Open)
+	return &XP{flex, i, createWindow, center, launch, apps}
+}
+
+// Close stops the app protocol server, closing the windows of its programs.
+func (xp *XP) Close() error {
+	if xp.apps == nil {
+		return nil
+	}
+	return xp.apps.Close()
 }
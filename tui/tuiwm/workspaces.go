@@ -0,0 +1,150 @@
+package tuiwm
+
+import (
+	"math"
+
+	"github.com/snadrus/tuitop/deps/cview"
+	"github.com/snadrus/tuitop/tui/tuiwindow"
+)
+
+// workspaceCount is how many workspaces there are.
+const workspaceCount = 4
+
+// workspaces shows the windows of one workspace at a time, and keeps the
+// windows set always on top in front of the others. It is used on the UI
+// goroutine.
+type workspaces struct {
+	l       *launcher
+	current int
+	// on is the workspace of each window; windows missing opened on the
+	// current one
+	on    map[*cview.Window]int
+	onTop map[*cview.Window]bool
+}
+
+func newWorkspaces(l *launcher) *workspaces {
+	return &workspaces{l: l, on: map[*cview.Window]int{}, onTop: map[*cview.Window]bool{}}
+}
+
+// settle records the workspace of the windows opened since the last call,
+// and forgets the closed ones.
+func (s *workspaces) settle() []*cview.Window {
+	windows := tuiwindow.Windows()
+	open := map[*cview.Window]bool{}
+	for _, w := range windows {
+		open[w] = true
+		if _, ok := s.on[w]; !ok {
+			s.on[w] = s.current
+		}
+	}
+	for w := range s.on {
+		if !open[w] {
+			delete(s.on, w)
+			delete(s.onTop, w)
+		}
+	}
+	return windows
+}
+
+// switchTo shows the windows of workspace n, hiding the others, and raises
+// the newest of them.
+func (s *workspaces) switchTo(n int) {
+	n = (n + workspaceCount) % workspaceCount
+	var front *cview.Window
+	for _, w := range s.settle() {
+		w.SetVisible(s.on[w] == n)
+		if s.on[w] == n {
+			front = w
+		}
+	}
+	s.current = n
+	if front != nil {
+		s.l.raise(front)
+		return
+	}
+	s.l.app.SetFocus(s.l.wm)
+	s.l.redraw()
+}
+
+// reveal switches to the workspace of w.
+func (s *workspaces) reveal(w *cview.Window) {
+	s.settle()
+	if n, ok := s.on[w]; ok && n != s.current {
+		s.switchTo(n)
+	}
+}
+
+// move moves w to workspace n.
+func (s *workspaces) move(w *cview.Window, n int) {
+	s.settle()
+	s.on[w] = n
+	if n != s.current {
+		w.SetVisible(false)
+		s.l.app.SetFocus(s.l.wm)
+	}
+	s.l.redraw()
+}
+
+// toggleOnTop sets whether w is kept in front of the other windows.
+func (s *workspaces) toggleOnTop(w *cview.Window) {
+	s.onTop[w] = !s.onTop[w]
+	if !s.onTop[w] {
+		delete(s.onTop, w)
+	}
+	s.keepOnTop()
+	s.l.redraw()
+}
+
+// keepOnTop brings the windows kept on top, then the open menus, in front of
+// the others.
+func (s *workspaces) keepOnTop() {
+	for _, w := range tuiwindow.Windows() {
+		if s.onTop[w] {
+			s.l.wm.Remove(w)
+			s.l.wm.Add(w)
+		}
+	}
+	if len(s.l.menus) > 0 {
+		s.l.wm.Remove(s.l.menus...)
+		s.l.wm.Add(s.l.menus...)
+	}
+}
+
+// arrange tiles the windows shown on the desktop in a grid.
+func (s *workspaces) arrange() {
+	shown := []*cview.Window{}
+	for _, w := range tuiwindow.Windows() {
+		if w.GetVisible() {
+			shown = append(shown, w)
+		}
+	}
+	if len(shown) == 0 {
+		return
+	}
+	x, y, width, height := s.l.wm.GetRect()
+	for i, rect := range grid(len(shown), width, height) {
+		shown[i].SetFullscreen(false)
+		shown[i].SetRect(x+rect[0], y+rect[1], rect[2], rect[3])
+	}
+	s.l.redraw()
+}
+
+// grid returns the x, y, width and height of n tiles filling width and
+// height, as square a grid as can be, the last row sharing its width between
+// fewer tiles if they don't fill it.
+func grid(n, width, height int) [][4]int {
+	if n == 0 {
+		return nil
+	}
+	cols := int(math.Ceil(math.Sqrt(float64(n))))
+	rows := (n + cols - 1) / cols
+	tiles := make([][4]int, n)
+	for i := range tiles {
+		col, row := i%cols, i/cols
+		inRow := min(cols, n-row*cols)
+		x0, x1 := col*width/inRow, (col+1)*width/inRow
+		y0, y1 := row*height/rows, (row+1)*height/rows
+		tiles[i] = [4]int{x0, y0, x1 - x0, y1 - y0}
+	}
+	return tiles
+}
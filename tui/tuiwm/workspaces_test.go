@@ -0,0 +1,26 @@
+package tuiwm
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGrid(t *testing.T) {
+	for _, test := range []struct {
+		n    int
+		want [][4]int
+	}{
+		{0, nil},
+		{1, [][4]int{{0, 0, 80, 24}}},
+		{2, [][4]int{{0, 0, 40, 24}, {40, 0, 40, 24}}},
+		{3, [][4]int{{0, 0, 40, 12}, {40, 0, 40, 12}, {0, 12, 80, 12}}},
+		{4, [][4]int{{0, 0, 40, 12}, {40, 0, 40, 12}, {0, 12, 40, 12}, {40, 12, 40, 12}}},
+		// The last row shares its width between the two windows left
+		{5, [][4]int{{0, 0, 26, 12}, {26, 0, 27, 12}, {53, 0, 27, 12}, {0, 12, 40, 12}, {40, 12, 40, 12}}},
+		{7, [][4]int{{0, 0, 26, 8}, {26, 0, 27, 8}, {53, 0, 27, 8}, {0, 8, 26, 8}, {26, 8, 27, 8}, {53, 8, 27, 8}, {0, 16, 80, 8}}},
+	} {
+		if got := grid(test.n, 80, 24); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%d windows: got %v, want %v", test.n, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,208 @@
+package wallpaper
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// cp437 are the characters of code page 437 from 0x80, the encoding of
+// classic ANSI art.
+const cp437 = "ÇüéâäàåçêëèïîìÄÅÉæÆôöòûùÿÖÜ¢£¥₧ƒáíóúñÑªº¿⌐¬½¼¡«»" +
+	"░▒▓│┤╡╢╖╕╣║╗╝╜╛┐└┴┬├─┼╞╟╚╔╩╦╠═╬╧╨╤╥╙╘╒╓╫╪┘┌█▄▌▐▀" +
+	"αßΓπΣσµτΦΘΩδ∞φε∩≡±≥≤⌠⌡÷≈°∙·√ⁿ²■ "
+
+// artWidth is the width classic ANSI art wraps at.
+const artWidth = 80
+
+// maxArtWidth and maxArtHeight bound the art, so that cursor movements can't
+// make it huge. What is beyond is dropped.
+const (
+	maxArtWidth  = 500
+	maxArtHeight = 2000
+)
+
+// parseANSI reads ANSI art into rows of cells, drawing on base. Cells left
+// unwritten have a zero rune. Art in UTF-8 is taken as it is, otherwise it
+// is decoded as code page 437 and wrapped at 80 columns like in DOS.
+func parseANSI(data []byte, base tcell.Style) [][]Cell {
+	// A SAUCE record describing the art may follow an end of file
+	if end := bytes.IndexByte(data, 0x1a); end >= 0 {
+		data = data[:end]
+	}
+	text, wrap := string(data), 0
+	if !utf8.Valid(data) {
+		text, wrap = decodeCP437(data), artWidth
+	}
+
+	a := &artParser{base: base, style: base, fg: -1}
+	runes := []rune(text)
+	for i := 0; i < len(runes); i++ {
+		switch r := runes[i]; r {
+		case '\n':
+			a.x, a.y = 0, a.y+1
+		case '\r':
+			a.x = 0
+		case '\t':
+			a.x = (a.x/8 + 1) * 8
+		case 0x1b:
+			i = a.escape(runes, i)
+		default:
+			if r < ' ' {
+				continue
+			}
+			a.put(r)
+			if wrap > 0 && a.x >= wrap {
+				a.x, a.y = 0, a.y+1
+			}
+		}
+	}
+	return a.rows
+}
+
+func decodeCP437(data []byte) string {
+	high := []rune(cp437)
+	var text strings.Builder
+	for _, b := range data {
+		if b < 0x80 {
+			text.WriteByte(b)
+		} else {
+			text.WriteRune(high[b-0x80])
+		}
+	}
+	return text.String()
+}
+
+type artParser struct {
+	rows  [][]Cell
+	x, y  int
+	base  tcell.Style
+	style tcell.Style
+	// fg is the color number from 0 to 7 set by 30-37, brightened by bold,
+	// or -1 for other colors
+	fg   int
+	bold bool
+}
+
+func (a *artParser) put(r rune) {
+	if a.x >= maxArtWidth || a.y >= maxArtHeight {
+		a.x++
+		return
+	}
+	for len(a.rows) <= a.y {
+		a.rows = append(a.rows, nil)
+	}
+	for len(a.rows[a.y]) <= a.x {
+		a.rows[a.y] = append(a.rows[a.y], Cell{})
+	}
+	a.rows[a.y][a.x] = Cell{r, a.style}
+	a.x++
+}
+
+// escape reads the escape sequence at i, returning the index of its last
+// rune. Only colors and cursor movements are used.
+func (a *artParser) escape(runes []rune, i int) int {
+	if i+1 >= len(runes) || runes[i+1] != '[' {
+		return i + 1
+	}
+	end := i + 2
+	for end < len(runes) && (runes[end] < 0x40 || runes[end] > 0x7e) {
+		end++
+	}
+	if end == len(runes) {
+		return end - 1
+	}
+	params := []int{}
+	for _, p := range strings.Split(string(runes[i+2:end]), ";") {
+		n, _ := strconv.Atoi(p)
+		params = append(params, n)
+	}
+	count := max(params[0], 1)
+	switch runes[end] {
+	case 'm':
+		a.sgr(params)
+	case 'A':
+		a.y = max(a.y-count, 0)
+	case 'B':
+		a.y = min(a.y+count, maxArtHeight)
+	case 'C':
+		a.x = min(a.x+count, maxArtWidth)
+	case 'D':
+		a.x = max(a.x-count, 0)
+	case 'H', 'f':
+		a.y, a.x = min(max(params[0]-1, 0), maxArtHeight), 0
+		if len(params) > 1 {
+			a.x = min(max(params[1]-1, 0), maxArtWidth)
+		}
+	}
+	return end
+}
+
+// sgr sets the colors.
+func (a *artParser) sgr(params []int) {
+	for i := 0; i < len(params); i++ {
+		switch p := params[i]; {
+		case p == 0:
+			a.style, a.fg, a.bold = a.base, -1, false
+		case p == 1:
+			a.bold = true
+			a.setFg()
+		case p == 22:
+			a.bold = false
+			a.setFg()
+		case p == 7:
+			a.style = a.style.Reverse(true)
+		case p == 27:
+			a.style = a.style.Reverse(false)
+		case p >= 30 && p <= 37:
+			a.fg = p - 30
+			a.setFg()
+		case p == 39:
+			fg, _, _ := a.base.Decompose()
+			a.style, a.fg = a.style.Foreground(fg), -1
+		case p >= 40 && p <= 47:
+			a.style = a.style.Background(tcell.PaletteColor(p - 40))
+		case p == 49:
+			_, bg, _ := a.base.Decompose()
+			a.style = a.style.Background(bg)
+		case p >= 90 && p <= 97:
+			a.style, a.fg = a.style.Foreground(tcell.PaletteColor(p-90+8)), -1
+		case p >= 100 && p <= 107:
+			a.style = a.style.Background(tcell.PaletteColor(p - 100 + 8))
+		case p == 38 || p == 48:
+			var c tcell.Color
+			c, i = extendedColor(params, i)
+			if p == 38 {
+				a.style, a.fg = a.style.Foreground(c), -1
+			} else {
+				a.style = a.style.Background(c)
+			}
+		}
+	}
+}
+
+func (a *artParser) setFg() {
+	if a.fg < 0 {
+		return
+	}
+	n := a.fg
+	if a.bold {
+		n += 8
+	}
+	a.style = a.style.Foreground(tcell.PaletteColor(n))
+}
+
+// extendedColor reads the color of 38 or 48 at params[i], such as 5;n or
+// 2;r;g;b, returning the index of its last parameter.
+func extendedColor(params []int, i int) (tcell.Color, int) {
+	switch {
+	case i+2 < len(params) && params[i+1] == 5:
+		return tcell.PaletteColor(params[i+2]), i + 2
+	case i+4 < len(params) && params[i+1] == 2:
+		return tcell.NewRGBColor(int32(params[i+2]), int32(params[i+3]), int32(params[i+4])), i + 4
+	}
+	return tcell.ColorDefault, len(params)
+}
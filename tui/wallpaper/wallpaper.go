@@ -0,0 +1,216 @@
+// Package wallpaper renders the desktop's background: a solid color, a
+// gradient, ANSI art, or a PNG or JPEG picture drawn with half blocks.
+package wallpaper
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+	"math"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/snadrus/tuitop/tui/config"
+	"golang.org/x/xerrors"
+)
+
+// Cell is a character of the wallpaper.
+type Cell struct {
+	Rune  rune
+	Style tcell.Style
+}
+
+// Wallpaper is a background which can be rendered at any size. It is not
+// safe for concurrent use.
+type Wallpaper struct {
+	background tcell.Color
+	gradient   []tcell.Color
+	horizontal bool
+	// art is ANSI art, drawn centered
+	art [][]Cell
+	// picture is scaled to cover the screen
+	picture image.Image
+
+	// cells are the last rendered, for width and height
+	cells         [][]Cell
+	width, height int
+}
+
+// Load reads the wallpaper set in cfg. File is used if set, else Gradient,
+// else Color. Without any, the wallpaper is the terminal's background.
+func Load(cfg config.Wallpaper) (*Wallpaper, error) {
+	w := &Wallpaper{background: tcell.ColorDefault, horizontal: cfg.Horizontal}
+	if cfg.Color != "" {
+		c, err := parseColor(cfg.Color)
+		if err != nil {
+			return w, err
+		}
+		w.background = c
+	}
+	switch {
+	case cfg.File != "":
+		data, err := os.ReadFile(expandHome(cfg.File))
+		if err != nil {
+			return w, xerrors.Errorf("cannot read wallpaper: %w", err)
+		}
+		picture, _, err := image.Decode(bytes.NewReader(data))
+		switch {
+		case err == nil:
+			w.picture = picture
+		case errors.Is(err, image.ErrFormat):
+			w.art = parseANSI(data, tcell.StyleDefault.Background(w.background))
+		default:
+			return w, xerrors.Errorf("cannot decode %s: %w", cfg.File, err)
+		}
+	case len(cfg.Gradient) == 1:
+		return w, xerrors.New("a gradient needs two colors or more")
+	case len(cfg.Gradient) > 1:
+		for _, name := range cfg.Gradient {
+			c, err := parseColor(name)
+			if err != nil {
+				return w, err
+			}
+			w.gradient = append(w.gradient, c)
+		}
+	}
+	return w, nil
+}
+
+func parseColor(name string) (tcell.Color, error) {
+	c := tcell.GetColor(name)
+	if c == tcell.ColorDefault {
+		return c, xerrors.Errorf("unknown color %q", name)
+	}
+	return c, nil
+}
+
+func expandHome(file string) string {
+	if !strings.HasPrefix(file, "~/") {
+		return file
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return file
+	}
+	return path.Join(home, file[2:])
+}
+
+// Render returns the rows of the wallpaper on a screen width cells wide and
+// height high. It is rendered again only when the size changes.
+func (w *Wallpaper) Render(width, height int) [][]Cell {
+	if w.cells != nil && w.width == width && w.height == height {
+		return w.cells
+	}
+	w.width, w.height = width, height
+	w.cells = make([][]Cell, height)
+	blank := Cell{' ', tcell.StyleDefault.Background(w.background)}
+	for y := range w.cells {
+		w.cells[y] = make([]Cell, width)
+		for x := range w.cells[y] {
+			w.cells[y][x] = blank
+		}
+	}
+	switch {
+	case w.picture != nil:
+		w.renderPicture()
+	case w.art != nil:
+		w.renderArt()
+	case w.gradient != nil:
+		w.renderGradient()
+	}
+	return w.cells
+}
+
+func (w *Wallpaper) renderGradient() {
+	for y, row := range w.cells {
+		for x := range row {
+			at, span := y, w.height
+			if w.horizontal {
+				at, span = x, w.width
+			}
+			row[x] = Cell{' ', tcell.StyleDefault.Background(blend(w.gradient, at, span))}
+		}
+	}
+}
+
+// blend returns the color at of span cells, going through colors evenly.
+func blend(colors []tcell.Color, at, span int) tcell.Color {
+	if span < 2 {
+		return colors[0]
+	}
+	pos := float64(at) / float64(span-1) * float64(len(colors)-1)
+	i := min(int(pos), len(colors)-2)
+	frac := pos - float64(i)
+	r1, g1, b1 := colors[i].RGB()
+	r2, g2, b2 := colors[i+1].RGB()
+	mix := func(a, b int32) int32 {
+		return a + int32(math.Round(float64(b-a)*frac))
+	}
+	return tcell.NewRGBColor(mix(r1, r2), mix(g1, g2), mix(b1, b2))
+}
+
+// renderArt centers the art, cutting what doesn't fit.
+func (w *Wallpaper) renderArt() {
+	artWidth := 0
+	for _, row := range w.art {
+		artWidth = max(artWidth, len(row))
+	}
+	left, top := max((w.width-artWidth)/2, 0), max((w.height-len(w.art))/2, 0)
+	for y, row := range w.art {
+		if top+y >= w.height {
+			break
+		}
+		for x, c := range row {
+			if left+x >= w.width {
+				break
+			}
+			if c.Rune != 0 {
+				w.cells[top+y][left+x] = c
+			}
+		}
+	}
+}
+
+// renderPicture scales the picture to cover the screen, keeping its aspect
+// and cutting the edges which don't fit. Each cell shows two pixels, the
+// upper as the foreground of a half block and the lower as the background.
+func (w *Wallpaper) renderPicture() {
+	bounds := w.picture.Bounds()
+	pixelsHigh := 2 * w.height
+	scale := max(float64(w.width)/float64(bounds.Dx()), float64(pixelsHigh)/float64(bounds.Dy()))
+	// Where the screen starts in the picture, centered
+	left := float64(bounds.Min.X) + (float64(bounds.Dx())-float64(w.width)/scale)/2
+	top := float64(bounds.Min.Y) + (float64(bounds.Dy())-float64(pixelsHigh)/scale)/2
+	for y, row := range w.cells {
+		for x := range row {
+			x0 := left + float64(x)/scale
+			upper := average(w.picture, x0, top+float64(2*y)/scale, 1/scale)
+			lower := average(w.picture, x0, top+float64(2*y+1)/scale, 1/scale)
+			row[x] = Cell{'▀', tcell.StyleDefault.Foreground(upper).Background(lower)}
+		}
+	}
+}
+
+// average returns the mean color of the square of picture at x, y and size
+// pixels wide, taking at least a pixel.
+func average(picture image.Image, x, y, size float64) tcell.Color {
+	bounds := picture.Bounds()
+	x0, y0 := max(int(x), bounds.Min.X), max(int(y), bounds.Min.Y)
+	x1, y1 := min(max(int(x+size), x0+1), bounds.Max.X), min(max(int(y+size), y0+1), bounds.Max.Y)
+	var r, g, b, n uint64
+	for py := y0; py < y1; py++ {
+		for px := x0; px < x1; px++ {
+			pr, pg, pb, _ := picture.At(px, py).RGBA()
+			r, g, b, n = r+uint64(pr), g+uint64(pg), b+uint64(pb), n+1
+		}
+	}
+	if n == 0 {
+		return tcell.ColorBlack
+	}
+	// RGBA is 16 bits per channel
+	return tcell.NewRGBColor(int32(r/n>>8), int32(g/n>>8), int32(b/n>>8))
+}
@@ -0,0 +1,166 @@
+package wallpaper
+
+import (
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/snadrus/tuitop/tui/config"
+)
+
+func TestSolidAndGradient(t *testing.T) {
+	w, err := Load(config.Wallpaper{Color: "#102030"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cells := w.Render(3, 2)
+	if _, bg, _ := cells[1][2].Style.Decompose(); bg != tcell.NewRGBColor(0x10, 0x20, 0x30) {
+		t.Errorf("got background %v", bg)
+	}
+
+	w, err = Load(config.Wallpaper{Gradient: []string{"#000000", "#c8c8c8", "#000000"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cells = w.Render(1, 5)
+	for y, want := range []tcell.Color{
+		tcell.NewRGBColor(0, 0, 0),
+		tcell.NewRGBColor(100, 100, 100),
+		tcell.NewRGBColor(200, 200, 200),
+		tcell.NewRGBColor(100, 100, 100),
+		tcell.NewRGBColor(0, 0, 0),
+	} {
+		if _, bg, _ := cells[y][0].Style.Decompose(); bg != want {
+			t.Errorf("row %d is %v, want %v", y, bg, want)
+		}
+	}
+
+	w, _ = Load(config.Wallpaper{Gradient: []string{"red", "blue"}, Horizontal: true})
+	cells = w.Render(2, 1)
+	if _, bg, _ := cells[0][1].Style.Decompose(); bg != tcell.NewRGBColor(0, 0, 255) {
+		t.Errorf("got %v at the right, want blue", bg)
+	}
+
+	for _, bad := range []config.Wallpaper{
+		{Color: "nocolor"},
+		{Gradient: []string{"red"}},
+		{File: "/does/not/exist"},
+	} {
+		if _, err := Load(bad); err == nil {
+			t.Errorf("loaded %+v", bad)
+		}
+	}
+}
+
+func TestANSI(t *testing.T) {
+	base := tcell.StyleDefault.Background(tcell.ColorNavy)
+	rows := parseANSI([]byte("a\x1b[1;31mb\x1b[0m\x1b[2Cc\r\n\x1b[44;38;2;1;2;3md\x1b[49me\x1a SAUCE00"), base)
+	if len(rows) != 2 || len(rows[0]) != 5 || len(rows[1]) != 2 {
+		t.Fatalf("got rows %v", rows)
+	}
+	if rows[0][0] != (Cell{'a', base}) || rows[0][4] != (Cell{'c', base}) || rows[0][2].Rune != 0 {
+		t.Errorf("got first row %v", rows[0])
+	}
+	if fg, _, _ := rows[0][1].Style.Decompose(); fg != tcell.PaletteColor(9) {
+		t.Errorf("bold red is %v", fg)
+	}
+	if fg, bg, _ := rows[1][0].Style.Decompose(); fg != tcell.NewRGBColor(1, 2, 3) || bg != tcell.PaletteColor(4) {
+		t.Errorf("got %v on %v", fg, bg)
+	}
+	if _, bg, _ := rows[1][1].Style.Decompose(); bg != tcell.ColorNavy {
+		t.Errorf("default background is %v", bg)
+	}
+
+	// Code page 437, wrapped at 80 columns
+	line := make([]byte, 81)
+	for i := range line {
+		line[i] = 0xdb
+	}
+	rows = parseANSI(line, base)
+	if len(rows) != 2 || len(rows[0]) != 80 || rows[1][0].Rune != '█' {
+		t.Fatalf("got %d rows", len(rows))
+	}
+
+	// Cursor movements far away are bounded
+	rows = parseANSI([]byte("\x1b[999999999Ca\x1b[999999999;999999999Hb\x1b[999999999Bc"), base)
+	if len(rows) != 0 {
+		t.Errorf("got %d rows", len(rows))
+	}
+	rows = parseANSI([]byte("\x1b[1;999999999Ha\x1b[999999999;1Hb"), base)
+	if len(rows) != 0 {
+		t.Errorf("got %d rows", len(rows))
+	}
+}
+
+func TestArtIsCentered(t *testing.T) {
+	dir := t.TempDir()
+	file := path.Join(dir, "art.ans")
+	if err := os.WriteFile(file, []byte("\x1b[32mhi"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	w, err := Load(config.Wallpaper{File: file, Color: "black"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cells := w.Render(6, 3)
+	if cells[1][2].Rune != 'h' || cells[1][3].Rune != 'i' || cells[0][0].Rune != ' ' {
+		t.Errorf("got %v", cells)
+	}
+	// Art wider than the desktop is cut
+	if cells := w.Render(1, 1); cells[0][0].Rune != 'h' {
+		t.Errorf("got %v", cells)
+	}
+}
+
+func TestPicture(t *testing.T) {
+	// Red above green, on the left of white above blue
+	img := image.NewRGBA(image.Rect(0, 0, 4, 4))
+	for y := 0; y < 4; y++ {
+		for x := 0; x < 4; x++ {
+			c := color.RGBA{255, 255, 255, 255}
+			switch {
+			case x < 2 && y < 2:
+				c = color.RGBA{255, 0, 0, 255}
+			case x < 2:
+				c = color.RGBA{0, 255, 0, 255}
+			case y >= 2:
+				c = color.RGBA{0, 0, 255, 255}
+			}
+			img.Set(x, y, c)
+		}
+	}
+	file := path.Join(t.TempDir(), "picture.png")
+	f, err := os.Create(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := png.Encode(f, img); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	w, err := Load(config.Wallpaper{File: file})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cells := w.Render(2, 1)
+	for x, want := range [][2]tcell.Color{
+		{tcell.NewRGBColor(255, 0, 0), tcell.NewRGBColor(0, 255, 0)},
+		{tcell.NewRGBColor(255, 255, 255), tcell.NewRGBColor(0, 0, 255)},
+	} {
+		fg, bg, _ := cells[0][x].Style.Decompose()
+		if cells[0][x].Rune != '▀' || fg != want[0] || bg != want[1] {
+			t.Errorf("cell %d is %v on %v", x, fg, bg)
+		}
+	}
+
+	// A wide desktop cuts the top and bottom of the picture
+	cells = w.Render(4, 1)
+	if fg, bg, _ := cells[0][0].Style.Decompose(); fg != tcell.NewRGBColor(255, 0, 0) || bg != tcell.NewRGBColor(0, 255, 0) {
+		t.Errorf("got %v on %v", fg, bg)
+	}
+}